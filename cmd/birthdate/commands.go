@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"flag"
+	"fmt"
+	"github.com/almaznur91/splitty/internal/bot"
+	"github.com/almaznur91/splitty/internal/repository"
+	"github.com/rs/zerolog/log"
+	"github.com/xlab/closer"
+	"os"
+	"sort"
+	"time"
+)
+
+// command is a mode of the binary, the first argument chooses it, the rest are flags of the command
+type command func(ctx context.Context, cfg *config, args []string) error
+
+var commands = map[string]command{
+	"serve":   serve,
+	"migrate": migrateDb,
+	"backup":  backupDb,
+	"restore": restoreDb,
+}
+
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// serve runs the bot, it is the default command
+func serve(ctx context.Context, cfg *config, _ []string) error {
+	app, cl, err := initApp(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	closer.Bind(cl)
+	go bot.WatchLanguages(ctx)
+	return app.Do(ctx)
+}
+
+// migrateDb only updates the database, e.g. before the deploy of a new version
+func migrateDb(ctx context.Context, cfg *config, _ []string) error {
+	cfg.MigrateOnStart = true
+	_, cl, err := initMongoConnection(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	cl()
+	return nil
+}
+
+// backupDb dumps collections of the bot to the gzip NDJSON archive
+func backupDb(ctx context.Context, cfg *config, args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	out := fs.String("out", fmt.Sprintf("birthdate-%s.ndjson.gz", time.Now().Format("20060102-150405")), "archive file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg.MigrateOnStart = false
+	db, cl, err := initMongoConnection(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer cl()
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(f)
+	stats, err := repository.NewBackup(db).Dump(ctx, gz)
+	if err == nil {
+		err = gz.Close()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(*out)
+		return err
+	}
+	log.Info().Msgf("backup saved to %s: %v", *out, stats)
+	return nil
+}
+
+// restoreDb loads the archive made by backupDb and migrates the database to the current version.
+// Dry run checks the archive without writing to the database, with drop the archive is checked before the data is deleted
+func restoreDb(ctx context.Context, cfg *config, args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only check the archive")
+	drop := fs.Bool("drop", false, "delete the current data before the restore")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: birthdate restore [-dry-run] [-drop] <archive>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("archive file is required")
+	}
+
+	cfg.MigrateOnStart = false
+	db, cl, err := initMongoConnection(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer cl()
+	backup := repository.NewBackup(db)
+
+	if *dryRun || *drop {
+		stats, _, err := restoreArchive(ctx, backup, fs.Arg(0), true, false)
+		if err != nil {
+			return err
+		}
+		log.Info().Msgf("archive is valid: %v", stats)
+		if *dryRun {
+			log.Info().Msg("dry run, nothing is written")
+			return nil
+		}
+	}
+	stats, h, err := restoreArchive(ctx, backup, fs.Arg(0), false, *drop)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("restored %v", stats)
+	// steps applied after the backup was made run again on the restored data
+	if err := repository.NewMigrator(db).Reset(ctx, h.Schema); err != nil {
+		return err
+	}
+	return migrate(ctx, db)
+}
+
+// restoreArchive reads the gzip archive from the start and restores it by the backup
+func restoreArchive(ctx context.Context, backup *repository.Backup, name string, dryRun bool, drop bool) (repository.BackupStats, *repository.BackupHeader, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	r := bufio.NewReader(gz)
+	h, err := repository.ReadBackupHeader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Info().Msgf("read backup of %s, schema %v", h.CreateAt.Format(time.RFC3339), h.Schema)
+	stats, err := backup.Restore(ctx, r, dryRun, drop)
+	return stats, h, err
+}
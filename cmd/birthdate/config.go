@@ -1,26 +1,242 @@
 package main
 
-import "github.com/caarlos0/env/v6"
+import (
+	"flag"
+	"fmt"
+	"github.com/caarlos0/env/v6"
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
 
+// config is read by layers: defaults, the YAML file, env and flags, each layer overrides the previous one.
+// Flags are named after env keys, DB_HOST is set by -db-host. Fields tagged as secret are masked in logs
 type config struct {
-	Listen   string `env:"LISTEN" envDefault:"localhost:7171"`
-	LogLevel string `env:"LOG_LEVEL" envDefault:"debug"`
-	LogFmt   string `env:"LOG_FMT" envDefault:"console"`
+	Listen   string `env:"LISTEN" yaml:"listen"`
+	LogLevel string `env:"LOG_LEVEL" yaml:"log_level"`
+	LogFmt   string `env:"LOG_FMT" yaml:"log_fmt"`
 
-	DbAddr          string   `env:"DB_HOST" envDefault:"mongodb://localhost:27017/"`
-	DbName          string   `env:"DB_NAME" envDefault:"birthday"`
-	TgToken         string   `env:"TG_TOKEN" envDefault:"619387871:AAFncJTTUXXC7wHylHcLhff8QNf_8EeCvpE"`
-	SuperUsers      []string `env:"SUPER_USER" envSeparator:":" envDefault:"mazanur:zagirnur"`
-	TgDebug         bool     `env:"TG_DEBUG" envDefault:"false"`
-	DefaultLanguage string   `env:"DEFAULT_LANGUAGE" envDefault:"ru"`
+	DbAddr          string   `env:"DB_HOST" yaml:"db_host" secret:"url"`
+	DbName          string   `env:"DB_NAME" yaml:"db_name"`
+	MigrateOnStart  bool     `env:"MIGRATE_ON_START" yaml:"migrate_on_start"`
+	DbTransactions  bool     `env:"DB_TRANSACTIONS" yaml:"db_transactions"`
+	TgToken         string   `env:"TG_TOKEN" yaml:"tg_token" secret:"true"`
+	TgTokenFile     string   `env:"TG_TOKEN_FILE" yaml:"tg_token_file"`
+	SuperUsers      []string `env:"SUPER_USER" envSeparator:":" yaml:"super_users"`
+	TgDebug         bool     `env:"TG_DEBUG" yaml:"tg_debug"`
+	DefaultLanguage string   `env:"DEFAULT_LANGUAGE" yaml:"default_language"`
+	GreetingHour    int      `env:"GREETING_HOUR" yaml:"greeting_hour"`
+	ReminderDays    int      `env:"REMINDER_DAYS" yaml:"reminder_days"`
+	QuietHoursFrom  int      `env:"QUIET_HOURS_FROM" yaml:"quiet_hours_from"`
+	QuietHoursTo    int      `env:"QUIET_HOURS_TO" yaml:"quiet_hours_to"`
+	DigestHour      int      `env:"DIGEST_HOUR" yaml:"digest_hour"`
+	CollectionHour  int      `env:"COLLECTION_HOUR" yaml:"collection_hour"`
+	AssignDays      int      `env:"ASSIGN_DAYS" yaml:"assign_days"`
 }
 
-func initConfig() (*config, error) {
-	cfg := &config{}
+func defaultConfig() *config {
+	return &config{
+		Listen:          "localhost:7171",
+		LogLevel:        "debug",
+		LogFmt:          "console",
+		DbAddr:          "mongodb://localhost:27017/",
+		DbName:          "birthday",
+		MigrateOnStart:  true,
+		SuperUsers:      []string{"mazanur", "zagirnur"},
+		DefaultLanguage: "ru",
+		GreetingHour:    9,
+		ReminderDays:    2,
+		QuietHoursFrom:  22,
+		QuietHoursTo:    9,
+		DigestHour:      10,
+		CollectionHour:  10,
+		AssignDays:      3,
+	}
+}
+
+var tokenPattern = regexp.MustCompile(`^\d+:[\w-]{30,}$`)
+
+// initConfig reads the config from every layer and validates it, returns arguments left after flags.
+// The YAML file is set by the -config flag or CONFIG_FILE env. Values of env and flags which can't be parsed
+// are reported together with other problems
+func initConfig(args []string) (*config, []string, error) {
+	cfg := defaultConfig()
+
+	fs := flag.NewFlagSet("birthdate", flag.ContinueOnError)
+	file := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML config file")
+	flags := make(map[string]string)
+	forEachField(cfg, func(f reflect.StructField, _ reflect.Value) {
+		key := f.Tag.Get("env")
+		fs.Func(strings.ToLower(strings.ReplaceAll(key, "_", "-")), "overrides "+key, func(v string) error {
+			flags[key] = v
+			return nil
+		})
+	})
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: birthdate [flags] [%s] [command flags]\n", strings.Join(commandNames(), "|"))
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	var problems configErrors
+	if *file != "" {
+		// env and flags are still checked, so all problems are reported at once
+		if err := readConfigFile(*file, cfg); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	environment := make(map[string]string)
+	forEachField(cfg, func(f reflect.StructField, _ reflect.Value) {
+		if v, ok := os.LookupEnv(f.Tag.Get("env")); ok {
+			environment[f.Tag.Get("env")] = v
+		}
+	})
+	problems = append(problems, parseEnv(cfg, environment)...)
+	problems = append(problems, parseEnv(cfg, flags)...)
+
+	name, _ := commandName(fs.Args())
+	problems = append(problems, cfg.validate(name == "serve")...)
+	if len(problems) > 0 {
+		// the map of hours makes the order random
+		sort.Strings(problems)
+		return nil, nil, problems
+	}
+	return cfg, fs.Args(), nil
+}
+
+// commandName splits the command and its arguments, serve is the default command
+func commandName(args []string) (string, []string) {
+	if len(args) == 0 {
+		return "serve", args
+	}
+	return args[0], args[1:]
+}
+
+// parseEnv sets fields by env keys one by one, so every value which can't be parsed is reported
+func parseEnv(cfg *config, environment map[string]string) configErrors {
+	var problems configErrors
+	forEachField(cfg, func(f reflect.StructField, _ reflect.Value) {
+		key := f.Tag.Get("env")
+		v, ok := environment[key]
+		if !ok {
+			return
+		}
+		if err := env.Parse(cfg, env.Options{Environment: map[string]string{key: v}}); err != nil {
+			problems = append(problems, fmt.Sprintf("%s %q is not a valid %s", key, v, f.Type))
+		}
+	})
+	return problems
+}
+
+// readConfigFile sets fields present in the YAML file, unknown keys are errors to catch typos
+func readConfigFile(name string, cfg *config) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil {
+		return fmt.Errorf("config file %s: %v", name, err)
+	}
+	return nil
+}
+
+// configErrors lists every problem of the config, so all of them can be fixed at once
+type configErrors []string
+
+func (e configErrors) Error() string {
+	return "invalid config:\n  - " + strings.Join(e, "\n  - ")
+}
 
-	if err := env.Parse(cfg); err != nil {
-		return cfg, err
+// validate reads the token from TG_TOKEN_FILE and checks every field, the token is checked only
+// for the bot, other commands work with the database only
+func (c *config) validate(serve bool) configErrors {
+	var problems configErrors
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
 	}
 
-	return cfg, nil
+	if serve {
+		if c.TgTokenFile != "" {
+			if c.TgToken != "" {
+				problems = append(problems, "TG_TOKEN and TG_TOKEN_FILE are both set, keep one of them")
+			} else if b, err := ioutil.ReadFile(c.TgTokenFile); err != nil {
+				problems = append(problems, fmt.Sprintf("TG_TOKEN_FILE can't be read: %v", err))
+			} else if c.TgToken = strings.TrimSpace(string(b)); c.TgToken == "" {
+				problems = append(problems, "TG_TOKEN_FILE is empty")
+			}
+		}
+		check(c.TgToken != "" || c.TgTokenFile != "", "TG_TOKEN or TG_TOKEN_FILE is required")
+		check(c.TgToken == "" || tokenPattern.MatchString(c.TgToken), "TG_TOKEN doesn't look like a bot token")
+	}
+
+	_, _, err := net.SplitHostPort(c.Listen)
+	check(err == nil, "LISTEN %q is not host:port", c.Listen)
+	_, err = zerolog.ParseLevel(strings.ToLower(c.LogLevel))
+	check(err == nil, "LOG_LEVEL %q is unknown", c.LogLevel)
+	check(c.LogFmt == "console" || c.LogFmt == "json", "LOG_FMT %q is not console or json", c.LogFmt)
+	check(strings.HasPrefix(c.DbAddr, "mongodb://") || strings.HasPrefix(c.DbAddr, "mongodb+srv://"),
+		"DB_HOST is not a mongodb:// address")
+	check(c.DbName != "", "DB_NAME is required")
+	check(c.DefaultLanguage == "ru" || c.DefaultLanguage == "en", "DEFAULT_LANGUAGE %q is not ru or en", c.DefaultLanguage)
+	for key, hour := range map[string]int{"GREETING_HOUR": c.GreetingHour, "QUIET_HOURS_FROM": c.QuietHoursFrom,
+		"QUIET_HOURS_TO": c.QuietHoursTo, "DIGEST_HOUR": c.DigestHour, "COLLECTION_HOUR": c.CollectionHour} {
+		check(hour >= 0 && hour <= 23, "%s %d is not an hour from 0 to 23", key, hour)
+	}
+	check(c.ReminderDays >= 0, "REMINDER_DAYS %d is negative", c.ReminderDays)
+	check(c.AssignDays >= 0, "ASSIGN_DAYS %d is negative", c.AssignDays)
+	return problems
+}
+
+// String shows the config for logs, secrets are masked
+func (c *config) String() string {
+	var fields []string
+	forEachField(c, func(f reflect.StructField, v reflect.Value) {
+		value := fmt.Sprint(v.Interface())
+		switch f.Tag.Get("secret") {
+		case "true":
+			value = maskSecret(value)
+		case "url":
+			value = maskURL(value)
+		}
+		fields = append(fields, f.Tag.Get("env")+"="+value)
+	})
+	return strings.Join(fields, " ")
+}
+
+func forEachField(c *config, fn func(f reflect.StructField, v reflect.Value)) {
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		fn(v.Type().Field(i), v.Field(i))
+	}
+}
+
+// maskSecret keeps the last characters only, so it's still clear which secret is used
+func maskSecret(s string) string {
+	if len(s) <= 8 {
+		return strings.Repeat("*", len(s))
+	}
+	return "****" + s[len(s)-4:]
+}
+
+// maskURL hides the password of the address
+func maskURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return maskSecret(s)
+	}
+	return u.Redacted()
 }
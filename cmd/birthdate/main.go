@@ -2,9 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/almaznur91/splitty/internal/handler"
-	"github.com/gookit/i18n"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -14,9 +15,11 @@ import (
 	"os"
 	"strings"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/almaznur91/splitty/internal/bot"
 	"github.com/almaznur91/splitty/internal/events"
+	"github.com/almaznur91/splitty/internal/repository"
 	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/xlab/closer"
 )
@@ -27,29 +30,30 @@ func main() {
 	defer closer.Close()
 	ctx := context.Background()
 
-	cfg, err := initConfig()
-	if err != nil {
-		log.Err(err).Msg("Can not init config")
+	cfg, args, err := initConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	} else if err != nil {
+		log.Fatal().Err(err).Msg("Can not init config")
 	}
 
-	initI18n(cfg)
-
 	if err := initLogger(cfg); err != nil {
 		log.Fatal().Err(err).Msg("Can not init logger")
 	}
+	if err := initI18n(cfg); err != nil {
+		log.Fatal().Err(err).Msg("Can not init languages")
+	}
+	log.Info().Msgf("config: %s", cfg)
 
 	rand.Seed(int64(time.Now().Nanosecond()))
 
-	app, cl, err := initApp(ctx, cfg)
-	if err != nil {
-		log.Error().Err(err).Msg("Can not init application")
-		return
+	name, args := commandName(args)
+	run, ok := commands[name]
+	if !ok {
+		log.Fatal().Msgf("unknown command %q, use one of: %s", name, strings.Join(commandNames(), ", "))
 	}
-	closer.Bind(cl)
-
-	if err := app.Do(ctx); err != nil {
-		log.Error().Err(err).Msg("telegram listener failed")
-		return
+	if err := run(ctx, cfg, args); err != nil {
+		log.Fatal().Err(err).Msgf("%s failed", name)
 	}
 }
 
@@ -74,21 +78,61 @@ func initTelegramApi(cfg *config, bcfg *bot.Config) (*tbapi.BotAPI, error) {
 	return tbAPI, nil
 }
 
-func initTelegramConfig(tbAPI *tbapi.BotAPI, bots []bot.Interface, bs events.ButtonService, us events.UserService, cs events.ChatStateService, eh *handler.ErrorHandler) (*events.TelegramListener, error) {
+func initTelegramConfig(tbAPI *tbapi.BotAPI, bots []bot.Interface, bs events.ButtonService, us events.UserService, cs events.ChatStateService, eh *handler.ErrorHandler, bn *events.BirthdayNotifier, dn *events.DebtNotifier, sc *events.CollectionScheduler) (*events.TelegramListener, error) {
 	multiBot := bot.MultiBot(bots)
 
 	tgListener := &events.TelegramListener{
-		TbAPI:            tbAPI,
-		Bots:             multiBot,
-		ErrorHandler:     eh,
-		ChatStateService: cs,
-		ButtonService:    bs,
-		UserService:      us,
+		TbAPI:               tbAPI,
+		Bots:                multiBot,
+		ErrorHandler:        eh,
+		ChatStateService:    cs,
+		ButtonService:       bs,
+		UserService:         us,
+		BirthdayNotifier:    bn,
+		DebtNotifier:        dn,
+		CollectionScheduler: sc,
 	}
 
 	return tgListener, nil
 }
 
+func initBirthdayNotifier(cfg *config, tbAPI *tbapi.BotAPI, rs events.RoomService, ms events.MemberService, eh *handler.ErrorHandler) *events.BirthdayNotifier {
+	return &events.BirthdayNotifier{
+		TbAPI:         tbAPI,
+		RoomService:   rs,
+		MemberService: ms,
+		ErrorHandler:  eh,
+		GreetingHour:  cfg.GreetingHour,
+	}
+}
+
+func initDebtNotifier(cfg *config, tbAPI *tbapi.BotAPI, cs events.CollectionService, ms events.MemberService, bs events.ButtonService, eh *handler.ErrorHandler) *events.DebtNotifier {
+	return &events.DebtNotifier{
+		TbAPI:             tbAPI,
+		CollectionService: cs,
+		MemberService:     ms,
+		ButtonService:     bs,
+		ErrorHandler:      eh,
+		ReminderDays:      cfg.ReminderDays,
+		QuietHoursFrom:    cfg.QuietHoursFrom,
+		QuietHoursTo:      cfg.QuietHoursTo,
+		DigestHour:        cfg.DigestHour,
+	}
+}
+
+func initCollectionScheduler(cfg *config, tbAPI *tbapi.BotAPI, rs events.RoomService, cs events.CollectionService, ms events.MemberService, bs events.ButtonService, eh *handler.ErrorHandler) *events.CollectionScheduler {
+	return &events.CollectionScheduler{
+		TbAPI:             tbAPI,
+		RoomService:       rs,
+		CollectionService: cs,
+		MemberService:     ms,
+		ButtonService:     bs,
+		ErrorHandler:      eh,
+		OpenHour:          cfg.CollectionHour,
+		AssignDays:        cfg.AssignDays,
+	}
+}
+
 func initLogger(c *config) error {
 	log.Debug().Msg("initialize logger")
 	logLvl, err := zerolog.ParseLevel(strings.ToLower(c.LogLevel))
@@ -123,13 +167,38 @@ func initMongoConnection(ctx context.Context, cfg *config) (*mongo.Database, fun
 	if err != nil {
 		return nil, nil, err
 	}
-	return client.Database(cfg.DbName), func() {
+
+	db := client.Database(cfg.DbName)
+	if cfg.MigrateOnStart {
+		if err := migrate(ctx, db); err != nil {
+			return nil, nil, err
+		}
+	}
+	return db, func() {
 		if err := client.Disconnect(ctx); err != nil {
 			log.Fatal().Err(err).Msg("error while connect to mongo")
 		}
 	}, nil
 }
 
+// migrate brings indexes and data of the database to the current version
+func migrate(ctx context.Context, db *mongo.Database) error {
+	applied, err := repository.NewMigrator(db).Migrate(ctx)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("database is up to date, applied %v migrations", applied)
+	return nil
+}
+
+// initUnitOfWork runs multi-document changes in transactions, they need mongo started as a replica set
+func initUnitOfWork(cfg *config, db *mongo.Database) repository.UnitOfWork {
+	if cfg.DbTransactions {
+		return repository.NewUnitOfWork(db)
+	}
+	return repository.NoopUnitOfWork{}
+}
+
 func initBotConfig(c *config) *bot.Config {
 	cfg := &bot.Config{
 		SuperUsers: c.SuperUsers,
@@ -137,10 +206,10 @@ func initBotConfig(c *config) *bot.Config {
 	return cfg
 }
 
-func initI18n(c *config) {
+func initI18n(c *config) error {
 	languages := map[string]string{
 		language.English.String(): "English",
 		language.Russian.String(): "Русский",
 	}
-	i18n.Init("conf/lang", c.DefaultLanguage, languages)
+	return bot.LoadLanguages("conf/lang", c.DefaultLanguage, languages)
 }
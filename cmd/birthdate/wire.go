@@ -14,12 +14,15 @@ import (
 )
 
 func initApp(ctx context.Context, cfg *config) (tg *events.TelegramListener, closer func(), err error) {
-	wire.Build(initMongoConnection, initTelegramApi, initTelegramConfig, initBotConfig, handler.NewErrorHandler,
+	wire.Build(initMongoConnection, initUnitOfWork, initTelegramApi, initTelegramConfig, initBotConfig, handler.NewErrorHandler, initBirthdayNotifier,
+		initDebtNotifier, initCollectionScheduler,
 		service.NewUserService, wire.Bind(new(bot.UserService), new(*service.UserService)),
 		wire.Bind(new(events.UserService), new(*service.UserService)),
 		service.NewChatStateService, wire.Bind(new(bot.ChatStateService), new(*service.ChatStateService)),
 		service.NewButtonService, wire.Bind(new(bot.ButtonService), new(*service.ButtonService)),
 		service.NewRoomService, wire.Bind(new(bot.RoomService), new(*service.RoomService)),
+		wire.Bind(new(events.RoomService), new(*service.RoomService)),
+		wire.Bind(new(events.MemberService), new(*service.UserService)),
 		wire.Bind(new(events.ChatStateService), new(*service.ChatStateService)),
 		wire.Bind(new(events.ButtonService), new(*service.ButtonService)),
 		ProvideBotList, bots,
@@ -27,12 +30,46 @@ func initApp(ctx context.Context, cfg *config) (tg *events.TelegramListener, clo
 		repository.NewChatStateRepository, wire.Bind(new(repository.ChatStateRepository), new(*repository.MongoChatStateRepository)),
 		repository.NewRoomRepository, wire.Bind(new(repository.RoomRepository), new(*repository.MongoRoomRepository)),
 		repository.NewButtonRepository, wire.Bind(new(repository.ButtonRepository), new(*repository.MongoButtonRepository)),
+		service.NewCollectionService, wire.Bind(new(bot.CollectionService), new(*service.CollectionService)),
+		wire.Bind(new(events.CollectionService), new(*service.CollectionService)),
+		repository.NewCollectionRepository, wire.Bind(new(repository.CollectionRepository), new(*repository.MongoCollectionRepository)),
+		service.NewInviteService, wire.Bind(new(bot.InviteService), new(*service.InviteService)),
+		repository.NewInviteRepository, wire.Bind(new(repository.InviteRepository), new(*repository.MongoInviteRepository)),
+		service.NewPrivacyService, wire.Bind(new(bot.PrivacyService), new(*service.PrivacyService)),
 	)
 	return nil, nil, nil
 }
 
-var bots = wire.NewSet(bot.NewStartScreen, bot.NewRoomSetName, bot.NewRoomCreating, bot.NewStartScreenInitPerson)
+var bots = wire.NewSet(bot.NewStartScreen, bot.NewRoomSetName, bot.NewRoomCreating, bot.NewStartScreenInitPerson,
+	bot.NewUserSetting, bot.NewUserSettingChoose, bot.NewUserSettingBirtDate, bot.NewSetBirtDate, bot.NewAllRooms,
+	bot.NewSearchRoom, bot.NewViewRoom, bot.NewRoomSetting, bot.NewRoomRenaming, bot.NewRoomRename, bot.NewRoomMembers,
+	bot.NewRoomMember, bot.NewRoomConfirm, bot.NewRoomExit, bot.NewBindRoom, bot.NewBindRoomChoose, bot.NewGroupMembers,
+	bot.NewJoinRoom, bot.NewGreetingSetting, bot.NewGreetingTemplates, bot.NewGreetingInput, bot.NewGreetingPreview,
+	bot.NewRoomCurrency, bot.NewDebts, bot.NewCollectionCreating, bot.NewCollectionAmount, bot.NewContributionView,
+	bot.NewReceiptInput, bot.NewPaymentReview, bot.NewPaymentDetailsSetting, bot.NewPaymentFieldInput, bot.NewPaymentQR,
+	bot.NewCollectionDeadline, bot.NewSnoozeReminder, bot.NewStatistics,
+	bot.NewOperationHistory, bot.NewOperationView, bot.NewOperationAmount, bot.NewExpenseRecording, bot.NewExpenseInput,
+	bot.NewCollectionRuleSetting, bot.NewCollectionRuleChoose, bot.NewRuleAmountWriting, bot.NewRuleAmountInput,
+	bot.NewAssignmentAnswer, bot.NewOrganizerOptOut, bot.NewJointMerge, bot.NewRoomInvites, bot.NewInviteSetting,
+	bot.NewInviteJoin, bot.NewJoinRequestReview, bot.NewInlineRoomSearch, bot.NewMyData, bot.NewForgetMeConfirm,
+	bot.NewForgetMe, bot.NewRoomLanguage)
 
-func ProvideBotList(b2 *bot.StartScreen, b3 *bot.RoomCreating, b4 *bot.RoomSetName, b5 *bot.StartScreenInitPerson) []bot.Interface {
-	return []bot.Interface{b2, b3, b4}
+func ProvideBotList(b2 *bot.StartScreen, b3 *bot.RoomCreating, b4 *bot.RoomSetName, b5 *bot.StartScreenInitPerson,
+	b6 *bot.UserSetting, b7 *bot.UserSettingChoose, b8 *bot.UserSettingBirtDate, b9 *bot.SetBirtDate, b10 *bot.AllRooms,
+	b11 *bot.SearchRoom, b12 *bot.ViewRoom, b13 *bot.RoomSetting, b14 *bot.RoomRenaming, b15 *bot.RoomRename,
+	b16 *bot.RoomMembers, b17 *bot.RoomMember, b18 *bot.RoomConfirm, b19 *bot.RoomExit, b20 *bot.BindRoom,
+	b21 *bot.BindRoomChoose, b22 *bot.GroupMembers, b23 *bot.JoinRoom, b24 *bot.GreetingSetting,
+	b25 *bot.GreetingTemplates, b26 *bot.GreetingInput, b27 *bot.GreetingPreview, b28 *bot.RoomCurrency, b29 *bot.Debts,
+	b30 *bot.CollectionCreating, b31 *bot.CollectionAmount, b32 *bot.ContributionView, b33 *bot.ReceiptInput,
+	b34 *bot.PaymentReview, b35 *bot.PaymentDetailsSetting, b36 *bot.PaymentFieldInput, b37 *bot.PaymentQR,
+	b38 *bot.CollectionDeadline, b39 *bot.SnoozeReminder, b40 *bot.Statistics,
+	b41 *bot.OperationHistory, b42 *bot.OperationView, b43 *bot.OperationAmount, b44 *bot.ExpenseRecording,
+	b45 *bot.ExpenseInput, b46 *bot.CollectionRuleSetting, b47 *bot.CollectionRuleChoose, b48 *bot.RuleAmountWriting,
+	b49 *bot.RuleAmountInput, b50 *bot.AssignmentAnswer, b51 *bot.OrganizerOptOut,
+	b52 *bot.JointMerge, b53 *bot.RoomInvites, b54 *bot.InviteSetting, b55 *bot.InviteJoin,
+	b56 *bot.JoinRequestReview, b57 *bot.InlineRoomSearch, b58 *bot.MyData, b59 *bot.ForgetMeConfirm,
+	b60 *bot.ForgetMe, b61 *bot.RoomLanguage) []bot.Interface {
+	return []bot.Interface{b2, b3, b4, b5, b6, b7, b8, b9, b10, b11, b12, b13, b14, b15, b16, b17, b18, b19, b20, b21,
+		b22, b23, b24, b25, b26, b27, b28, b29, b30, b31, b32, b33, b34, b35, b36, b37, b38, b39, b40, b41, b42, b43, b44, b45,
+		b46, b47, b48, b49, b50, b51, b52, b53, b54, b55, b56, b57, b58, b59, b60, b61}
 }
@@ -39,9 +39,75 @@ func initApp(ctx context.Context, cfg *config) (*events.TelegramListener, func()
 	mongoRoomRepository := repository.NewRoomRepository(database)
 	roomService := service.NewRoomService(mongoRoomRepository)
 	roomSetName := bot.NewRoomSetName(chatStateService, buttonService, roomService, botConfig)
-	v := ProvideBotList(startScreen, roomCreating, roomSetName)
+	startScreenInitPerson := bot.NewStartScreenInitPerson(chatStateService, buttonService, userService, botConfig)
+	userSetting := bot.NewUserSetting(chatStateService, buttonService, userService, botConfig)
+	userSettingChoose := bot.NewUserSettingChoose(buttonService, botConfig)
+	userSettingBirtDate := bot.NewUserSettingBirtDate(chatStateService, buttonService, botConfig)
+	setBirtDate := bot.NewSetBirtDate(chatStateService, userService, botConfig)
+	allRooms := bot.NewAllRooms(chatStateService, buttonService, roomService, botConfig)
+	searchRoom := bot.NewSearchRoom(chatStateService, buttonService, botConfig)
+	viewRoom := bot.NewViewRoom(buttonService, roomService, chatStateService, botConfig)
+	roomSetting := bot.NewRoomSetting(buttonService, roomService, chatStateService, botConfig)
+	roomRenaming := bot.NewRoomRenaming(chatStateService, buttonService, botConfig)
+	roomRename := bot.NewRoomRename(chatStateService, roomService, botConfig)
+	roomMembers := bot.NewRoomMembers(buttonService, roomService, botConfig)
+	roomMember := bot.NewRoomMember(buttonService, roomService, botConfig)
+	roomConfirm := bot.NewRoomConfirm(buttonService, roomService, botConfig)
+	roomExit := bot.NewRoomExit(roomService, botConfig)
+	bindRoom := bot.NewBindRoom(buttonService, roomService, botConfig)
+	bindRoomChoose := bot.NewBindRoomChoose(buttonService, roomService, botConfig)
+	groupMembers := bot.NewGroupMembers(roomService, botConfig)
+	joinRoom := bot.NewJoinRoom(chatStateService, buttonService, roomService, botConfig)
+	greetingSetting := bot.NewGreetingSetting(buttonService, roomService, chatStateService, botConfig)
+	greetingTemplates := bot.NewGreetingTemplates(buttonService, botConfig)
+	greetingInput := bot.NewGreetingInput(chatStateService, buttonService, botConfig)
+	greetingPreview := bot.NewGreetingPreview(chatStateService, buttonService, roomService, botConfig)
+	roomCurrency := bot.NewRoomCurrency(buttonService, roomService, botConfig)
+	mongoCollectionRepository := repository.NewCollectionRepository(database)
+	unitOfWork := initUnitOfWork(cfg, database)
+	collectionService := service.NewCollectionService(mongoCollectionRepository, mongoRoomRepository, unitOfWork)
+	mongoInviteRepository := repository.NewInviteRepository(database)
+	inviteService := service.NewInviteService(mongoInviteRepository, mongoRoomRepository, unitOfWork)
+	privacyService := service.NewPrivacyService(mongoUserRepository, mongoRoomRepository, collectionService, mongoChatStateRepository, mongoButtonRepository, unitOfWork)
+	debts := bot.NewDebts(buttonService, roomService, collectionService, chatStateService, botConfig)
+	collectionCreating := bot.NewCollectionCreating(buttonService, roomService, chatStateService, botConfig)
+	collectionAmount := bot.NewCollectionAmount(buttonService, roomService, collectionService, chatStateService, botConfig)
+	contributionView := bot.NewContributionView(buttonService, collectionService, userService, chatStateService, botConfig)
+	receiptInput := bot.NewReceiptInput(buttonService, collectionService, userService, chatStateService, botConfig)
+	paymentReview := bot.NewPaymentReview(buttonService, collectionService, userService, botConfig)
+	paymentDetailsSetting := bot.NewPaymentDetailsSetting(chatStateService, buttonService, botConfig)
+	paymentFieldInput := bot.NewPaymentFieldInput(chatStateService, userService, botConfig)
+	paymentQR := bot.NewPaymentQR(collectionService, userService, botConfig)
+	collectionDeadline := bot.NewCollectionDeadline(buttonService, collectionService, botConfig)
+	snoozeReminder := bot.NewSnoozeReminder(collectionService, botConfig)
+	statistics := bot.NewStatistics(buttonService, roomService, collectionService, userService, chatStateService, botConfig)
+	operationHistory := bot.NewOperationHistory(buttonService, roomService, collectionService, chatStateService, botConfig)
+	operationView := bot.NewOperationView(buttonService, roomService, collectionService, chatStateService, botConfig)
+	operationAmount := bot.NewOperationAmount(collectionService, chatStateService, botConfig)
+	expenseRecording := bot.NewExpenseRecording(chatStateService, buttonService, collectionService, botConfig)
+	expenseInput := bot.NewExpenseInput(buttonService, collectionService, chatStateService, botConfig)
+	collectionRuleSetting := bot.NewCollectionRuleSetting(buttonService, roomService, chatStateService, botConfig)
+	collectionRuleChoose := bot.NewCollectionRuleChoose(buttonService, roomService, botConfig)
+	ruleAmountWriting := bot.NewRuleAmountWriting(chatStateService, buttonService, roomService, botConfig)
+	ruleAmountInput := bot.NewRuleAmountInput(chatStateService, roomService, botConfig)
+	assignmentAnswer := bot.NewAssignmentAnswer(buttonService, roomService, collectionService, userService, botConfig)
+	organizerOptOut := bot.NewOrganizerOptOut(roomService, botConfig)
+	jointMerge := bot.NewJointMerge(buttonService, roomService, collectionService, userService, botConfig)
+	roomInvites := bot.NewRoomInvites(buttonService, roomService, inviteService, chatStateService, botConfig)
+	inviteSetting := bot.NewInviteSetting(inviteService, botConfig)
+	inviteJoin := bot.NewInviteJoin(buttonService, inviteService, userService, botConfig)
+	joinRequestReview := bot.NewJoinRequestReview(inviteService, roomService, userService, botConfig)
+	inlineRoomSearch := bot.NewInlineRoomSearch(roomService, botConfig)
+	myData := bot.NewMyData(privacyService, botConfig)
+	forgetMeConfirm := bot.NewForgetMeConfirm(buttonService, botConfig)
+	forgetMe := bot.NewForgetMe(privacyService, botConfig)
+	roomLanguage := bot.NewRoomLanguage(buttonService, roomService, botConfig)
+	v := ProvideBotList(startScreen, roomCreating, roomSetName, startScreenInitPerson, userSetting, userSettingChoose, userSettingBirtDate, setBirtDate, allRooms, searchRoom, viewRoom, roomSetting, roomRenaming, roomRename, roomMembers, roomMember, roomConfirm, roomExit, bindRoom, bindRoomChoose, groupMembers, joinRoom, greetingSetting, greetingTemplates, greetingInput, greetingPreview, roomCurrency, debts, collectionCreating, collectionAmount, contributionView, receiptInput, paymentReview, paymentDetailsSetting, paymentFieldInput, paymentQR, collectionDeadline, snoozeReminder, statistics, operationHistory, operationView, operationAmount, expenseRecording, expenseInput, collectionRuleSetting, collectionRuleChoose, ruleAmountWriting, ruleAmountInput, assignmentAnswer, organizerOptOut, jointMerge, roomInvites, inviteSetting, inviteJoin, joinRequestReview, inlineRoomSearch, myData, forgetMeConfirm, forgetMe, roomLanguage)
 	errorHandler := handler.NewErrorHandler()
-	telegramListener, err := initTelegramConfig(botAPI, v, buttonService, userService, chatStateService, errorHandler)
+	birthdayNotifier := initBirthdayNotifier(cfg, botAPI, roomService, userService, errorHandler)
+	debtNotifier := initDebtNotifier(cfg, botAPI, collectionService, userService, buttonService, errorHandler)
+	collectionScheduler := initCollectionScheduler(cfg, botAPI, roomService, collectionService, userService, buttonService, errorHandler)
+	telegramListener, err := initTelegramConfig(botAPI, v, buttonService, userService, chatStateService, errorHandler, birthdayNotifier, debtNotifier, collectionScheduler)
 	if err != nil {
 		cleanup()
 		return nil, nil, err
@@ -53,8 +119,36 @@ func initApp(ctx context.Context, cfg *config) (*events.TelegramListener, func()
 
 // wire.go:
 
-var bots = wire.NewSet(bot.NewStartScreen, bot.NewRoomSetName, bot.NewRoomCreating)
+var bots = wire.NewSet(bot.NewStartScreen, bot.NewRoomSetName, bot.NewRoomCreating, bot.NewStartScreenInitPerson,
+	bot.NewUserSetting, bot.NewUserSettingChoose, bot.NewUserSettingBirtDate, bot.NewSetBirtDate, bot.NewAllRooms,
+	bot.NewSearchRoom, bot.NewViewRoom, bot.NewRoomSetting, bot.NewRoomRenaming, bot.NewRoomRename, bot.NewRoomMembers,
+	bot.NewRoomMember, bot.NewRoomConfirm, bot.NewRoomExit, bot.NewBindRoom, bot.NewBindRoomChoose, bot.NewGroupMembers,
+	bot.NewJoinRoom, bot.NewGreetingSetting, bot.NewGreetingTemplates, bot.NewGreetingInput, bot.NewGreetingPreview,
+	bot.NewRoomCurrency, bot.NewDebts, bot.NewCollectionCreating, bot.NewCollectionAmount, bot.NewContributionView,
+	bot.NewReceiptInput, bot.NewPaymentReview, bot.NewPaymentDetailsSetting, bot.NewPaymentFieldInput, bot.NewPaymentQR,
+	bot.NewCollectionDeadline, bot.NewSnoozeReminder, bot.NewStatistics,
+	bot.NewOperationHistory, bot.NewOperationView, bot.NewOperationAmount, bot.NewExpenseRecording, bot.NewExpenseInput,
+	bot.NewCollectionRuleSetting, bot.NewCollectionRuleChoose, bot.NewRuleAmountWriting, bot.NewRuleAmountInput,
+	bot.NewAssignmentAnswer, bot.NewOrganizerOptOut, bot.NewJointMerge, bot.NewRoomInvites, bot.NewInviteSetting,
+	bot.NewInviteJoin, bot.NewJoinRequestReview, bot.NewInlineRoomSearch, bot.NewMyData, bot.NewForgetMeConfirm,
+	bot.NewForgetMe, bot.NewRoomLanguage)
 
-func ProvideBotList(b2 *bot.StartScreen, b3 *bot.RoomCreating, b4 *bot.RoomSetName) []bot.Interface {
-	return []bot.Interface{b2, b3, b4}
+func ProvideBotList(b2 *bot.StartScreen, b3 *bot.RoomCreating, b4 *bot.RoomSetName, b5 *bot.StartScreenInitPerson,
+	b6 *bot.UserSetting, b7 *bot.UserSettingChoose, b8 *bot.UserSettingBirtDate, b9 *bot.SetBirtDate, b10 *bot.AllRooms,
+	b11 *bot.SearchRoom, b12 *bot.ViewRoom, b13 *bot.RoomSetting, b14 *bot.RoomRenaming, b15 *bot.RoomRename,
+	b16 *bot.RoomMembers, b17 *bot.RoomMember, b18 *bot.RoomConfirm, b19 *bot.RoomExit, b20 *bot.BindRoom,
+	b21 *bot.BindRoomChoose, b22 *bot.GroupMembers, b23 *bot.JoinRoom, b24 *bot.GreetingSetting,
+	b25 *bot.GreetingTemplates, b26 *bot.GreetingInput, b27 *bot.GreetingPreview, b28 *bot.RoomCurrency, b29 *bot.Debts,
+	b30 *bot.CollectionCreating, b31 *bot.CollectionAmount, b32 *bot.ContributionView, b33 *bot.ReceiptInput,
+	b34 *bot.PaymentReview, b35 *bot.PaymentDetailsSetting, b36 *bot.PaymentFieldInput, b37 *bot.PaymentQR,
+	b38 *bot.CollectionDeadline, b39 *bot.SnoozeReminder, b40 *bot.Statistics,
+	b41 *bot.OperationHistory, b42 *bot.OperationView, b43 *bot.OperationAmount, b44 *bot.ExpenseRecording,
+	b45 *bot.ExpenseInput, b46 *bot.CollectionRuleSetting, b47 *bot.CollectionRuleChoose, b48 *bot.RuleAmountWriting,
+	b49 *bot.RuleAmountInput, b50 *bot.AssignmentAnswer, b51 *bot.OrganizerOptOut,
+	b52 *bot.JointMerge, b53 *bot.RoomInvites, b54 *bot.InviteSetting, b55 *bot.InviteJoin,
+	b56 *bot.JoinRequestReview, b57 *bot.InlineRoomSearch, b58 *bot.MyData, b59 *bot.ForgetMeConfirm,
+	b60 *bot.ForgetMe, b61 *bot.RoomLanguage) []bot.Interface {
+	return []bot.Interface{b2, b3, b4, b5, b6, b7, b8, b9, b10, b11, b12, b13, b14, b15, b16, b17, b18, b19, b20, b21,
+		b22, b23, b24, b25, b26, b27, b28, b29, b30, b31, b32, b33, b34, b35, b36, b37, b38, b39, b40, b41, b42, b43, b44, b45,
+		b46, b47, b48, b49, b50, b51, b52, b53, b54, b55, b56, b57, b58, b59, b60, b61}
 }
@@ -0,0 +1,117 @@
+// Command langconv converts lang files from printf verbs to the message format of the bot:
+// %s, %v and %d become {0}, {1}, ... in the order of verbs, literal braces and apostrophes before them are quoted.
+// Files are rewritten in place and marked, so converted files are skipped.
+//
+//	go run ./cmd/langconv conf/lang/*.ini
+//
+// Plurals, dates and named arguments are added by hand after the conversion
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// formatMark is the first line of converted files
+const formatMark = "; format: icu"
+
+var quoted = regexp.MustCompile(`^(['"]).*(['"])$`)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "Usage: langconv <lang file>...")
+		os.Exit(2)
+	}
+	for _, name := range os.Args[1:] {
+		converted, err := convertFile(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			os.Exit(1)
+		}
+		if converted {
+			fmt.Printf("%s converted\n", name)
+		} else {
+			fmt.Printf("%s is already converted\n", name)
+		}
+	}
+}
+
+func convertFile(name string) (bool, error) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return false, err
+	}
+	lines := strings.Split(string(b), "\n")
+	if len(lines) > 0 && strings.TrimSpace(lines[0]) == formatMark {
+		return false, nil
+	}
+
+	for i, line := range lines {
+		eq := strings.Index(line, "=")
+		if strings.HasPrefix(strings.TrimSpace(line), ";") || eq < 0 {
+			continue
+		}
+		key, value := line[:eq+1], strings.TrimSpace(line[eq+1:])
+		value, err := convertValue(value)
+		if err != nil {
+			return false, fmt.Errorf("line %v: %v", i+1, err)
+		}
+		// the ini parser strips quotes around the value
+		if quoted.MatchString(value) {
+			value = `"` + value + `"`
+		}
+		lines[i] = key + " " + value
+	}
+	lines = append([]string{formatMark}, lines...)
+	return true, ioutil.WriteFile(name, []byte(strings.Join(lines, "\n")), 0644)
+}
+
+// convertValue replaces printf verbs with positional arguments and quotes text which is special in the new format
+func convertValue(value string) (string, error) {
+	s := []rune(value)
+	var b strings.Builder
+	arg := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '%':
+			if i+1 == len(s) {
+				return "", fmt.Errorf("verb expected after %%")
+			}
+			i++
+			switch s[i] {
+			case '%':
+				b.WriteRune('%')
+			case 's', 'v', 'd':
+				fmt.Fprintf(&b, "{%d}", arg)
+				arg++
+			default:
+				return "", fmt.Errorf("unsupported verb %%%c", s[i])
+			}
+		case '\'':
+			b.WriteRune('\'')
+			if i+1 < len(s) && (s[i+1] == '{' || s[i+1] == '}' || s[i+1] == '\'') {
+				b.WriteRune('\'')
+			}
+		case '{':
+			// the literal {name} is quoted as a whole, it reads better than quoting every brace
+			end := i + 1
+			for end < len(s) && !strings.ContainsRune("{}%'", s[end]) {
+				end++
+			}
+			if end == len(s) || s[end] != '}' {
+				b.WriteString("'{'")
+				continue
+			}
+			b.WriteString("'" + string(s[i:end+1]) + "'")
+			i = end
+		case '}':
+			b.WriteString("'}'")
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String(), nil
+}
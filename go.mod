@@ -5,14 +5,16 @@ go 1.16
 require (
 	github.com/caarlos0/env/v6 v6.4.0
 	github.com/go-pkgz/syncs v1.1.1
-	github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.4.0-beta.0 // indirect
+	github.com/go-telegram-bot-api/telegram-bot-api/v5 v5.4.0-beta.0
 	github.com/google/wire v0.4.0
 	github.com/gookit/i18n v1.1.3
 	github.com/pkg/errors v0.9.1
 	github.com/rs/zerolog v1.20.0
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 	github.com/technoweenie/multipartstreamer v1.0.1 // indirect
 	github.com/xlab/closer v0.0.0-20190328110542-03326addb7c2
 	go.mongodb.org/mongo-driver v1.4.4
 	golang.org/x/crypto v0.0.0-20200820211705-5c72a883971a // indirect
 	golang.org/x/text v0.3.3
+	gopkg.in/yaml.v3 v3.0.1
 )
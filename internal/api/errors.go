@@ -0,0 +1,32 @@
+package api
+
+import "errors"
+
+var (
+	// ErrForbidden is returned when user has not enough rights in the room
+	ErrForbidden = errors.New("not enough rights")
+	// ErrNotMember is returned when operation target is not a room member
+	ErrNotMember = errors.New("user is not a room member")
+	// ErrOwnerLeave is returned when the owner leaves the room with other members
+	ErrOwnerLeave = errors.New("owner must transfer ownership before leaving")
+	// ErrWrongAmount is returned when money amount can't be parsed
+	ErrWrongAmount = errors.New("wrong money amount")
+	// ErrCurrencyMismatch is returned when amounts of different currencies are summed
+	ErrCurrencyMismatch = errors.New("currencies of amounts differ")
+	// ErrNotPending is returned when the payment is reviewed twice
+	ErrNotPending = errors.New("payment is not waiting for review")
+	// ErrWrongPaymentField is returned when payment details can't be recognized
+	ErrWrongPaymentField = errors.New("wrong payment details")
+	// ErrUnknownCurrency is returned when currency is not supported
+	ErrUnknownCurrency = errors.New("unknown currency")
+	// ErrAlreadyReverted is returned when the operation is undone twice
+	ErrAlreadyReverted = errors.New("operation is already undone")
+	// ErrAlreadySettled is returned when the expense of the collection is recorded twice
+	ErrAlreadySettled = errors.New("expense is already recorded")
+	// ErrAlreadyOpened is returned when the organizer declines the assignment after the collection is opened
+	ErrAlreadyOpened = errors.New("collection is already opened")
+	// ErrInvalidInvite is returned when the invite code is unknown, revoked, expired or used up
+	ErrInvalidInvite = errors.New("invite is invalid")
+	// ErrNoJoinRequest is returned when the join request is reviewed twice
+	ErrNoJoinRequest = errors.New("join request is already reviewed")
+)
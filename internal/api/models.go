@@ -6,17 +6,309 @@ import (
 )
 
 type Room struct {
-	ID       primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Name     string             `json:"name" bson:"name"`
-	Chat     Chat               `json:"chat" bson:"chat"`
-	Members  *[]User            `json:"users" bson:"users"`
-	CreateAt time.Time          `json:"createAt" bson:"create_at"`
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name      string             `json:"name" bson:"name"`
+	Chat      Chat               `json:"chat" bson:"chat"`
+	Members   *[]User            `json:"users" bson:"users"`
+	Owner     int64              `json:"owner" bson:"owner"`
+	Admins    []int64            `json:"admins" bson:"admins"`
+	GreetedAt *time.Time         `json:"greetedAt" bson:"greeted_at,omitempty"`
+	Currency  string             `json:"currency" bson:"currency,omitempty"`
+	CreateAt  time.Time          `json:"createAt" bson:"create_at"`
+	// SearchName is the name normalized by NormalizeSearch, rooms are searched by it
+	SearchName string `json:"-" bson:"search_name"`
+	// Lang is the language of posts in the group chat, empty means the language of every reader
+	Lang string `json:"lang" bson:"lang,omitempty"`
+
+	Greeting      *Greeting `json:"greeting" bson:"greeting,omitempty"`
+	GreetingDraft *Greeting `json:"greetingDraft" bson:"greeting_draft,omitempty"`
+
+	CollectionRule *CollectionRule `json:"collectionRule" bson:"collection_rule,omitempty"`
+	// OrganizerOptOuts are members who are never assigned as organizers by the rotation
+	OrganizerOptOuts []int64 `json:"organizerOptOuts" bson:"organizer_opt_outs,omitempty"`
+	// JoinRequests are users who came by an invite with approval and wait for an admin
+	JoinRequests []User `json:"joinRequests" bson:"join_requests,omitempty"`
+}
+
+// Invite lets users join the room by the deep link with the Code, MaxUses 0 means unlimited uses.
+// Users who came by an invite with Approval wait for an admin
+type Invite struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Code      string             `json:"code" bson:"code"`
+	RoomId    string             `json:"roomId" bson:"room_id"`
+	CreatedBy int64              `json:"createdBy" bson:"created_by"`
+	ExpiresAt *time.Time         `json:"expiresAt" bson:"expires_at,omitempty"`
+	MaxUses   int                `json:"maxUses" bson:"max_uses"`
+	Uses      int                `json:"uses" bson:"uses"`
+	Approval  bool               `json:"approval" bson:"approval"`
+	Revoked   bool               `json:"revoked" bson:"revoked"`
+	CreateAt  time.Time          `json:"createAt" bson:"create_at"`
+}
+
+// IsValid checks that the invite is not revoked, expired or used up
+func (i *Invite) IsValid(now time.Time) bool {
+	return !i.Revoked && (i.ExpiresAt == nil || now.Before(*i.ExpiresAt)) && (i.MaxUses == 0 || i.Uses < i.MaxUses)
+}
+
+// CollectionRule opens a collection automatically DaysBefore days before the birthday of every member.
+// Organizer is a fixed organizer, without it organizers are assigned by the rotation, LastOrganizer breaks ties of the load
+type CollectionRule struct {
+	Amount        Money `json:"amount" bson:"amount"`
+	DaysBefore    int   `json:"daysBefore" bson:"days_before"`
+	Organizer     int64 `json:"organizer" bson:"organizer,omitempty"`
+	LastOrganizer int64 `json:"lastOrganizer" bson:"last_organizer,omitempty"`
+	Enabled       bool  `json:"enabled" bson:"enabled"`
+}
+
+// Greeting describes birthday greeting posted by the room, TemplateKey refers to a localized template,
+// Text is a template written by organizer
+type Greeting struct {
+	TemplateKey string `json:"templateKey" bson:"template_key,omitempty"`
+	Text        string `json:"text" bson:"text,omitempty"`
+	PhotoId     string `json:"photoId" bson:"photo_id,omitempty"`
+	VideoId     string `json:"videoId" bson:"video_id,omitempty"`
+	ToChat      bool   `json:"toChat" bson:"to_chat"`
+	ToPrivate   bool   `json:"toPrivate" bson:"to_private"`
+}
+
+// CurrencyCode returns ISO 4217 code of the room currency
+func (r *Room) CurrencyCode() string {
+	if r.Currency == "" {
+		return DefaultCurrency
+	}
+	return r.Currency
+}
+
+// IsOwner checks that user owns the room
+func (r *Room) IsOwner(userId int64) bool {
+	return r.Owner == userId
+}
+
+// IsAdmin checks that user is the room owner or one of admins
+func (r *Room) IsAdmin(userId int64) bool {
+	if r.IsOwner(userId) {
+		return true
+	}
+	for _, id := range r.Admins {
+		if id == userId {
+			return true
+		}
+	}
+	return false
+}
+
+// IsMember checks that user is in the room
+func (r *Room) IsMember(userId int64) bool {
+	if r.Members == nil {
+		return false
+	}
+	for _, u := range *r.Members {
+		if u.ID == userId {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOptedOut checks that the member doesn't want to organize collections of the room
+func (r *Room) IsOptedOut(userId int64) bool {
+	for _, id := range r.OrganizerOptOuts {
+		if id == userId {
+			return true
+		}
+	}
+	return false
+}
+
+// FindMember returns a copy of the room member, nil if user is not in the room
+func (r *Room) FindMember(userId int64) *User {
+	if r.Members == nil {
+		return nil
+	}
+	for _, u := range *r.Members {
+		if u.ID == userId {
+			u := u
+			return &u
+		}
+	}
+	return nil
 }
 
 type Debt struct {
 	Lender *User `json:"lender" bson:"lender"`
 	Debtor *User `json:"debtor" bson:"debtor"`
-	Sum    int   `json:"sum" bson:"sum"`
+	Sum    Money `json:"sum" bson:"sum"`
+}
+
+// Collection gathers money from room members, usually for a birthday gift of the Celebrant.
+// Amount is a contribution of every member, Birthday is set for collections opened by the room rule
+type Collection struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	RoomId    string             `json:"roomId" bson:"room_id"`
+	Celebrant *User              `json:"celebrant" bson:"celebrant,omitempty"`
+	Organizer int64              `json:"organizer" bson:"organizer"`
+	Amount    Money              `json:"amount" bson:"amount"`
+	Deadline  *time.Time         `json:"deadline" bson:"deadline,omitempty"`
+	DigestAt  *time.Time         `json:"digestAt" bson:"digest_at,omitempty"`
+	Birthday  *time.Time         `json:"birthday" bson:"birthday,omitempty"`
+	Expense   *Money             `json:"expense" bson:"expense,omitempty"`
+	SettledAt *time.Time         `json:"settledAt" bson:"settled_at,omitempty"`
+	// JointId refers to the joint collection of another room, contributions are moved there and operations are kept
+	JointId  *primitive.ObjectID `json:"jointId" bson:"joint_id,omitempty"`
+	CreateAt time.Time           `json:"createAt" bson:"create_at"`
+}
+
+type AssignmentStatus string
+
+const (
+	AssignmentPending    AssignmentStatus = "pending"
+	AssignmentAccepted   AssignmentStatus = "accepted"
+	AssignmentUnassigned AssignmentStatus = "unassigned"
+)
+
+// Assignment appoints the organizer of the collection for the upcoming birthday of the Celebrant.
+// Declined members are not offered again, the assignment is closed when the collection is opened
+type Assignment struct {
+	ID           primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	RoomId       string              `json:"roomId" bson:"room_id"`
+	Celebrant    User                `json:"celebrant" bson:"celebrant"`
+	Birthday     time.Time           `json:"birthday" bson:"birthday"`
+	Organizer    int64               `json:"organizer" bson:"organizer"`
+	Status       AssignmentStatus    `json:"status" bson:"status"`
+	Declined     []int64             `json:"declined" bson:"declined,omitempty"`
+	CollectionId *primitive.ObjectID `json:"collectionId" bson:"collection_id,omitempty"`
+	CreateAt     time.Time           `json:"createAt" bson:"create_at"`
+}
+
+type ContributionStatus string
+
+const (
+	ContributionOutstanding ContributionStatus = "outstanding"
+	ContributionPending     ContributionStatus = "pending"
+	ContributionApproved    ContributionStatus = "approved"
+	ContributionRejected    ContributionStatus = "rejected"
+)
+
+// ContributionKind distinguishes settlement debts created after the expense from shares of the collection
+type ContributionKind string
+
+const (
+	ContributionShare  ContributionKind = ""
+	ContributionExtra  ContributionKind = "extra"
+	ContributionRefund ContributionKind = "refund"
+)
+
+// Contribution is a share of the room member in the collection, Receipt is attached by the member when paid.
+// A refund is paid by the organizer to the Recipient, other contributions are paid to the organizer
+type Contribution struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	CollectionId primitive.ObjectID `json:"collectionId" bson:"collection_id"`
+	RoomId       string             `json:"roomId" bson:"room_id"`
+	User         User               `json:"user" bson:"user"`
+	Kind         ContributionKind   `json:"kind" bson:"kind,omitempty"`
+	Recipient    *User              `json:"recipient" bson:"recipient,omitempty"`
+	Amount       Money              `json:"amount" bson:"amount"`
+	Status       ContributionStatus `json:"status" bson:"status"`
+	Receipt      *Receipt           `json:"receipt" bson:"receipt,omitempty"`
+	PaidAt       *time.Time         `json:"paidAt" bson:"paid_at,omitempty"`
+	ReviewedBy   int64              `json:"reviewedBy" bson:"reviewed_by,omitempty"`
+	ReviewedAt   *time.Time         `json:"reviewedAt" bson:"reviewed_at,omitempty"`
+	RemindedAt   *time.Time         `json:"remindedAt" bson:"reminded_at,omitempty"`
+	Reminders    int                `json:"reminders" bson:"reminders,omitempty"`
+	SnoozedUntil *time.Time         `json:"snoozedUntil" bson:"snoozed_until,omitempty"`
+	CreateAt     time.Time          `json:"createAt" bson:"create_at"`
+}
+
+// Receipt is a screenshot or a PDF document confirming the payment
+type Receipt struct {
+	FileId     string `json:"fileId" bson:"file_id"`
+	IsDocument bool   `json:"isDocument" bson:"is_document"`
+}
+
+// IsDebt checks that the contribution is still not paid, rejected payments return to debts
+func (c *Contribution) IsDebt() bool {
+	return c.Status == ContributionOutstanding || c.Status == ContributionRejected
+}
+
+// Payee returns id of the user receiving the payment
+func (c *Contribution) Payee(collection *Collection) int64 {
+	if c.Recipient != nil {
+		return c.Recipient.ID
+	}
+	return collection.Organizer
+}
+
+type OperationType string
+
+const (
+	OperationContribution OperationType = "contribution"
+	OperationRefund       OperationType = "refund"
+	OperationTarget       OperationType = "target"
+	OperationExpense      OperationType = "expense"
+)
+
+// Operation is an immutable record of a financial event of the collection, it is never changed or deleted.
+// Undo writes a compensating operation with the negated Amount which refers to the undone one by RevertOf.
+// Amount of a target operation is a change of the contribution amount of every member
+type Operation struct {
+	ID             primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	RoomId         string              `json:"roomId" bson:"room_id"`
+	CollectionId   primitive.ObjectID  `json:"collectionId" bson:"collection_id"`
+	ContributionId *primitive.ObjectID `json:"contributionId" bson:"contribution_id,omitempty"`
+	Type           OperationType       `json:"type" bson:"type"`
+	Author         User                `json:"author" bson:"author"`
+	User           *User               `json:"user" bson:"user,omitempty"`
+	Amount         Money               `json:"amount" bson:"amount"`
+	Receipt        *Receipt            `json:"receipt" bson:"receipt,omitempty"`
+	RevertOf       *primitive.ObjectID `json:"revertOf" bson:"revert_of,omitempty"`
+	CreateAt       time.Time           `json:"createAt" bson:"create_at"`
+}
+
+// IsEditable checks that the operation can be undone or edited: compensations, expenses, operations
+// of collections merged into joint ones and of settled collections are final, the settlement is made from them
+func (op *Operation) IsEditable(collection *Collection) bool {
+	return op.RevertOf == nil && op.Type != OperationExpense && collection.JointId == nil && collection.Expense == nil
+}
+
+// RoomStatistics is a summary of collections of the room, amounts are grouped by currency
+type RoomStatistics struct {
+	CollectedByYear []YearTotal
+	Average         []Money
+	Members         []MemberStatistics
+}
+
+type YearTotal struct {
+	Year   int
+	Totals []Money
+}
+
+// MemberStatistics counts payments of the member, Due are contributions paid or overdue by now
+type MemberStatistics struct {
+	User   User
+	Due    int
+	OnTime int
+	Total  []Money
+}
+
+// UserData is everything stored about the user, it is exported on request of the user
+type UserData struct {
+	Profile       *User          `json:"profile"`
+	Rooms         []UserRoom     `json:"rooms"`
+	Contributions []Contribution `json:"contributions"`
+	Operations    []Operation    `json:"operations"`
+	Assignments   []Assignment   `json:"assignments"`
+	Organized     []Collection   `json:"organizedCollections"`
+	ChatState     *ChatState     `json:"chatState"`
+	ExportedAt    time.Time      `json:"exportedAt"`
+}
+
+// UserRoom is the room of the user with the role and settings of the user in it
+type UserRoom struct {
+	ID              primitive.ObjectID `json:"id"`
+	Name            string             `json:"name"`
+	Role            string             `json:"role"`
+	Archived        bool               `json:"archived"`
+	OrganizerOptOut bool               `json:"organizerOptOut"`
 }
 
 // ChatState stores user state
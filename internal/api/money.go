@@ -0,0 +1,255 @@
+package api
+
+import (
+	"golang.org/x/text/language"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is used for rooms created before currencies were introduced
+const DefaultCurrency = "RUB"
+
+// Currency describes ISO 4217 currency, Digits is a count of minor unit digits
+type Currency struct {
+	Code   string
+	Symbol string
+	Digits int
+}
+
+// Currencies lists currencies which can be chosen for the room
+var Currencies = []Currency{
+	{Code: "RUB", Symbol: "₽", Digits: 2},
+	{Code: "USD", Symbol: "$", Digits: 2},
+	{Code: "EUR", Symbol: "€", Digits: 2},
+	{Code: "GBP", Symbol: "£", Digits: 2},
+	{Code: "KZT", Symbol: "₸", Digits: 2},
+	{Code: "UAH", Symbol: "₴", Digits: 2},
+	{Code: "BYN", Symbol: "Br", Digits: 2},
+	{Code: "TRY", Symbol: "₺", Digits: 2},
+	{Code: "GEL", Symbol: "₾", Digits: 2},
+	{Code: "AMD", Symbol: "֏", Digits: 2},
+	{Code: "JPY", Symbol: "¥", Digits: 0},
+}
+
+// FindCurrency returns currency by ISO 4217 code
+func FindCurrency(code string) (Currency, bool) {
+	for _, c := range Currencies {
+		if c.Code == code {
+			return c, true
+		}
+	}
+	return Currency{}, false
+}
+
+// Money is an amount in minor units (kopecks, cents) of the currency
+type Money struct {
+	Amount   int64  `json:"amount" bson:"amount"`
+	Currency string `json:"currency" bson:"currency"`
+}
+
+// NewMoney makes money from major units, e.g. NewMoney(15, "USD") is $15.00
+func NewMoney(major int64, currency string) Money {
+	return Money{Amount: major * minorFactor(currency), Currency: currency}
+}
+
+// Add sums amounts of the same currency
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}, nil
+}
+
+// Split divides the amount into n parts differing by one minor unit at most, the first parts are greater
+// by absolute value. The sum of parts is always equal to the amount
+func (m Money) Split(n int) []Money {
+	if n <= 0 {
+		return nil
+	}
+	parts := make([]Money, n)
+	base, rest := m.Amount/int64(n), m.Amount%int64(n)
+	for i := range parts {
+		parts[i] = Money{Amount: base, Currency: m.Currency}
+		if int64(i) < rest {
+			parts[i].Amount++
+		} else if int64(i) < -rest {
+			parts[i].Amount--
+		}
+	}
+	return parts
+}
+
+// IsZero checks that amount is zero
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Format formats money by rules of the language: "$1,500.50" for english and "1 500,50 ₽" for russian
+func (m Money) Format(lang string) string {
+	c, ok := FindCurrency(m.Currency)
+	if !ok {
+		c = Currency{Code: m.Currency, Symbol: m.Currency, Digits: 2}
+	}
+	group, decimal := ",", "."
+	if lang == language.Russian.String() {
+		group, decimal = " ", ","
+	}
+
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	factor := minorFactor(c.Code)
+	s := groupDigits(strconv.FormatInt(amount/factor, 10), group)
+	if c.Digits > 0 {
+		s += decimal + leftPad(strconv.FormatInt(amount%factor, 10), c.Digits)
+	}
+
+	if lang == language.Russian.String() {
+		return sign + s + " " + c.Symbol
+	}
+	if len([]rune(c.Symbol)) > 1 {
+		return sign + s + " " + c.Symbol
+	}
+	return sign + c.Symbol + s
+}
+
+// ParseMoney parses user input such as "1500", "1 500,50", "1,500.50" or "1.5k" in the currency.
+// A single separator followed by exactly three digits is treated as thousands separator. The decimal separator
+// is the last one and is used once, thousands separators must split the whole part by three digits
+func ParseMoney(s string, currency string) (Money, error) {
+	c, ok := FindCurrency(currency)
+	if !ok {
+		return Money{}, ErrUnknownCurrency
+	}
+
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.NewReplacer(" ", "", "\u00a0", "", "\u202f", "", "'", "").Replace(s)
+	scale := 0
+	for suffix, exp := range map[string]int{"k": 3, "к": 3, "m": 6, "м": 6} {
+		if strings.HasSuffix(s, suffix) {
+			s, scale = strings.TrimSuffix(s, suffix), exp
+			break
+		}
+	}
+	if s == "" {
+		return Money{}, ErrWrongAmount
+	}
+
+	whole, frac, group := s, "", ""
+	lastDot, lastComma := strings.LastIndex(s, "."), strings.LastIndex(s, ",")
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		sep := lastDot
+		group = ","
+		if lastComma > lastDot {
+			sep, group = lastComma, "."
+		}
+		whole, frac = s[:sep], s[sep+1:]
+	case lastDot >= 0 || lastComma >= 0:
+		sep := lastDot + lastComma + 1
+		count := strings.Count(s, s[sep:sep+1])
+		if count == 1 && (len(s)-sep-1 != 3 || scale > 0) {
+			whole, frac = s[:sep], s[sep+1:]
+		} else {
+			group = s[sep : sep+1]
+		}
+	}
+	if group != "" {
+		if !isGrouped(whole, group) {
+			return Money{}, ErrWrongAmount
+		}
+		whole = strings.ReplaceAll(whole, group, "")
+	}
+	if whole == "" || !isDigits(whole) || !isDigits(frac) {
+		return Money{}, ErrWrongAmount
+	}
+
+	digits := whole + frac
+	shift := c.Digits + scale - len(frac)
+	if shift >= 0 {
+		digits += strings.Repeat("0", shift)
+	} else {
+		cut := len(digits) + shift
+		if strings.Trim(digits[cut:], "0") != "" {
+			return Money{}, ErrWrongAmount
+		}
+		digits = digits[:cut]
+	}
+	digits = strings.TrimLeft(digits, "0")
+	if digits == "" {
+		return Money{}, ErrWrongAmount
+	}
+	amount, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Money{}, ErrWrongAmount
+	}
+	return Money{Amount: amount, Currency: c.Code}, nil
+}
+
+// SumByCurrency sums amounts of every currency separately, amounts of different currencies are never summed.
+// The result is ordered by currency code
+func SumByCurrency(ms []Money) []Money {
+	totals := map[string]int64{}
+	for _, m := range ms {
+		totals[m.Currency] += m.Amount
+	}
+	res := make([]Money, 0, len(totals))
+	for code, amount := range totals {
+		res = append(res, Money{Amount: amount, Currency: code})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Currency < res[j].Currency })
+	return res
+}
+
+func minorFactor(currency string) int64 {
+	digits := 2
+	if c, ok := FindCurrency(currency); ok {
+		digits = c.Digits
+	}
+	f := int64(1)
+	for i := 0; i < digits; i++ {
+		f *= 10
+	}
+	return f
+}
+
+func groupDigits(s string, sep string) string {
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + sep + s[i:]
+	}
+	return s
+}
+
+func leftPad(s string, width int) string {
+	if len(s) >= width {
+		return s
+	}
+	return strings.Repeat("0", width-len(s)) + s
+}
+
+// isGrouped checks that the separator splits digits into groups of three, the first group may be shorter
+// and doesn't start with zero
+func isGrouped(s string, sep string) bool {
+	groups := strings.Split(s, sep)
+	if len(groups[0]) == 0 || len(groups[0]) > 3 || groups[0][0] == '0' {
+		return false
+	}
+	for _, g := range groups[1:] {
+		if len(g) != 3 {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
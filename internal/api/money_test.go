@@ -0,0 +1,100 @@
+package api
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input    string
+		currency string
+		want     int64
+		err      error
+	}{
+		{"1500", "RUB", 150000, nil},
+		{" 1 500,50 ", "RUB", 150050, nil},
+		{"1 500", "RUB", 150000, nil},
+		{"1,500.50", "USD", 150050, nil},
+		{"1.500,50", "EUR", 150050, nil},
+		{"1'500.5", "USD", 150050, nil},
+		{"1,500", "USD", 150000, nil},
+		{"1.500", "RUB", 150000, nil},
+		{"1,000,000", "USD", 100000000, nil},
+		{"1.000.000,25", "RUB", 100000025, nil},
+		{"1,5", "RUB", 150, nil},
+		{"0.99", "USD", 99, nil},
+		{"1.5k", "RUB", 150000, nil},
+		{"2К", "RUB", 200000, nil},
+		{"1,500k", "USD", 150000, nil},
+		{"1m", "USD", 100000000, nil},
+		{"1500", "JPY", 1500, nil},
+		{"1.50", "JPY", 0, ErrWrongAmount},
+		{"1.001", "USD", 100100, nil},
+		{"0.001", "USD", 0, ErrWrongAmount},
+		{"1.5.0", "RUB", 0, ErrWrongAmount},
+		{"1,5,0.5", "RUB", 0, ErrWrongAmount},
+		{"1.5,0.5", "RUB", 0, ErrWrongAmount},
+		{"1,50,000.5", "USD", 0, ErrWrongAmount},
+		{"12345,678.9", "USD", 0, ErrWrongAmount},
+		{",5", "RUB", 0, ErrWrongAmount},
+		{"0", "RUB", 0, ErrWrongAmount},
+		{"-5", "RUB", 0, ErrWrongAmount},
+		{"abc", "RUB", 0, ErrWrongAmount},
+		{"k", "RUB", 0, ErrWrongAmount},
+		{"", "RUB", 0, ErrWrongAmount},
+		{"99999999999999999999", "RUB", 0, ErrWrongAmount},
+		{"100", "XXX", 0, ErrUnknownCurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseMoney(tt.input, tt.currency)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ParseMoney(%q, %s) error = %v, want %v", tt.input, tt.currency, err, tt.err)
+			}
+			if tt.err == nil && (got.Amount != tt.want || got.Currency != tt.currency) {
+				t.Errorf("ParseMoney(%q, %s) = %v, want %v %s", tt.input, tt.currency, got, tt.want, tt.currency)
+			}
+		})
+	}
+}
+
+func TestMoneySplit(t *testing.T) {
+	tests := []struct {
+		amount int64
+		n      int
+		want   []int64
+	}{
+		{100, 1, []int64{100}},
+		{100, 3, []int64{34, 33, 33}},
+		{101, 4, []int64{26, 25, 25, 25}},
+		{103, 4, []int64{26, 26, 26, 25}},
+		{2, 5, []int64{1, 1, 0, 0, 0}},
+		{0, 3, []int64{0, 0, 0}},
+		{-100, 1, []int64{-100}},
+		{-100, 3, []int64{-34, -33, -33}},
+		{-7, 4, []int64{-2, -2, -2, -1}},
+		{-1, 2, []int64{-1, 0}},
+		{100, 0, nil},
+		{100, -1, nil},
+	}
+	for _, tt := range tests {
+		parts := Money{Amount: tt.amount, Currency: "RUB"}.Split(tt.n)
+		if len(parts) != len(tt.want) {
+			t.Fatalf("Split(%v, %v) has %v parts, want %v", tt.amount, tt.n, len(parts), len(tt.want))
+		}
+		var sum int64
+		for i, p := range parts {
+			if p.Currency != "RUB" {
+				t.Errorf("Split(%v, %v)[%v] currency = %s, want RUB", tt.amount, tt.n, i, p.Currency)
+			}
+			if p.Amount != tt.want[i] {
+				t.Errorf("Split(%v, %v)[%v] = %v, want %v", tt.amount, tt.n, i, p.Amount, tt.want[i])
+			}
+			sum += p.Amount
+		}
+		if tt.n > 0 && sum != tt.amount {
+			t.Errorf("Split(%v, %v) sums to %v", tt.amount, tt.n, sum)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// PaymentDetails are bank details of the organizer shown to members paying debts
+type PaymentDetails struct {
+	Recipient string `json:"recipient" bson:"recipient,omitempty"`
+	Phone     string `json:"phone" bson:"phone,omitempty"`
+	Card      string `json:"card" bson:"card,omitempty"`
+	Bank      string `json:"bank" bson:"bank,omitempty"`
+	Account   string `json:"account" bson:"account,omitempty"`
+	BIC       string `json:"bic" bson:"bic,omitempty"`
+	IBAN      string `json:"iban" bson:"iban,omitempty"`
+	Link      string `json:"link" bson:"link,omitempty"`
+}
+
+// PaymentFields lists bson names of PaymentDetails fields in the order they are shown to the user
+var PaymentFields = []string{"recipient", "phone", "card", "bank", "account", "bic", "iban", "link"}
+
+var (
+	phoneRe   = regexp.MustCompile(`^\+?\d{10,15}$`)
+	cardRe    = regexp.MustCompile(`^\d{13,19}$`)
+	accountRe = regexp.MustCompile(`^\d{20}$`)
+	bicRe     = regexp.MustCompile(`^(\d{9}|[A-Z]{6}[A-Z0-9]{2}([A-Z0-9]{3})?)$`)
+	ibanRe    = regexp.MustCompile(`^[A-Z]{2}\d{2}[A-Z0-9]{11,30}$`)
+)
+
+// NormalizePaymentField validates the value written by the user and strips spaces and dashes of numbers
+func NormalizePaymentField(field string, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	compact := strings.ToUpper(strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(value))
+	switch field {
+	case "recipient", "bank":
+		if value == "" || len([]rune(value)) > 140 || strings.ContainsAny(value, "|\n") {
+			return "", ErrWrongPaymentField
+		}
+		return value, nil
+	case "phone":
+		if !phoneRe.MatchString(compact) {
+			return "", ErrWrongPaymentField
+		}
+		if strings.HasPrefix(compact, "8") && len(compact) == 11 {
+			compact = "+7" + compact[1:]
+		} else if !strings.HasPrefix(compact, "+") {
+			compact = "+" + compact
+		}
+		return compact, nil
+	case "card":
+		if !cardRe.MatchString(compact) || !luhn(compact) {
+			return "", ErrWrongPaymentField
+		}
+		return compact, nil
+	case "account":
+		if !accountRe.MatchString(compact) {
+			return "", ErrWrongPaymentField
+		}
+		return compact, nil
+	case "bic":
+		if !bicRe.MatchString(compact) {
+			return "", ErrWrongPaymentField
+		}
+		return compact, nil
+	case "iban":
+		if !ibanRe.MatchString(compact) || !ibanChecksum(compact) {
+			return "", ErrWrongPaymentField
+		}
+		return compact, nil
+	case "link":
+		if !(strings.HasPrefix(value, "https://") || strings.HasPrefix(value, "http://")) || strings.ContainsAny(value, " \n") {
+			return "", ErrWrongPaymentField
+		}
+		return value, nil
+	}
+	return "", ErrWrongPaymentField
+}
+
+// IsPaymentField checks that PaymentDetails has the field
+func IsPaymentField(field string) bool {
+	for _, f := range PaymentFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the value of the field by its bson name
+func (p *PaymentDetails) Get(field string) string {
+	switch field {
+	case "recipient":
+		return p.Recipient
+	case "phone":
+		return p.Phone
+	case "card":
+		return p.Card
+	case "bank":
+		return p.Bank
+	case "account":
+		return p.Account
+	case "bic":
+		return p.BIC
+	case "iban":
+		return p.IBAN
+	case "link":
+		return p.Link
+	}
+	return ""
+}
+
+// IsEmpty checks that no details are filled in
+func (p *PaymentDetails) IsEmpty() bool {
+	for _, f := range PaymentFields {
+		if p.Get(f) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// QRPayload makes the content of a payment QR code for the amount: Russian ST00012 for rubles
+// paid to the bank account, EPC (SEPA credit transfer) for euros paid to IBAN, otherwise the payment link.
+// EPC limits the recipient to 70 chars and the purpose to 140, longer ones are cut.
+// It returns an empty string when no QR code can be made
+func (p *PaymentDetails) QRPayload(m Money, purpose string) string {
+	purpose = strings.NewReplacer("|", " ", "\n", " ").Replace(purpose)
+	switch {
+	case m.Currency == "RUB" && p.Recipient != "" && p.Account != "" && p.Bank != "" && p.BIC != "":
+		// bank applications find the correspondent account by BIC, so it may be zero
+		return fmt.Sprintf("ST00012|Name=%s|PersonalAcc=%s|BankName=%s|BIC=%s|CorrespAcc=0|Sum=%d|Purpose=%s",
+			p.Recipient, p.Account, p.Bank, p.BIC, m.Amount, purpose)
+	case m.Currency == "EUR" && p.Recipient != "" && p.IBAN != "":
+		return strings.Join([]string{"BCD", "002", "1", "SCT", p.BIC, truncate(p.Recipient, 70), p.IBAN,
+			fmt.Sprintf("EUR%d.%02d", m.Amount/100, m.Amount%100), "", "", truncate(purpose, 140)}, "\n")
+	case p.Link != "":
+		return p.Link
+	}
+	return ""
+}
+
+// truncate cuts the string to n chars
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return strings.TrimSpace(string(r[:n]))
+	}
+	return s
+}
+
+func luhn(number string) bool {
+	sum := 0
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if (len(number)-i)%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// ibanChecksum checks IBAN by ISO 13616: the number made of the rearranged IBAN gives remainder 1 modulo 97
+func ibanChecksum(iban string) bool {
+	var sb strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		if r >= 'A' && r <= 'Z' {
+			sb.WriteString(fmt.Sprint(r - 'A' + 10))
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	n, ok := new(big.Int).SetString(sb.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
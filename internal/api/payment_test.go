@@ -0,0 +1,99 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLuhn(t *testing.T) {
+	tests := []struct {
+		number string
+		want   bool
+	}{
+		{"4111111111111111", true},
+		{"4111111111111112", false},
+		{"5500005555555559", true},
+		{"2200000000000004", true},
+		{"2200000000000005", false},
+		{"378282246310005", true},
+		{"6011111111111117", true},
+		{"79927398713", true},
+		{"79927398710", false},
+		{"0", true},
+	}
+	for _, tt := range tests {
+		if got := luhn(tt.number); got != tt.want {
+			t.Errorf("luhn(%s) = %v, want %v", tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestIbanChecksum(t *testing.T) {
+	tests := []struct {
+		iban string
+		want bool
+	}{
+		{"DE89370400440532013000", true},
+		{"GB82WEST12345698765432", true},
+		{"FR1420041010050500013M02606", true},
+		{"NL91ABNA0417164300", true},
+		{"DE89370400440532013001", false},
+		{"GB82WEST12345698765433", false},
+		{"DE98370400440532013000", false},
+	}
+	for _, tt := range tests {
+		if got := ibanChecksum(tt.iban); got != tt.want {
+			t.Errorf("ibanChecksum(%s) = %v, want %v", tt.iban, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizePaymentField(t *testing.T) {
+	tests := []struct {
+		field string
+		value string
+		want  string
+		err   error
+	}{
+		{"card", "4111 1111 1111 1111", "4111111111111111", nil},
+		{"card", "4111-1111-1111-1112", "", ErrWrongPaymentField},
+		{"card", "4111", "", ErrWrongPaymentField},
+		{"iban", "de89 3704 0044 0532 0130 00", "DE89370400440532013000", nil},
+		{"iban", "DE89 3704 0044 0532 0130 01", "", ErrWrongPaymentField},
+		{"iban", "DE89", "", ErrWrongPaymentField},
+		{"phone", "8 (900) 123-45-67", "+79001234567", nil},
+		{"phone", "49 30 1234567", "+49301234567", nil},
+		{"bic", "044525225", "044525225", nil},
+		{"bic", "deutdeff", "DEUTDEFF", nil},
+		{"account", "40817810099910004312", "40817810099910004312", nil},
+		{"recipient", "  Ivan Petrov ", "Ivan Petrov", nil},
+		{"recipient", "Ivan|Petrov", "", ErrWrongPaymentField},
+		{"recipient", strings.Repeat("a", 141), "", ErrWrongPaymentField},
+		{"link", "https://pay.example.com/x", "https://pay.example.com/x", nil},
+		{"link", "pay.example.com", "", ErrWrongPaymentField},
+		{"unknown", "x", "", ErrWrongPaymentField},
+	}
+	for _, tt := range tests {
+		got, err := NormalizePaymentField(tt.field, tt.value)
+		if err != tt.err || got != tt.want {
+			t.Errorf("NormalizePaymentField(%s, %q) = %q, %v, want %q, %v", tt.field, tt.value, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestQRPayloadEPC(t *testing.T) {
+	p := &PaymentDetails{Recipient: strings.Repeat("Ä", 69) + "BC", IBAN: "DE89370400440532013000", BIC: "COBADEFFXXX"}
+	lines := strings.Split(p.QRPayload(Money{Amount: 150050, Currency: "EUR"}, strings.Repeat("x", 150)), "\n")
+	if len(lines) != 11 {
+		t.Fatalf("QRPayload() has %v lines, want 11", len(lines))
+	}
+	if want := strings.Repeat("Ä", 69) + "B"; lines[5] != want {
+		t.Errorf("recipient = %q, want %q", lines[5], want)
+	}
+	if lines[7] != "EUR1500.50" {
+		t.Errorf("amount = %q, want EUR1500.50", lines[7])
+	}
+	if len(lines[10]) != 140 {
+		t.Errorf("purpose has %v chars, want 140", len(lines[10]))
+	}
+}
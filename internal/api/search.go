@@ -0,0 +1,40 @@
+package api
+
+import (
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"unicode"
+)
+
+var yoReplacer = strings.NewReplacer("ё", "е")
+
+// NormalizeSearch makes the text comparable for search: lower case, without diacritics of Latin letters and
+// extra spaces, ё is searched as е, so "Ёлка Café" and "елка cafe" are the same. Other marks are kept
+// because they make other letters, й isn't и
+func NormalizeSearch(s string) string {
+	var b strings.Builder
+	var base rune
+	for _, r := range norm.NFD.String(s) {
+		if !unicode.Is(unicode.Mn, r) {
+			base = r
+		} else if unicode.Is(unicode.Latin, base) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	res := strings.ToLower(norm.NFC.String(b.String()))
+	return strings.Join(strings.Fields(yoReplacer.Replace(res)), " ")
+}
+
+// SearchRank orders search results: the name starting with the query goes first, then a word of the name
+// starting with the query, then the query anywhere. Both strings must be normalized
+func SearchRank(name string, query string) int {
+	switch {
+	case strings.HasPrefix(name, query):
+		return 0
+	case strings.Contains(" "+name, " "+query):
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestNormalizeSearch(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Ёлка Café", "елка cafe"},
+		{"ЁЖИК", "ежик"},
+		{"Йога", "йога"},
+		{"Мой май", "мой май"},
+		{"Crème Brûlée", "creme brulee"},
+		{"Zoë", "zoe"},
+		{"İstanbul", "istanbul"},
+		{"  Новый \t год  ", "новый год"},
+		{".*(a+)+$", ".*(a+)+$"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeSearch(tt.input); got != tt.want {
+			t.Errorf("NormalizeSearch(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSearchRank(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"йога дома", "йога", 0},
+		{"дом йоги", "йог", 1},
+		{"мойка", "йк", 2},
+		{"мойка", "ик", 2},
+		{"cafe", "cafe", 0},
+		{"x .*(a+)+$", ".*(a+)+$", 1},
+		{"aaaa", ".*(a+)+$", 2},
+		{".*(a+)+$ room", ".*(a+)+$", 0},
+	}
+	for _, tt := range tests {
+		if got := SearchRank(tt.name, tt.query); got != tt.want {
+			t.Errorf("SearchRank(%q, %q) = %v, want %v", tt.name, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSearchRankOrder(t *testing.T) {
+	names := []string{"с днем рождения", "рождение анны", "день рождения", "рождество"}
+	query := NormalizeSearch("Рожд")
+	sort.SliceStable(names, func(i, j int) bool { return SearchRank(names[i], query) < SearchRank(names[j], query) })
+	want := []string{"рождение анны", "рождество", "с днем рождения", "день рождения"}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("order = %q, want %q", names, want)
+		}
+	}
+}
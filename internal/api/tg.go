@@ -43,6 +43,9 @@ type Message struct {
 	Image    *Image    `json:",omitempty"`
 	Document *Document `json:",omitempty"`
 	Video    *Video    `json:",omitempty"`
+
+	NewChatMembers *[]User `json:",omitempty"`
+	LeftChatMember *User   `json:",omitempty"`
 }
 
 // Entity represents one special entity in a text message.
@@ -88,8 +91,15 @@ type User struct {
 	BirtDate       *time.Time `json:"birtDate" bson:"birt_date"`
 	NotificationOn *bool      `json:"notificationOn" bson:"notification_on,omitempty"`
 	CountInPage    int        `json:"countInPage" bson:"count_in_page,omitempty"`
+	HideYear       bool       `json:"hideYear" bson:"hide_year,omitempty"`
+	TimeZone       string     `json:"timeZone" bson:"time_zone,omitempty"`
+
+	PaymentDetails *PaymentDetails `json:"paymentDetails" bson:"payment_details,omitempty"`
 }
 
+// ForgottenName replaces the name of the user who asked to forget them in records kept for other members
+const ForgottenName = "Deleted user"
+
 func DefineLang(u *User) string {
 	if u.SelectedLang != "" {
 		return u.SelectedLang
@@ -123,8 +133,9 @@ type CallbackQuery struct {
 
 // Chat contains information about the place a message was sent.
 type Chat struct {
-	ID   int64  `json:"id"`
-	Type string `json:"type"`
+	ID    int64  `json:"id"`
+	Type  string `json:"type"`
+	Title string `json:"title"`
 }
 
 type TelegramMessage struct {
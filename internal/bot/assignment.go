@@ -0,0 +1,152 @@
+package bot
+
+import (
+	"context"
+	"github.com/almaznur91/splitty/internal/api"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// AssignmentAnswer accepts or declines the organizer assignment, the declined assignment is offered to the next candidate.
+// React on acceptAssignment and declineAssignment actions
+type AssignmentAnswer struct {
+	bs  ButtonService
+	rs  RoomService
+	cs  CollectionService
+	us  UserService
+	cfg *Config
+}
+
+// NewAssignmentAnswer makes a bot for answers to organizer assignments
+func NewAssignmentAnswer(bs ButtonService, rs RoomService, cs CollectionService, us UserService, cfg *Config) *AssignmentAnswer {
+	return &AssignmentAnswer{
+		bs:  bs,
+		rs:  rs,
+		cs:  cs,
+		us:  us,
+		cfg: cfg,
+	}
+}
+
+func (bot AssignmentAnswer) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && (hasAction(u, acceptAssignment) || hasAction(u, declineAssignment))
+}
+
+func (bot *AssignmentAnswer) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	data := u.Button.CallbackData
+	room, err := bot.rs.FindById(ctx, data.RoomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", data.RoomId)
+		return api.TelegramMessage{}, err
+	}
+
+	if u.Button.Action == acceptAssignment {
+		a, err := bot.cs.AcceptAssignment(ctx, u.User.ID, data.ExternalId)
+		if err != nil {
+			log.Error().Err(err).Msgf("accept assignment %v failed", data.ExternalId)
+			return roomErrorMessage(u, err)
+		}
+		text := I18n(u.User, "msg_assignment_accepted", a.Celebrant.DisplayName, room.Name)
+		return api.TelegramMessage{
+			Chattable: []tgbotapi.Chattable{createScreen(u, text, &[][]tgbotapi.InlineKeyboardButton{})},
+			Send:      true,
+		}, nil
+	}
+
+	a, err := bot.cs.DeclineAssignment(ctx, u.User.ID, data.ExternalId)
+	if err != nil {
+		log.Error().Err(err).Msgf("decline assignment %v failed", data.ExternalId)
+		return roomErrorMessage(u, err)
+	}
+	text := I18n(u.User, "msg_assignment_declined", a.Celebrant.DisplayName, room.Name)
+	chattable := []tgbotapi.Chattable{createScreen(u, text, &[][]tgbotapi.InlineKeyboardButton{})}
+
+	notices, err := bot.assignmentNotices(ctx, room, a)
+	if err != nil {
+		return api.TelegramMessage{}, err
+	}
+	return api.TelegramMessage{Chattable: append(chattable, notices...), Send: true}, nil
+}
+
+// assignmentNotices offers the assignment to the next candidate or tells admins that nobody is left
+func (bot *AssignmentAnswer) assignmentNotices(ctx context.Context, room *api.Room, a *api.Assignment) ([]tgbotapi.Chattable, error) {
+	ids := []int64{a.Organizer}
+	if a.Status == api.AssignmentUnassigned {
+		ids = append([]int64{room.Owner}, room.Admins...)
+	}
+	users, err := bot.us.FindByIds(ctx, ids)
+	if err != nil {
+		log.Error().Err(err).Msgf("find users of room %v failed", room.ID.Hex())
+		return nil, err
+	}
+
+	var notices []tgbotapi.Chattable
+	for _, user := range *users {
+		user := user
+		if user.ID == a.Celebrant.ID {
+			continue
+		}
+		if a.Status == api.AssignmentUnassigned {
+			notices = append(notices, AssignmentUnassigned(&user, room, a))
+			continue
+		}
+		msg, buttons := OrganizerAssignment(&user, room, a)
+		if _, err := bot.bs.SaveAll(ctx, buttons...); err != nil {
+			log.Error().Err(err).Msg("create btn failed")
+			return nil, err
+		}
+		notices = append(notices, msg)
+	}
+	return notices, nil
+}
+
+// OrganizerAssignment asks the assigned member to organize the collection for the birthday.
+// Returned buttons must be saved before the message is sent
+func OrganizerAssignment(user *api.User, room *api.Room, a *api.Assignment) (tgbotapi.Chattable, []*api.Button) {
+	data := &api.CallbackData{RoomId: a.RoomId, ExternalId: a.ID.Hex()}
+	acceptB := api.NewButton(acceptAssignment, data)
+	declineB := api.NewButton(declineAssignment, data)
+	text := I18n(user, "msg_assignment", a.Celebrant.DisplayName, room.Name, a.Birthday.UTC())
+	return NewMessage(user.ID, text, [][]tgbotapi.InlineKeyboardButton{{
+		tgbotapi.NewInlineKeyboardButtonData(I18n(user, "btn_accept_assignment"), acceptB.ID.Hex()),
+		tgbotapi.NewInlineKeyboardButtonData(I18n(user, "btn_decline_assignment"), declineB.ID.Hex()),
+	}}), []*api.Button{acceptB, declineB}
+}
+
+// AssignmentUnassigned tells the admin that every candidate declined or opted out, so the collection should be opened by hand
+func AssignmentUnassigned(user *api.User, room *api.Room, a *api.Assignment) tgbotapi.Chattable {
+	text := I18n(user, "msg_assignment_unassigned", a.Celebrant.DisplayName, room.Name, a.Birthday.UTC())
+	msg := tgbotapi.NewMessage(user.ID, text)
+	msg.ParseMode = parseMode
+	return msg
+}
+
+// OrganizerOptOut excludes the member from the organizer rotation or brings the member back, react on switchOrganizerOptOut action
+type OrganizerOptOut struct {
+	rs  RoomService
+	cfg *Config
+}
+
+// NewOrganizerOptOut makes a bot for switching organizer opt-out
+func NewOrganizerOptOut(rs RoomService, cfg *Config) *OrganizerOptOut {
+	return &OrganizerOptOut{
+		rs:  rs,
+		cfg: cfg,
+	}
+}
+
+func (bot OrganizerOptOut) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && hasAction(u, switchOrganizerOptOut)
+}
+
+func (bot *OrganizerOptOut) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	roomId := u.Button.CallbackData.RoomId
+	if err := bot.rs.SwitchOrganizerOptOut(ctx, u.User.ID, roomId); err != nil {
+		log.Error().Err(err).Msgf("switch organizer opt-out in room %v failed", roomId)
+		return roomErrorMessage(u, err)
+	}
+	return api.TelegramMessage{
+		Redirect: &api.Update{CallbackQuery: u.CallbackQuery, User: u.User, Button: api.NewButton(roomSetting, &api.CallbackData{RoomId: roomId})},
+		Send:     true,
+	}, nil
+}
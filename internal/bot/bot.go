@@ -20,9 +20,104 @@ const (
 	chooseOperations   api.Action = "choose_operations"
 	chooseDebts        api.Action = "choose_debts"
 	roomSetting        api.Action = "room_setting"
-	viewAllRooms       api.Action = "user_setting"
-	statistics         api.Action = "archive_room"
-	wantDonorOperation api.Action = "archive_room"
+	viewAllRooms       api.Action = "view_all_rooms"
+	statistics         api.Action = "statistics"
+	wantDonorOperation api.Action = "want_donor_operation"
+
+	viewUserSetting    api.Action = "view_user_setting"
+	chooseLang         api.Action = "choose_lang"
+	setLang            api.Action = "set_lang"
+	switchNotification api.Action = "switch_notification"
+	chooseCountInPage  api.Action = "choose_count_in_page"
+	setCountInPage     api.Action = "set_count_in_page"
+	editBirtDate       api.Action = "edit_birt_date"
+	switchHideYear     api.Action = "switch_hide_year"
+	chooseTimeZone     api.Action = "choose_time_zone"
+	setTimeZone        api.Action = "set_time_zone"
+
+	viewArchivedRooms api.Action = "view_archived_rooms"
+	viewFoundRooms    api.Action = "view_found_rooms"
+	searchRoom        api.Action = "search_room"
+	archiveRoom       api.Action = "room_archive"
+	unArchiveRoom     api.Action = "room_unarchive"
+
+	renameRoom        api.Action = "rename_room"
+	viewRoomMembers   api.Action = "view_room_members"
+	viewRoomMember    api.Action = "view_room_member"
+	removeMember      api.Action = "remove_member"
+	switchAdmin       api.Action = "switch_admin"
+	transferOwnership api.Action = "transfer_ownership"
+	confirmLeaveRoom  api.Action = "confirm_leave_room"
+	leaveRoom         api.Action = "leave_room"
+	confirmDeleteRoom api.Action = "confirm_delete_room"
+	deleteRoom        api.Action = "delete_room"
+
+	bindRoom api.Action = "bind_room"
+
+	forgetUser api.Action = "forget_user"
+
+	viewInvites  api.Action = "view_invites"
+	createInvite api.Action = "create_invite"
+	revokeInvite api.Action = "revoke_invite"
+	approveJoin  api.Action = "approve_join"
+	declineJoin  api.Action = "decline_join"
+
+	chooseCurrency api.Action = "choose_currency"
+	setCurrency    api.Action = "set_currency"
+	chooseRoomLang api.Action = "choose_room_lang"
+	setRoomLang    api.Action = "set_room_lang"
+
+	viewCollectionRule   api.Action = "view_collection_rule"
+	writeRuleAmount      api.Action = "write_rule_amount"
+	chooseRuleDays       api.Action = "choose_rule_days"
+	setRuleDays          api.Action = "set_rule_days"
+	chooseRuleOrganizer  api.Action = "choose_rule_organizer"
+	setRuleOrganizer     api.Action = "set_rule_organizer"
+	switchCollectionRule api.Action = "switch_collection_rule"
+
+	acceptAssignment      api.Action = "accept_assignment"
+	declineAssignment     api.Action = "decline_assignment"
+	switchOrganizerOptOut api.Action = "switch_organizer_opt_out"
+
+	mergeCollections api.Action = "merge_collections"
+	keepSeparate     api.Action = "keep_separate"
+
+	newCollection         api.Action = "new_collection"
+	chooseCelebrant       api.Action = "choose_celebrant"
+	writeCollectionAmount api.Action = "write_collection_amount"
+	viewContribution      api.Action = "view_contribution"
+	markPaid              api.Action = "mark_paid"
+	attachReceipt         api.Action = "attach_receipt"
+	viewReviewQueue       api.Action = "view_review_queue"
+	approvePayment        api.Action = "approve_payment"
+	rejectPayment         api.Action = "reject_payment"
+
+	viewPaymentDetails api.Action = "view_payment_details"
+	editPaymentField   api.Action = "edit_payment_field"
+	setPaymentField    api.Action = "set_payment_field"
+	payByQR            api.Action = "pay_by_qr"
+
+	chooseDeadline api.Action = "choose_deadline"
+	setDeadline    api.Action = "set_deadline"
+	snoozeReminder api.Action = "snooze_reminder"
+
+	viewOperation        api.Action = "view_operation"
+	undoOperation        api.Action = "undo_operation"
+	editOperation        api.Action = "edit_operation"
+	writeOperationAmount api.Action = "write_operation_amount"
+
+	recordExpense api.Action = "record_expense"
+	writeExpense  api.Action = "write_expense"
+
+	viewGreeting           api.Action = "view_greeting"
+	chooseGreetingTemplate api.Action = "choose_greeting_template"
+	writeGreeting          api.Action = "write_greeting"
+	attachGreetingMedia    api.Action = "attach_greeting_media"
+	previewGreeting        api.Action = "preview_greeting"
+	activateGreeting       api.Action = "activate_greeting"
+	switchGreetingChat     api.Action = "switch_greeting_chat"
+	switchGreetingPrivate  api.Action = "switch_greeting_private"
+	removeGreetingMedia    api.Action = "remove_greeting_media"
 )
 
 // Interface is a bot reactive spec. response will be sent if "send" result is true
@@ -0,0 +1,291 @@
+package bot
+
+import (
+	"context"
+	"github.com/almaznur91/splitty/internal/api"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+	"strconv"
+)
+
+// defaultRuleDays is a count of days before the birthday when the collection opens, if the admin hasn't chosen it
+const defaultRuleDays = 7
+
+var ruleDaysVariants = []int{3, 7, 14, 30}
+
+// CollectionRuleSetting shows the rule of automatic collections and changes it,
+// react on viewCollectionRule, setRuleDays, setRuleOrganizer and switchCollectionRule actions
+type CollectionRuleSetting struct {
+	bs  ButtonService
+	rs  RoomService
+	css ChatStateService
+	cfg *Config
+}
+
+// NewCollectionRuleSetting makes a bot for screen collection rule
+func NewCollectionRuleSetting(bs ButtonService, rs RoomService, css ChatStateService, cfg *Config) *CollectionRuleSetting {
+	return &CollectionRuleSetting{
+		bs:  bs,
+		rs:  rs,
+		css: css,
+		cfg: cfg,
+	}
+}
+
+func (bot CollectionRuleSetting) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && (hasAction(u, viewCollectionRule) || hasAction(u, setRuleDays) ||
+		hasAction(u, setRuleOrganizer) || hasAction(u, switchCollectionRule))
+}
+
+func (bot *CollectionRuleSetting) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	defer bot.css.CleanChatState(ctx, u.ChatState)
+
+	roomId := u.Button.CallbackData.RoomId
+	room, err := bot.rs.FindById(ctx, roomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", roomId)
+		return api.TelegramMessage{}, err
+	}
+	if !room.IsAdmin(u.User.ID) {
+		return roomErrorMessage(u, api.ErrForbidden)
+	}
+
+	rule := collectionRule(room)
+	if u.Button.Action != viewCollectionRule {
+		value := u.Button.CallbackData.ExternalData
+		switch u.Button.Action {
+		case setRuleDays:
+			rule.DaysBefore, _ = strconv.Atoi(value)
+		case setRuleOrganizer:
+			rule.Organizer, _ = strconv.ParseInt(value, 10, 64)
+		case switchCollectionRule:
+			if !rule.Enabled && rule.Amount.Amount == 0 {
+				return api.TelegramMessage{CallbackConfig: createCallback(u, I18n(u.User, "msg_rule_no_amount"), true), Send: true}, nil
+			}
+			rule.Enabled = !rule.Enabled
+		}
+		if err := bot.rs.SetCollectionRule(ctx, u.User.ID, roomId, &rule); err != nil {
+			log.Error().Err(err).Msgf("set collection rule of room %v failed", roomId)
+			return roomErrorMessage(u, err)
+		}
+	}
+
+	data := &api.CallbackData{RoomId: roomId}
+	amountB := api.NewButton(writeRuleAmount, data)
+	daysB := api.NewButton(chooseRuleDays, data)
+	organizerB := api.NewButton(chooseRuleOrganizer, data)
+	switchB := api.NewButton(switchCollectionRule, data)
+	backB := api.NewButton(roomSetting, data)
+	if _, err := bot.bs.SaveAll(ctx, amountB, daysB, organizerB, switchB, backB); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	switchKey, status := "btn_rule_enable", I18n(u.User, "msg_rule_disabled")
+	if rule.Enabled {
+		switchKey, status = "btn_rule_disable", I18n(u.User, "msg_rule_enabled")
+	}
+	amount := I18n(u.User, "msg_rule_not_set")
+	if rule.Amount.Amount > 0 {
+		amount = formatMoney(u.User, rule.Amount)
+	}
+	keyboard := [][]tgbotapi.InlineKeyboardButton{
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_rule_amount"), amountB.ID.Hex()),
+			tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_rule_days"), daysB.ID.Hex())},
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_rule_organizer"), organizerB.ID.Hex())},
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, switchKey), switchB.ID.Hex())},
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_back"), backB.ID.Hex())},
+	}
+	text := I18n(u.User, "scrn_collection_rule", room.Name, status, amount, rule.DaysBefore, ruleOrganizerName(u.User, room, &rule))
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createScreen(u, text, &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// CollectionRuleChoose shows variants of days and organizers of the rule, react on chooseRuleDays and chooseRuleOrganizer actions
+type CollectionRuleChoose struct {
+	bs  ButtonService
+	rs  RoomService
+	cfg *Config
+}
+
+// NewCollectionRuleChoose makes a bot for screens of collection rule variants
+func NewCollectionRuleChoose(bs ButtonService, rs RoomService, cfg *Config) *CollectionRuleChoose {
+	return &CollectionRuleChoose{
+		bs:  bs,
+		rs:  rs,
+		cfg: cfg,
+	}
+}
+
+func (bot CollectionRuleChoose) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && (hasAction(u, chooseRuleDays) || hasAction(u, chooseRuleOrganizer))
+}
+
+func (bot *CollectionRuleChoose) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	roomId := u.Button.CallbackData.RoomId
+	room, err := bot.rs.FindById(ctx, roomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", roomId)
+		return api.TelegramMessage{}, err
+	}
+	if !room.IsAdmin(u.User.ID) {
+		return roomErrorMessage(u, api.ErrForbidden)
+	}
+	rule := collectionRule(room)
+
+	var buttons []*api.Button
+	var keyboardButtons []tgbotapi.InlineKeyboardButton
+	add := func(action api.Action, value string, name string, selected bool) {
+		b := api.NewButton(action, &api.CallbackData{RoomId: roomId, ExternalData: value})
+		buttons = append(buttons, b)
+		if selected {
+			name = "✅ " + name
+		}
+		keyboardButtons = append(keyboardButtons, tgbotapi.NewInlineKeyboardButtonData(name, b.ID.Hex()))
+	}
+
+	screen := "scrn_choose_rule_days"
+	if u.Button.Action == chooseRuleDays {
+		for _, days := range ruleDaysVariants {
+			add(setRuleDays, strconv.Itoa(days), I18n(u.User, "btn_rule_days_variant", days), days == rule.DaysBefore)
+		}
+	} else {
+		screen = "scrn_choose_rule_organizer"
+		add(setRuleOrganizer, "0", I18n(u.User, "btn_rule_rotation"), rule.Organizer == 0)
+		for _, m := range *room.Members {
+			add(setRuleOrganizer, strconv.FormatInt(m.ID, 10), m.DisplayName, m.ID == rule.Organizer)
+		}
+	}
+	backB := api.NewButton(viewCollectionRule, &api.CallbackData{RoomId: roomId})
+	buttons = append(buttons, backB)
+	if _, err := bot.bs.SaveAll(ctx, buttons...); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	keyboard := optimizeKeyboardButtons(keyboardButtons)
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_back"), backB.ID.Hex())})
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createScreen(u, I18n(u.User, screen), &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// RuleAmountWriting asks admin to write the amount of automatic collections, react on writeRuleAmount action
+type RuleAmountWriting struct {
+	css ChatStateService
+	bs  ButtonService
+	rs  RoomService
+	cfg *Config
+}
+
+// NewRuleAmountWriting makes a bot for screen rule amount writing
+func NewRuleAmountWriting(s ChatStateService, bs ButtonService, rs RoomService, cfg *Config) *RuleAmountWriting {
+	return &RuleAmountWriting{
+		css: s,
+		bs:  bs,
+		rs:  rs,
+		cfg: cfg,
+	}
+}
+
+func (bot RuleAmountWriting) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && hasAction(u, writeRuleAmount)
+}
+
+func (bot *RuleAmountWriting) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	data := &api.CallbackData{RoomId: u.Button.CallbackData.RoomId}
+	room, err := bot.rs.FindById(ctx, data.RoomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", data.RoomId)
+		return api.TelegramMessage{}, err
+	}
+
+	cs := &api.ChatState{UserId: getChatID(u), Action: writeRuleAmount, CallbackData: data}
+	if err := bot.css.Save(ctx, cs); err != nil {
+		log.Error().Err(err).Msg("create chat state failed")
+		return api.TelegramMessage{}, err
+	}
+	cb := api.NewButton(viewCollectionRule, data)
+	if _, err := bot.bs.SaveAll(ctx, cb); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+	screen := createScreen(u, I18n(u.User, "scrn_write_collection_amount", room.CurrencyCode()),
+		&[][]tgbotapi.InlineKeyboardButton{
+			{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_cancel"), cb.ID.Hex())},
+		})
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{screen},
+		Send:      true,
+	}, nil
+}
+
+// RuleAmountInput saves the amount of automatic collections written by admin, react on writeRuleAmount chat state
+type RuleAmountInput struct {
+	css ChatStateService
+	rs  RoomService
+	cfg *Config
+}
+
+// NewRuleAmountInput makes a bot for saving rule amount
+func NewRuleAmountInput(s ChatStateService, rs RoomService, cfg *Config) *RuleAmountInput {
+	return &RuleAmountInput{
+		css: s,
+		rs:  rs,
+		cfg: cfg,
+	}
+}
+
+func (bot RuleAmountInput) HasReact(u *api.Update) bool {
+	return isPrivate(u) && hasMessage(u) && u.ChatState != nil && u.ChatState.Action == writeRuleAmount
+}
+
+func (bot *RuleAmountInput) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	roomId := u.ChatState.CallbackData.RoomId
+	room, err := bot.rs.FindById(ctx, roomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", roomId)
+		return api.TelegramMessage{}, err
+	}
+	amount, err := api.ParseMoney(u.Message.Text, room.CurrencyCode())
+	if err != nil || amount.Amount <= 0 {
+		return api.TelegramMessage{
+			Chattable: []tgbotapi.Chattable{tgbotapi.NewMessage(getChatID(u), I18n(u.User, "msg_wrong_amount"))},
+			Send:      true,
+		}, nil
+	}
+	defer bot.css.CleanChatState(ctx, u.ChatState)
+
+	rule := collectionRule(room)
+	rule.Amount = amount
+	if err := bot.rs.SetCollectionRule(ctx, u.User.ID, roomId, &rule); err != nil {
+		log.Error().Err(err).Msgf("set collection rule of room %v failed", roomId)
+		return roomErrorMessage(u, err)
+	}
+	return api.TelegramMessage{
+		Redirect: &api.Update{Message: u.Message, User: u.User, Button: api.NewButton(viewCollectionRule, &api.CallbackData{RoomId: roomId})},
+		Send:     true,
+	}, nil
+}
+
+// collectionRule returns a copy of the room rule to change, the rule of a new room is disabled
+func collectionRule(room *api.Room) api.CollectionRule {
+	if room.CollectionRule == nil {
+		return api.CollectionRule{DaysBefore: defaultRuleDays}
+	}
+	return *room.CollectionRule
+}
+
+func ruleOrganizerName(user *api.User, room *api.Room, rule *api.CollectionRule) string {
+	if rule.Organizer == 0 {
+		return I18nPlain(user, "msg_rule_rotation")
+	}
+	if m := room.FindMember(rule.Organizer); m != nil {
+		return m.DisplayName
+	}
+	return I18nPlain(user, "msg_rule_rotation")
+}
@@ -0,0 +1,686 @@
+package bot
+
+import (
+	"context"
+	"github.com/almaznur91/splitty/internal/api"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+	"sort"
+	"strings"
+)
+
+// Debts shows contributions of the user in the room, react on chooseDebts action
+type Debts struct {
+	bs  ButtonService
+	rs  RoomService
+	cs  CollectionService
+	css ChatStateService
+	cfg *Config
+}
+
+// NewDebts makes a bot for screen with debts of the user
+func NewDebts(bs ButtonService, rs RoomService, cs CollectionService, css ChatStateService, cfg *Config) *Debts {
+	return &Debts{
+		bs:  bs,
+		rs:  rs,
+		cs:  cs,
+		css: css,
+		cfg: cfg,
+	}
+}
+
+func (bot Debts) HasReact(u *api.Update) bool {
+	return isPrivate(u) && u.Button != nil && hasAction(u, chooseDebts)
+}
+
+func (bot *Debts) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	defer bot.css.CleanChatState(ctx, u.ChatState)
+
+	roomId := u.Button.CallbackData.RoomId
+	room, err := bot.rs.FindById(ctx, roomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", roomId)
+		return api.TelegramMessage{}, err
+	}
+	if !room.IsMember(u.User.ID) {
+		return roomErrorMessage(u, api.ErrNotMember)
+	}
+
+	contributions, err := bot.cs.FindContributionsByUserId(ctx, roomId, u.User.ID)
+	if err != nil {
+		log.Error().Err(err).Msgf("find contributions of room %v failed", roomId)
+		return api.TelegramMessage{}, err
+	}
+	collections, err := bot.findCollections(ctx, roomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("find collections of room %v failed", roomId)
+		return api.TelegramMessage{}, err
+	}
+	queue, err := bot.cs.FindReviewQueue(ctx, u.User.ID, roomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("find review queue of room %v failed", roomId)
+		return api.TelegramMessage{}, err
+	}
+	refunds, err := bot.cs.FindRefundsByRecipient(ctx, roomId, u.User.ID)
+	if err != nil {
+		log.Error().Err(err).Msgf("find refunds of room %v failed", roomId)
+		return api.TelegramMessage{}, err
+	}
+
+	var buttons []*api.Button
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	var debts []api.Money
+	for _, c := range *contributions {
+		if c.Status == api.ContributionApproved {
+			continue
+		}
+		if c.IsDebt() {
+			debts = append(debts, c.Amount)
+		}
+		b := api.NewButton(viewContribution, &api.CallbackData{RoomId: roomId, ExternalId: c.ID.Hex()})
+		buttons = append(buttons, b)
+		text := contributionMark(&c) + contributionTitle(u.User, &c, collections[c.CollectionId.Hex()]) +
+			" — " + formatMoney(u.User, c.Amount)
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardButtonData(text, b.ID.Hex())})
+	}
+
+	data := &api.CallbackData{RoomId: roomId}
+	if len(*queue) > 0 {
+		reviewB := api.NewButton(viewReviewQueue, data)
+		buttons = append(buttons, reviewB)
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_review_payments", len(*queue)), reviewB.ID.Hex())})
+	}
+	for _, c := range unsettledCollections(collections, u.User.ID) {
+		expenseB := api.NewButton(recordExpense, &api.CallbackData{RoomId: roomId, ExternalId: c.ID.Hex()})
+		buttons = append(buttons, expenseB)
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(I18nPlain(u.User, "btn_record_expense", collectionTitle(u.User, c)), expenseB.ID.Hex())})
+	}
+	if room.IsAdmin(u.User.ID) {
+		newB := api.NewButton(newCollection, data)
+		buttons = append(buttons, newB)
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_new_collection"), newB.ID.Hex())})
+	}
+	backB := api.NewButton(viewRoom, data)
+	buttons = append(buttons, backB)
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_back"), backB.ID.Hex())})
+
+	if _, err := bot.bs.SaveAll(ctx, buttons...); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	text := I18n(u.User, "scrn_debts", room.Name) + "\n\n"
+	if len(debts) > 0 {
+		text += I18n(u.User, "msg_you_debt", formatTotals(u.User, debts))
+	} else {
+		text += I18n(u.User, "msg_no_debts")
+	}
+	var awaited []api.Money
+	for _, c := range *refunds {
+		if c.Status != api.ContributionApproved {
+			awaited = append(awaited, c.Amount)
+		}
+	}
+	if len(awaited) > 0 {
+		text += "\n" + I18n(u.User, "msg_refunds_to_you", formatTotals(u.User, awaited))
+	}
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createTextScreen(u, text, &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// findCollections returns collections of the room by id, joint collections of other rooms are included,
+// contributions of merged collections refer to them
+func (bot *Debts) findCollections(ctx context.Context, roomId string) (map[string]*api.Collection, error) {
+	collections, err := bot.cs.FindCollectionsByRoomId(ctx, roomId)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]*api.Collection, len(*collections))
+	for i := range *collections {
+		c := &(*collections)[i]
+		res[c.ID.Hex()] = c
+		if c.JointId == nil {
+			continue
+		}
+		joint, err := bot.cs.FindCollectionById(ctx, c.JointId.Hex())
+		if err != nil {
+			return nil, err
+		}
+		res[joint.ID.Hex()] = joint
+	}
+	return res, nil
+}
+
+// unsettledCollections returns collections of the organizer without the expense, the newest first.
+// Merged collections are settled by the joint ones, so they are skipped
+func unsettledCollections(collections map[string]*api.Collection, organizer int64) []*api.Collection {
+	var res []*api.Collection
+	for _, c := range collections {
+		if c.Organizer == organizer && c.Expense == nil && c.JointId == nil {
+			res = append(res, c)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].CreateAt.After(res[j].CreateAt) })
+	return res
+}
+
+// CollectionCreating asks admin to choose the celebrant and to write the amount, react on newCollection and chooseCelebrant actions
+type CollectionCreating struct {
+	bs  ButtonService
+	rs  RoomService
+	css ChatStateService
+	cfg *Config
+}
+
+// NewCollectionCreating makes a bot for screens of collection creating
+func NewCollectionCreating(bs ButtonService, rs RoomService, css ChatStateService, cfg *Config) *CollectionCreating {
+	return &CollectionCreating{
+		bs:  bs,
+		rs:  rs,
+		css: css,
+		cfg: cfg,
+	}
+}
+
+func (bot CollectionCreating) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && (hasAction(u, newCollection) || hasAction(u, chooseCelebrant))
+}
+
+func (bot *CollectionCreating) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	roomId := u.Button.CallbackData.RoomId
+	room, err := bot.rs.FindById(ctx, roomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", roomId)
+		return api.TelegramMessage{}, err
+	}
+	if !room.IsAdmin(u.User.ID) {
+		return roomErrorMessage(u, api.ErrForbidden)
+	}
+	backB := api.NewButton(chooseDebts, &api.CallbackData{RoomId: roomId})
+
+	if u.Button.Action == chooseCelebrant {
+		data := &api.CallbackData{RoomId: roomId, UserId: u.Button.CallbackData.UserId}
+		cs := &api.ChatState{UserId: getChatID(u), Action: writeCollectionAmount, CallbackData: data}
+		if err := bot.css.Save(ctx, cs); err != nil {
+			log.Error().Err(err).Msg("create chat state failed")
+			return api.TelegramMessage{}, err
+		}
+		if _, err := bot.bs.SaveAll(ctx, backB); err != nil {
+			log.Error().Err(err).Msg("create btn failed")
+			return api.TelegramMessage{}, err
+		}
+		keyboard := [][]tgbotapi.InlineKeyboardButton{
+			{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_cancel"), backB.ID.Hex())},
+		}
+		return api.TelegramMessage{
+			Chattable: []tgbotapi.Chattable{createScreen(u, I18n(u.User, "scrn_write_collection_amount", room.CurrencyCode()), &keyboard)},
+			Send:      true,
+		}, nil
+	}
+
+	buttons := make([]*api.Button, 0, len(*room.Members)+1)
+	var keyboardButtons []tgbotapi.InlineKeyboardButton
+	for _, m := range *room.Members {
+		b := api.NewButton(chooseCelebrant, &api.CallbackData{RoomId: roomId, UserId: int(m.ID)})
+		buttons = append(buttons, b)
+		keyboardButtons = append(keyboardButtons, tgbotapi.NewInlineKeyboardButtonData(m.DisplayName, b.ID.Hex()))
+	}
+	buttons = append(buttons, backB)
+	if _, err := bot.bs.SaveAll(ctx, buttons...); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	keyboard := optimizeKeyboardButtons(keyboardButtons)
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_back"), backB.ID.Hex())})
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createScreen(u, I18n(u.User, "scrn_choose_celebrant"), &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// CollectionAmount creates the collection with the amount written by admin, react on writeCollectionAmount chat state
+type CollectionAmount struct {
+	bs  ButtonService
+	rs  RoomService
+	cs  CollectionService
+	css ChatStateService
+	cfg *Config
+}
+
+// NewCollectionAmount makes a bot for collection creating
+func NewCollectionAmount(bs ButtonService, rs RoomService, cs CollectionService, css ChatStateService, cfg *Config) *CollectionAmount {
+	return &CollectionAmount{
+		bs:  bs,
+		rs:  rs,
+		cs:  cs,
+		css: css,
+		cfg: cfg,
+	}
+}
+
+func (bot CollectionAmount) HasReact(u *api.Update) bool {
+	return isPrivate(u) && hasMessage(u) && u.ChatState != nil && u.ChatState.Action == writeCollectionAmount
+}
+
+func (bot *CollectionAmount) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	data := u.ChatState.CallbackData
+	room, err := bot.rs.FindById(ctx, data.RoomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", data.RoomId)
+		return api.TelegramMessage{}, err
+	}
+
+	amount, err := api.ParseMoney(u.Message.Text, room.CurrencyCode())
+	if err != nil {
+		return api.TelegramMessage{
+			Chattable: []tgbotapi.Chattable{tgbotapi.NewMessage(getChatID(u), I18n(u.User, "msg_wrong_amount"))},
+			Send:      true,
+		}, nil
+	}
+	defer bot.css.CleanChatState(ctx, u.ChatState)
+
+	collection, err := bot.cs.CreateCollection(ctx, u.User.ID, data.RoomId, int64(data.UserId), amount)
+	if err != nil {
+		log.Error().Err(err).Msgf("create collection in room %v failed", data.RoomId)
+		return roomErrorMessage(u, err)
+	}
+
+	var chattable []tgbotapi.Chattable
+	offer, err := jointOffer(ctx, bot.bs, bot.rs, bot.cs, u.User, collection)
+	if err != nil {
+		log.Error().Err(err).Msgf("find joint collection for %v failed", collection.ID.Hex())
+		return api.TelegramMessage{}, err
+	}
+	if offer != nil {
+		chattable = append(chattable, offer)
+	}
+
+	deadlineB := api.NewButton(chooseDeadline, &api.CallbackData{RoomId: data.RoomId, ExternalId: collection.ID.Hex()})
+	return api.TelegramMessage{
+		Chattable: chattable,
+		Redirect:  &api.Update{Message: u.Message, User: u.User, Button: deadlineB},
+		Send:      true,
+	}, nil
+}
+
+// ContributionView shows the contribution and marks it paid, react on viewContribution, markPaid and attachReceipt actions
+type ContributionView struct {
+	bs  ButtonService
+	cs  CollectionService
+	us  UserService
+	css ChatStateService
+	cfg *Config
+}
+
+// NewContributionView makes a bot for screen with contribution
+func NewContributionView(bs ButtonService, cs CollectionService, us UserService, css ChatStateService, cfg *Config) *ContributionView {
+	return &ContributionView{
+		bs:  bs,
+		cs:  cs,
+		us:  us,
+		css: css,
+		cfg: cfg,
+	}
+}
+
+func (bot ContributionView) HasReact(u *api.Update) bool {
+	return isPrivate(u) && u.Button != nil &&
+		(hasAction(u, viewContribution) || hasAction(u, markPaid) || hasAction(u, attachReceipt))
+}
+
+func (bot *ContributionView) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	data := &api.CallbackData{RoomId: u.Button.CallbackData.RoomId, ExternalId: u.Button.CallbackData.ExternalId}
+
+	switch u.Button.Action {
+	case attachReceipt:
+		cs := &api.ChatState{UserId: getChatID(u), Action: attachReceipt, CallbackData: data}
+		if err := bot.css.Save(ctx, cs); err != nil {
+			log.Error().Err(err).Msg("create chat state failed")
+			return api.TelegramMessage{}, err
+		}
+		cb := api.NewButton(viewContribution, data)
+		if _, err := bot.bs.SaveAll(ctx, cb); err != nil {
+			log.Error().Err(err).Msg("create btn failed")
+			return api.TelegramMessage{}, err
+		}
+		keyboard := [][]tgbotapi.InlineKeyboardButton{
+			{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_cancel"), cb.ID.Hex())},
+		}
+		return api.TelegramMessage{
+			Chattable: []tgbotapi.Chattable{createScreen(u, I18n(u.User, "scrn_send_receipt"), &keyboard)},
+			Send:      true,
+		}, nil
+	case markPaid:
+		c, err := bot.cs.MarkPaid(ctx, u.User.ID, data.ExternalId, nil)
+		if err != nil {
+			log.Error().Err(err).Msgf("mark contribution %v paid failed", data.ExternalId)
+			return roomErrorMessage(u, err)
+		}
+		notice, err := paymentReviewNotice(ctx, bot.bs, bot.cs, bot.us, c)
+		if err != nil {
+			return api.TelegramMessage{}, err
+		}
+		return api.TelegramMessage{
+			Chattable: []tgbotapi.Chattable{notice},
+			Redirect:  &api.Update{CallbackQuery: u.CallbackQuery, User: u.User, Button: api.NewButton(viewContribution, data)},
+			Send:      true,
+		}, nil
+	}
+	defer bot.css.CleanChatState(ctx, u.ChatState)
+
+	c, err := bot.cs.FindContributionById(ctx, data.ExternalId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find contribution, id:%s", data.ExternalId)
+		return api.TelegramMessage{}, err
+	}
+	if c.User.ID != u.User.ID {
+		return roomErrorMessage(u, api.ErrForbidden)
+	}
+	collection, err := bot.cs.FindCollectionById(ctx, c.CollectionId.Hex())
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find collection, id:%s", c.CollectionId.Hex())
+		return api.TelegramMessage{}, err
+	}
+	payee, err := bot.us.FindById(ctx, c.Payee(collection))
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find user, id:%v", c.Payee(collection))
+		return api.TelegramMessage{}, err
+	}
+
+	details := payee.PaymentDetails
+	var buttons []*api.Button
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	if c.Status != api.ContributionApproved {
+		paidB := api.NewButton(markPaid, data)
+		receiptB := api.NewButton(attachReceipt, data)
+		buttons = append(buttons, paidB, receiptB)
+		if details != nil && details.QRPayload(c.Amount, "") != "" {
+			qrB := api.NewButton(payByQR, data)
+			buttons = append(buttons, qrB)
+			keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+				tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_pay_by_qr"), qrB.ID.Hex())})
+		}
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_mark_paid"), paidB.ID.Hex())})
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_attach_receipt"), receiptB.ID.Hex())})
+	}
+	backB := api.NewButton(chooseDebts, &api.CallbackData{RoomId: data.RoomId})
+	buttons = append(buttons, backB)
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_back"), backB.ID.Hex())})
+
+	if _, err := bot.bs.SaveAll(ctx, buttons...); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	receipt := I18n(u.User, "msg_no_receipt")
+	if c.Receipt != nil {
+		receipt = I18n(u.User, "msg_has_receipt")
+	}
+	text := I18n(u.User, "scrn_contribution", contributionTitle(u.User, c, collection), formatMoney(u.User, c.Amount),
+		I18n(u.User, "msg_status_"+string(c.Status)), Mention(payee), receipt)
+	if collection.Deadline != nil {
+		text += "\n" + I18n(u.User, "msg_deadline", collection.Deadline.In(userLocation(u.User)))
+	}
+	if c.Status != api.ContributionApproved && details != nil && !details.IsEmpty() {
+		text += "\n\n" + I18n(u.User, "msg_pay_section") + "\n" + paymentDetailsText(u.User, details)
+	}
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createTextScreen(u, text, &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// ReceiptInput attaches a screenshot or a PDF receipt to the contribution, react on attachReceipt chat state
+type ReceiptInput struct {
+	bs  ButtonService
+	cs  CollectionService
+	us  UserService
+	css ChatStateService
+	cfg *Config
+}
+
+// NewReceiptInput makes a bot for receipt attaching
+func NewReceiptInput(bs ButtonService, cs CollectionService, us UserService, css ChatStateService, cfg *Config) *ReceiptInput {
+	return &ReceiptInput{
+		bs:  bs,
+		cs:  cs,
+		us:  us,
+		css: css,
+		cfg: cfg,
+	}
+}
+
+func (bot ReceiptInput) HasReact(u *api.Update) bool {
+	return isPrivate(u) && u.Message != nil && u.ChatState != nil && u.ChatState.Action == attachReceipt
+}
+
+func (bot *ReceiptInput) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	var receipt *api.Receipt
+	switch m := u.Message; {
+	case m.Image != nil:
+		receipt = &api.Receipt{FileId: m.Image.FileID}
+	case m.Document != nil && (m.Document.MimeType == "application/pdf" || strings.HasPrefix(m.Document.MimeType, "image/")):
+		receipt = &api.Receipt{FileId: m.Document.FileID, IsDocument: true}
+	default:
+		return api.TelegramMessage{
+			Chattable: []tgbotapi.Chattable{tgbotapi.NewMessage(getChatID(u), I18n(u.User, "msg_wrong_receipt"))},
+			Send:      true,
+		}, nil
+	}
+	defer bot.css.CleanChatState(ctx, u.ChatState)
+
+	data := u.ChatState.CallbackData
+	c, err := bot.cs.MarkPaid(ctx, u.User.ID, data.ExternalId, receipt)
+	if err != nil {
+		log.Error().Err(err).Msgf("attach receipt to contribution %v failed", data.ExternalId)
+		return roomErrorMessage(u, err)
+	}
+	notice, err := paymentReviewNotice(ctx, bot.bs, bot.cs, bot.us, c)
+	if err != nil {
+		return api.TelegramMessage{}, err
+	}
+
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{notice},
+		Redirect:  &api.Update{Message: u.Message, User: u.User, Button: api.NewButton(viewContribution, data)},
+		Send:      true,
+	}, nil
+}
+
+// PaymentReview shows payments waiting for review to the organizer, react on viewReviewQueue, approvePayment and rejectPayment actions
+type PaymentReview struct {
+	bs  ButtonService
+	cs  CollectionService
+	us  UserService
+	cfg *Config
+}
+
+// NewPaymentReview makes a bot for screen with review queue
+func NewPaymentReview(bs ButtonService, cs CollectionService, us UserService, cfg *Config) *PaymentReview {
+	return &PaymentReview{
+		bs:  bs,
+		cs:  cs,
+		us:  us,
+		cfg: cfg,
+	}
+}
+
+func (bot PaymentReview) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) &&
+		(hasAction(u, viewReviewQueue) || hasAction(u, approvePayment) || hasAction(u, rejectPayment))
+}
+
+func (bot *PaymentReview) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	roomId := u.Button.CallbackData.RoomId
+	var chattable []tgbotapi.Chattable
+
+	if u.Button.Action != viewReviewQueue {
+		c, err := bot.cs.ReviewPayment(ctx, u.User.ID, u.Button.CallbackData.ExternalId, u.Button.Action == approvePayment)
+		if err != nil {
+			log.Error().Err(err).Msgf("review payment %v failed", u.Button.CallbackData.ExternalId)
+			return roomErrorMessage(u, err)
+		}
+		notice, err := bot.reviewResultNotice(ctx, c)
+		if err != nil {
+			return api.TelegramMessage{}, err
+		}
+		chattable = append(chattable, notice)
+	}
+
+	queue, err := bot.cs.FindReviewQueue(ctx, u.User.ID, roomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("find review queue of room %v failed", roomId)
+		return api.TelegramMessage{}, err
+	}
+
+	backB := api.NewButton(chooseDebts, &api.CallbackData{RoomId: roomId})
+	buttons := []*api.Button{backB}
+	backKeyboard := []tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_back"), backB.ID.Hex())}
+
+	if len(*queue) == 0 {
+		if _, err := bot.bs.SaveAll(ctx, buttons...); err != nil {
+			log.Error().Err(err).Msg("create btn failed")
+			return api.TelegramMessage{}, err
+		}
+		keyboard := [][]tgbotapi.InlineKeyboardButton{backKeyboard}
+		chattable = append(chattable, createTextScreen(u, I18n(u.User, "scrn_review_queue_empty"), &keyboard))
+		return api.TelegramMessage{Chattable: chattable, Send: true}, nil
+	}
+
+	c := (*queue)[0]
+	collection, err := bot.cs.FindCollectionById(ctx, c.CollectionId.Hex())
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find collection, id:%s", c.CollectionId.Hex())
+		return api.TelegramMessage{}, err
+	}
+	data := &api.CallbackData{RoomId: roomId, ExternalId: c.ID.Hex()}
+	approveB := api.NewButton(approvePayment, data)
+	rejectB := api.NewButton(rejectPayment, data)
+	buttons = append(buttons, approveB, rejectB)
+	if _, err := bot.bs.SaveAll(ctx, buttons...); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+	keyboard := [][]tgbotapi.InlineKeyboardButton{
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_approve_payment"), approveB.ID.Hex()),
+			tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_reject_payment"), rejectB.ID.Hex())},
+		backKeyboard,
+	}
+
+	receipt := I18n(u.User, "msg_no_receipt")
+	if c.Receipt != nil {
+		receipt = I18n(u.User, "msg_has_receipt")
+	}
+	text := I18n(u.User, "scrn_review_payment", Mention(&c.User), formatMoney(u.User, c.Amount),
+		contributionTitle(u.User, &c, collection), receipt, len(*queue))
+
+	switch {
+	case c.Receipt != nil && c.Receipt.IsDocument:
+		msg := NewDocumentMessage(getChatID(u), text, tgbotapi.FileID(c.Receipt.FileId))
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+		chattable = append(chattable, msg)
+	case c.Receipt != nil:
+		msg := NewPhotoMessage(getChatID(u), text, tgbotapi.FileID(c.Receipt.FileId))
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+		chattable = append(chattable, msg)
+	default:
+		chattable = append(chattable, createTextScreen(u, text, &keyboard))
+	}
+	return api.TelegramMessage{Chattable: chattable, Send: true}, nil
+}
+
+// reviewResultNotice notifies the contributor about approved or rejected payment
+func (bot *PaymentReview) reviewResultNotice(ctx context.Context, c *api.Contribution) (tgbotapi.Chattable, error) {
+	contributor, err := bot.us.FindById(ctx, c.User.ID)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find user, id:%v", c.User.ID)
+		return nil, err
+	}
+	collection, err := bot.cs.FindCollectionById(ctx, c.CollectionId.Hex())
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find collection, id:%s", c.CollectionId.Hex())
+		return nil, err
+	}
+
+	if c.Status == api.ContributionApproved {
+		msg := tgbotapi.NewMessage(contributor.ID, I18n(contributor, "msg_payment_approved",
+			formatMoney(contributor, c.Amount), contributionTitle(contributor, c, collection)))
+		msg.ParseMode = parseMode
+		return msg, nil
+	}
+
+	b := api.NewButton(viewContribution, &api.CallbackData{RoomId: c.RoomId, ExternalId: c.ID.Hex()})
+	if _, err := bot.bs.SaveAll(ctx, b); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return nil, err
+	}
+	text := I18n(contributor, "msg_payment_rejected", formatMoney(contributor, c.Amount), contributionTitle(contributor, c, collection))
+	return NewMessage(contributor.ID, text, [][]tgbotapi.InlineKeyboardButton{
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(contributor, "btn_view_debt"), b.ID.Hex())},
+	}), nil
+}
+
+// paymentReviewNotice notifies the payee about the payment waiting for review
+func paymentReviewNotice(ctx context.Context, bs ButtonService, cs CollectionService, us UserService, c *api.Contribution) (tgbotapi.Chattable, error) {
+	collection, err := cs.FindCollectionById(ctx, c.CollectionId.Hex())
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find collection, id:%s", c.CollectionId.Hex())
+		return nil, err
+	}
+	payee, err := us.FindById(ctx, c.Payee(collection))
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find user, id:%v", c.Payee(collection))
+		return nil, err
+	}
+
+	b := api.NewButton(viewReviewQueue, &api.CallbackData{RoomId: c.RoomId})
+	if _, err := bs.SaveAll(ctx, b); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return nil, err
+	}
+	text := I18n(payee, "msg_payment_to_review", Mention(&c.User), formatMoney(payee, c.Amount),
+		contributionTitle(payee, c, collection))
+	return NewMessage(payee.ID, text, [][]tgbotapi.InlineKeyboardButton{
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(payee, "btn_review_payments", 1), b.ID.Hex())},
+	}), nil
+}
+
+func collectionTitle(user *api.User, c *api.Collection) string {
+	if c == nil || c.Celebrant == nil {
+		return I18nPlain(user, "msg_collection")
+	}
+	return I18nPlain(user, "msg_gift_for", c.Celebrant.DisplayName)
+}
+
+// contributionTitle names settlement debts after the collection, e.g. "Refund to Ivan, Gift for Anna"
+func contributionTitle(user *api.User, c *api.Contribution, collection *api.Collection) string {
+	switch c.Kind {
+	case api.ContributionRefund:
+		return I18nPlain(user, "msg_refund_to", c.Recipient.DisplayName, collectionTitle(user, collection))
+	case api.ContributionExtra:
+		return I18nPlain(user, "msg_extra_payment", collectionTitle(user, collection))
+	}
+	return collectionTitle(user, collection)
+}
+
+func contributionMark(c *api.Contribution) string {
+	switch c.Status {
+	case api.ContributionPending:
+		return "⏳ "
+	case api.ContributionRejected:
+		return "❌ "
+	default:
+		return "🔴 "
+	}
+}
@@ -0,0 +1,165 @@
+package bot
+
+import (
+	"context"
+	"github.com/almaznur91/splitty/internal/api"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// ExpenseRecording asks the organizer what was spent on the gift, react on recordExpense action
+type ExpenseRecording struct {
+	css ChatStateService
+	bs  ButtonService
+	cs  CollectionService
+	cfg *Config
+}
+
+// NewExpenseRecording makes a bot for screen expense recording
+func NewExpenseRecording(s ChatStateService, bs ButtonService, cs CollectionService, cfg *Config) *ExpenseRecording {
+	return &ExpenseRecording{
+		css: s,
+		bs:  bs,
+		cs:  cs,
+		cfg: cfg,
+	}
+}
+
+func (bot ExpenseRecording) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && hasAction(u, recordExpense)
+}
+
+func (bot *ExpenseRecording) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	data := &api.CallbackData{RoomId: u.Button.CallbackData.RoomId, ExternalId: u.Button.CallbackData.ExternalId}
+	collection, err := bot.cs.FindCollectionById(ctx, data.ExternalId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find collection, id:%s", data.ExternalId)
+		return api.TelegramMessage{}, err
+	}
+	if collection.Organizer != u.User.ID {
+		return roomErrorMessage(u, api.ErrForbidden)
+	}
+
+	cs := &api.ChatState{UserId: getChatID(u), Action: writeExpense, CallbackData: data}
+	if err := bot.css.Save(ctx, cs); err != nil {
+		log.Error().Err(err).Msg("create chat state failed")
+		return api.TelegramMessage{}, err
+	}
+	cb := api.NewButton(chooseDebts, &api.CallbackData{RoomId: data.RoomId})
+	if _, err := bot.bs.SaveAll(ctx, cb); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+	keyboard := [][]tgbotapi.InlineKeyboardButton{
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_cancel"), cb.ID.Hex())},
+	}
+	text := I18n(u.User, "scrn_write_expense", collectionTitle(u.User, collection), collection.Amount.Currency)
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createScreen(u, text, &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// ExpenseInput records the expense written by the organizer, a photo of the receipt may have the amount in the caption.
+// React on writeExpense chat state
+type ExpenseInput struct {
+	bs  ButtonService
+	cs  CollectionService
+	css ChatStateService
+	cfg *Config
+}
+
+// NewExpenseInput makes a bot for expense recording
+func NewExpenseInput(bs ButtonService, cs CollectionService, css ChatStateService, cfg *Config) *ExpenseInput {
+	return &ExpenseInput{
+		bs:  bs,
+		cs:  cs,
+		css: css,
+		cfg: cfg,
+	}
+}
+
+func (bot ExpenseInput) HasReact(u *api.Update) bool {
+	return isPrivate(u) && u.Message != nil && u.ChatState != nil && u.ChatState.Action == writeExpense
+}
+
+func (bot *ExpenseInput) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	data := u.ChatState.CallbackData
+	collection, err := bot.cs.FindCollectionById(ctx, data.ExternalId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find collection, id:%s", data.ExternalId)
+		return api.TelegramMessage{}, err
+	}
+
+	text := u.Message.Text
+	var receipt *api.Receipt
+	if m := u.Message; m.Image != nil {
+		text, receipt = m.Image.Caption, &api.Receipt{FileId: m.Image.FileID}
+	}
+	spent, err := api.ParseMoney(text, collection.Amount.Currency)
+	if err != nil {
+		return api.TelegramMessage{
+			Chattable: []tgbotapi.Chattable{tgbotapi.NewMessage(getChatID(u), I18n(u.User, "msg_wrong_expense"))},
+			Send:      true,
+		}, nil
+	}
+	defer bot.css.CleanChatState(ctx, u.ChatState)
+
+	settlement, err := bot.cs.RecordExpense(ctx, u.User.ID, data.ExternalId, spent, receipt)
+	if err != nil {
+		log.Error().Err(err).Msgf("record expense of collection %v failed", data.ExternalId)
+		return roomErrorMessage(u, err)
+	}
+
+	var extra, refunds int
+	chattable := make([]tgbotapi.Chattable, 0, len(*settlement)+1)
+	for i := range *settlement {
+		c := &(*settlement)[i]
+		if c.Kind == api.ContributionRefund {
+			refunds++
+		} else {
+			extra++
+		}
+		notice, err := bot.settlementNotice(ctx, c, collection)
+		if err != nil {
+			return api.TelegramMessage{}, err
+		}
+		chattable = append(chattable, notice)
+	}
+	summary := I18n(u.User, "msg_expense_recorded", formatMoney(u.User, spent))
+	if len(*settlement) == 0 {
+		summary += "\n" + I18n(u.User, "msg_settlement_exact")
+	} else {
+		summary += "\n" + I18n(u.User, "msg_settlement", extra, refunds)
+	}
+	msg := tgbotapi.NewMessage(getChatID(u), summary)
+	msg.ParseMode = parseMode
+	chattable = append(chattable, msg)
+
+	return api.TelegramMessage{
+		Chattable: chattable,
+		Redirect:  &api.Update{Message: u.Message, User: u.User, Button: api.NewButton(chooseDebts, &api.CallbackData{RoomId: data.RoomId})},
+		Send:      true,
+	}, nil
+}
+
+// settlementNotice tells the member about the extra payment, the refund recipient is told about the refund
+func (bot *ExpenseInput) settlementNotice(ctx context.Context, c *api.Contribution, collection *api.Collection) (tgbotapi.Chattable, error) {
+	if c.Kind == api.ContributionRefund {
+		msg := tgbotapi.NewMessage(c.Recipient.ID, I18n(c.Recipient, "msg_refund_notice",
+			formatMoney(c.Recipient, c.Amount), collectionTitle(c.Recipient, collection)))
+		msg.ParseMode = parseMode
+		return msg, nil
+	}
+
+	// settlement contributions are saved without ids, so the member opens them from the debts screen
+	b := api.NewButton(chooseDebts, &api.CallbackData{RoomId: c.RoomId})
+	if _, err := bot.bs.SaveAll(ctx, b); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return nil, err
+	}
+	text := I18n(&c.User, "msg_extra_payment_notice", formatMoney(&c.User, c.Amount), collectionTitle(&c.User, collection))
+	return NewMessage(c.User.ID, text, [][]tgbotapi.InlineKeyboardButton{
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(&c.User, "btn_debts"), b.ID.Hex())},
+	}), nil
+}
@@ -0,0 +1,437 @@
+package bot
+
+import (
+	"fmt"
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Args are named arguments of the message, e.g. I18n(u, "msg_joined_room", Args{"room": room.Name}).
+// Messages refer to positional arguments by the index, {0}, and to named ones by the name, {room}
+type Args map[string]interface{}
+
+// Messages of lang files are written in the subset of ICU MessageFormat:
+//
+//	{0}, {room}                                    argument
+//	{days, plural, one {# day} other {# days}}     plural form of the number in the language, =0 {...} matches
+//	                                               the exact value, # is the number
+//	{role, select, admin {...} other {...}}        text chosen by the value
+//	{date, date}, {date, date, long|short}         date as "March 12", "March 12, 2024" or "03/12/2024"
+//	{date, time}                                   time of the day
+//	{count, number}                                number
+//
+// Apostrophe quotes special chars, '{name}' is printed as {name}, two apostrophes are one apostrophe.
+// The apostrophe which is not followed by a special char is printed as is, so "doesn't" needs no quoting.
+// Date layouts and month names are fmt_ keys of lang files
+type msgPart struct {
+	text  string
+	arg   string
+	kind  string
+	style string
+	cases map[string][]msgPart
+}
+
+const (
+	kindArg    = ""
+	kindNumber = "number"
+	kindPlural = "plural"
+	kindSelect = "select"
+	kindDate   = "date"
+	kindTime   = "time"
+	// kindCount is # inside of the plural case
+	kindCount = "#"
+)
+
+var dateStyles = map[string]string{"": "fmt_date", "long": "fmt_date_long", "short": "fmt_date_short"}
+
+// parsed caches messages by the text, texts of reloaded lang files are parsed again
+var parsed sync.Map
+
+// formatMessage fills arguments of the message in the language, the message is returned as is when it is broken
+func formatMessage(lang string, msg string, args []interface{}) (string, error) {
+	parts, err := parseMessageCached(msg)
+	if err != nil {
+		return msg, err
+	}
+	var b strings.Builder
+	if err := formatParts(&b, lang, parts, args, nil); err != nil {
+		return msg, err
+	}
+	return b.String(), nil
+}
+
+func parseMessageCached(msg string) ([]msgPart, error) {
+	if parts, ok := parsed.Load(msg); ok {
+		return parts.([]msgPart), nil
+	}
+	parts, err := parseMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+	parsed.Store(msg, parts)
+	return parts, nil
+}
+
+func parseMessage(msg string) ([]msgPart, error) {
+	p := &msgParser{s: []rune(msg)}
+	parts, err := p.parts(false, false)
+	if err != nil {
+		return nil, err
+	}
+	if p.i < len(p.s) {
+		return nil, p.errorf("unexpected }")
+	}
+	return parts, nil
+}
+
+type msgParser struct {
+	s []rune
+	i int
+}
+
+func (p *msgParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("position %v: %s", p.i, fmt.Sprintf(format, args...))
+}
+
+// parts reads the text till the end or till } of the enclosing case
+func (p *msgParser) parts(nested bool, inPlural bool) ([]msgPart, error) {
+	var parts []msgPart
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			parts = append(parts, msgPart{text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		switch {
+		case c == '\'':
+			if err := p.quoted(&text, inPlural); err != nil {
+				return nil, err
+			}
+		case c == '{':
+			flush()
+			part, err := p.argument(inPlural)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, part)
+		case c == '}':
+			if !nested {
+				return nil, p.errorf("unexpected }")
+			}
+			flush()
+			return parts, nil
+		case c == '#' && inPlural:
+			flush()
+			parts = append(parts, msgPart{kind: kindCount})
+			p.i++
+		default:
+			text.WriteRune(c)
+			p.i++
+		}
+	}
+	if nested {
+		return nil, p.errorf("case is not closed")
+	}
+	flush()
+	return parts, nil
+}
+
+func (p *msgParser) quoted(text *strings.Builder, inPlural bool) error {
+	p.i++
+	if p.i == len(p.s) {
+		text.WriteRune('\'')
+		return nil
+	}
+	switch c := p.s[p.i]; {
+	case c == '\'':
+		text.WriteRune('\'')
+		p.i++
+		return nil
+	case c != '{' && c != '}' && !(c == '#' && inPlural):
+		text.WriteRune('\'')
+		return nil
+	}
+	for ; p.i < len(p.s); p.i++ {
+		if p.s[p.i] != '\'' {
+			text.WriteRune(p.s[p.i])
+			continue
+		}
+		if p.i+1 < len(p.s) && p.s[p.i+1] == '\'' {
+			text.WriteRune('\'')
+			p.i++
+			continue
+		}
+		p.i++
+		return nil
+	}
+	return p.errorf("quote is not closed")
+}
+
+// argument reads {name}, {name, type} or {name, type, style or cases}
+func (p *msgParser) argument(inPlural bool) (msgPart, error) {
+	p.i++
+	part := msgPart{arg: p.word()}
+	if part.arg == "" {
+		return part, p.errorf("argument name expected")
+	}
+	if p.next('}') {
+		return part, nil
+	}
+	if !p.next(',') {
+		return part, p.errorf("argument %s: , or } expected", part.arg)
+	}
+	part.kind = p.word()
+
+	switch part.kind {
+	case kindNumber, kindDate, kindTime:
+		if p.next(',') {
+			part.style = p.word()
+		}
+		if _, ok := dateStyles[part.style]; part.kind == kindDate && !ok || part.kind != kindDate && part.style != "" {
+			return part, p.errorf("argument %s: unknown style %q of %s", part.arg, part.style, part.kind)
+		}
+	case kindPlural, kindSelect:
+		if !p.next(',') {
+			return part, p.errorf("argument %s: cases expected", part.arg)
+		}
+		cases, err := p.cases(part.kind == kindPlural || inPlural)
+		if err != nil {
+			return part, err
+		}
+		if _, ok := cases["other"]; !ok {
+			return part, p.errorf("argument %s: other case is required", part.arg)
+		}
+		part.cases = cases
+	default:
+		return part, p.errorf("argument %s: unknown type %q", part.arg, part.kind)
+	}
+	if !p.next('}') {
+		return part, p.errorf("argument %s: } expected", part.arg)
+	}
+	return part, nil
+}
+
+// cases reads "key {text} key {text}" up to } of the argument, which is left for the caller
+func (p *msgParser) cases(inPlural bool) (map[string][]msgPart, error) {
+	cases := make(map[string][]msgPart)
+	for {
+		p.spaces()
+		if p.i == len(p.s) || p.s[p.i] == '}' {
+			return cases, nil
+		}
+		key := p.word()
+		if key == "" {
+			return nil, p.errorf("case name expected")
+		}
+		if !p.next('{') {
+			return nil, p.errorf("case %s: { expected", key)
+		}
+		parts, err := p.parts(true, inPlural)
+		if err != nil {
+			return nil, err
+		}
+		p.i++
+		cases[key] = parts
+	}
+}
+
+// word reads the name, the type or the case key surrounded by spaces
+func (p *msgParser) word() string {
+	p.spaces()
+	start := p.i
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' && c != '=' && c != '-' {
+			break
+		}
+		p.i++
+	}
+	w := string(p.s[start:p.i])
+	p.spaces()
+	return w
+}
+
+func (p *msgParser) spaces() {
+	for p.i < len(p.s) && unicode.IsSpace(p.s[p.i]) {
+		p.i++
+	}
+}
+
+func (p *msgParser) next(c rune) bool {
+	p.spaces()
+	if p.i < len(p.s) && p.s[p.i] == c {
+		p.i++
+		return true
+	}
+	return false
+}
+
+// formatParts writes parts with arguments, count is the number of the enclosing plural for #
+func formatParts(b *strings.Builder, lang string, parts []msgPart, args []interface{}, count *int64) error {
+	for _, part := range parts {
+		if part.kind == kindCount {
+			if count != nil {
+				b.WriteString(strconv.FormatInt(*count, 10))
+			}
+			continue
+		}
+		if part.arg == "" {
+			b.WriteString(part.text)
+			continue
+		}
+
+		v, ok := argValue(args, part.arg)
+		if !ok {
+			return fmt.Errorf("argument %s is missing", part.arg)
+		}
+		switch part.kind {
+		case kindArg, kindNumber:
+			b.WriteString(argString(lang, v))
+		case kindDate, kindTime:
+			t, ok := argTime(v)
+			if !ok {
+				return fmt.Errorf("argument %s: %T is not a time", part.arg, v)
+			}
+			if part.kind == kindTime {
+				b.WriteString(formatTime(lang, t))
+			} else {
+				b.WriteString(formatDate(lang, t, part.style))
+			}
+		case kindPlural:
+			n, ok := argInt(v)
+			if !ok {
+				return fmt.Errorf("argument %s: %T is not an integer", part.arg, v)
+			}
+			c, ok := part.cases["="+strconv.FormatInt(n, 10)]
+			if !ok {
+				c = pluralCase(part.cases, lang, n)
+			}
+			if err := formatParts(b, lang, c, args, &n); err != nil {
+				return err
+			}
+		case kindSelect:
+			c, ok := part.cases[fmt.Sprint(v)]
+			if !ok {
+				c = part.cases["other"]
+			}
+			if err := formatParts(b, lang, c, args, count); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var pluralForms = map[plural.Form]string{plural.Zero: "zero", plural.One: "one", plural.Two: "two", plural.Few: "few",
+	plural.Many: "many"}
+
+// pluralCase chooses the case by plural rules of the language, e.g. 21 is "one" and 5 is "many" in Russian
+func pluralCase(cases map[string][]msgPart, lang string, n int64) []msgPart {
+	if n < 0 {
+		n = -n
+	}
+	form := plural.Cardinal.MatchPlural(language.Make(lang), int(n), 0, 0, 0, 0)
+	if c, ok := cases[pluralForms[form]]; ok {
+		return c
+	}
+	return cases["other"]
+}
+
+// argValue finds the argument by the index among all arguments or by the name among Args
+func argValue(args []interface{}, name string) (interface{}, bool) {
+	if i, err := strconv.Atoi(name); err == nil {
+		if i < len(args) {
+			return args[i], true
+		}
+		return nil, false
+	}
+	for _, a := range args {
+		if named, ok := a.(Args); ok {
+			if v, ok := named[name]; ok {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func argString(lang string, v interface{}) string {
+	switch a := v.(type) {
+	case string:
+		return a
+	case Text:
+		return string(a)
+	case time.Time:
+		return formatDate(lang, a, "")
+	case *time.Time:
+		return formatDate(lang, *a, "")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func argTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case *time.Time:
+		if t != nil {
+			return *t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func argInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+// formatDate formats the date by the layout of the language, January of the layout is the month name of the language,
+// e.g. "2 January" is "12 марта" in Russian. Style is "", "long" or "short"
+func formatDate(lang string, t time.Time, style string) string {
+	layout := tr().Tr(lang, dateStyles[style])
+	s := t.Format(layout)
+	if !strings.Contains(layout, "January") {
+		return s
+	}
+	months := strings.Split(tr().Tr(lang, "fmt_months"), ",")
+	if len(months) != 12 {
+		return s
+	}
+	return strings.Replace(s, t.Month().String(), strings.TrimSpace(months[t.Month()-1]), 1)
+}
+
+func formatTime(lang string, t time.Time) string {
+	return t.Format(tr().Tr(lang, "fmt_time"))
+}
+
+// messageArgs collects names of arguments used by the message
+func messageArgs(parts []msgPart, names map[string]bool) {
+	for _, part := range parts {
+		if part.arg != "" {
+			names[part.arg] = true
+		}
+		for _, c := range part.cases {
+			messageArgs(c, names)
+		}
+	}
+}
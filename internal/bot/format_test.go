@@ -0,0 +1,175 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testFormats = `
+fmt_months = January,February,March,April,May,June,July,August,September,October,November,December
+fmt_date = January 2
+fmt_date_long = January 2, 2006
+fmt_date_short = 01/02/2006
+fmt_time = 3:04 PM
+`
+
+const testFormatsRu = `
+fmt_months = января,февраля,марта,апреля,мая,июня,июля,августа,сентября,октября,ноября,декабря
+fmt_date = 2 January
+fmt_date_long = 2 January 2006
+fmt_date_short = 02.01.2006
+fmt_time = 15:04
+`
+
+func TestFormatMessage(t *testing.T) {
+	useMessages(t, map[string]string{"en": testFormats, "ru": testFormatsRu})
+	date := time.Date(2024, time.March, 12, 18, 5, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		lang string
+		msg  string
+		args []interface{}
+		want string
+	}{
+		{"positional", "en", "{0} and {1}", []interface{}{"a", 2}, "a and 2"},
+		{"named", "en", "{room} by {0}", []interface{}{"Ann", Args{"room": "Work"}}, "Work by Ann"},
+		{"quoted brace", "en", "'{'", nil, "{"},
+		{"quoted argument", "en", "'{name}' is {0}", []interface{}{"x"}, "{name} is x"},
+		{"two apostrophes", "en", "it''s", nil, "it's"},
+		{"apostrophe as is", "en", "doesn't", nil, "doesn't"},
+		{"apostrophe at the end", "en", "rooms'", nil, "rooms'"},
+		{"quoted with apostrophe", "en", "'{it''s}'", nil, "{it's}"},
+		{"hash outside plural", "en", "#{0}", []interface{}{1}, "#1"},
+		{"quoted hash in plural", "en", "{n, plural, other {'#' #}}", []interface{}{Args{"n": 3}}, "# 3"},
+		{"plural one", "en", "{n, plural, one {# debt} other {# debts}}", []interface{}{Args{"n": 1}}, "1 debt"},
+		{"plural other", "en", "{n, plural, one {# debt} other {# debts}}", []interface{}{Args{"n": 2}}, "2 debts"},
+		{"exact zero", "en", "{n, plural, =0 {no debts} one {# debt} other {# debts}}", []interface{}{Args{"n": 0}}, "no debts"},
+		{"zero without exact case", "en", "{n, plural, one {# debt} other {# debts}}", []interface{}{Args{"n": 0}}, "0 debts"},
+		{"select", "en", "{role, select, admin {boss} other {member}}", []interface{}{Args{"role": "admin"}}, "boss"},
+		{"select other", "en", "{role, select, admin {boss} other {member}}", []interface{}{Args{"role": "guest"}}, "member"},
+		{"hash in select in plural", "en", "{n, plural, one {{role, select, admin {# admin} other {# member}}} other {# people}}",
+			[]interface{}{Args{"n": 1, "role": "admin"}}, "1 admin"},
+		{"plural in select", "en", "{role, select, admin {{n, plural, one {# room} other {# rooms}}} other {none}}",
+			[]interface{}{Args{"n": 4, "role": "admin"}}, "4 rooms"},
+		{"argument in case", "en", "{n, plural, one {{name} owes #} other {{name} owes # times}}",
+			[]interface{}{Args{"n": 2, "name": "Ann"}}, "Ann owes 2 times"},
+		{"date", "en", "{0, date}", []interface{}{date}, "March 12"},
+		{"date long", "en", "{0, date, long}", []interface{}{&date}, "March 12, 2024"},
+		{"date short", "en", "{0, date, short}", []interface{}{date}, "03/12/2024"},
+		{"time", "en", "{0, time}", []interface{}{date}, "6:05 PM"},
+		{"date ru", "ru", "{0, date}", []interface{}{date}, "12 марта"},
+		{"date long ru", "ru", "{0, date, long}", []interface{}{date}, "12 марта 2024"},
+		{"date short ru", "ru", "{0, date, short}", []interface{}{date}, "12.03.2024"},
+		{"time ru", "ru", "{0, time}", []interface{}{date}, "18:05"},
+		{"date as argument", "ru", "{0}", []interface{}{date}, "12 марта"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatMessage(tt.lang, tt.msg, tt.args)
+			if err != nil {
+				t.Fatalf("formatMessage() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("formatMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatMessageRussianPlural(t *testing.T) {
+	msg := "{n, plural, one {# долг} few {# долга} many {# долгов} other {# долга}}"
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{1, "1 долг"},
+		{2, "2 долга"},
+		{5, "5 долгов"},
+		{11, "11 долгов"},
+		{21, "21 долг"},
+		{22, "22 долга"},
+		{111, "111 долгов"},
+	}
+	for _, tt := range tests {
+		got, err := formatMessage("ru", msg, []interface{}{Args{"n": tt.n}})
+		if err != nil {
+			t.Fatalf("formatMessage(%v) error = %v", tt.n, err)
+		}
+		if got != tt.want {
+			t.Errorf("formatMessage(%v) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestFormatMessageErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		args []interface{}
+	}{
+		{"not closed", "{0", nil},
+		{"unexpected brace", "a}", nil},
+		{"quote not closed", "'{a", nil},
+		{"unknown type", "{0, money}", []interface{}{1}},
+		{"unknown date style", "{0, date, full}", []interface{}{time.Now()}},
+		{"no other case", "{n, plural, one {#}}", []interface{}{Args{"n": 1}}},
+		{"case not closed", "{n, plural, other {#}", []interface{}{Args{"n": 1}}},
+		{"missing argument", "{1}", []interface{}{"a"}},
+		{"missing named argument", "{room}", []interface{}{Args{"name": "a"}}},
+		{"plural of string", "{n, plural, other {#}}", []interface{}{Args{"n": "one"}}},
+		{"date of string", "{0, date}", []interface{}{"today"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatMessage("en", tt.msg, tt.args)
+			if err == nil {
+				t.Fatalf("formatMessage() = %q, error expected", got)
+			}
+			if got != tt.msg {
+				t.Errorf("formatMessage() = %q, the broken message %q expected as is", got, tt.msg)
+			}
+		})
+	}
+}
+
+func TestCheckMessages(t *testing.T) {
+	tests := []struct {
+		name    string
+		en, ru  string
+		problem string
+	}{
+		{"valid", testFormats + "msg_a = {n, plural, one {# a} other {# b}}\n",
+			testFormatsRu + "msg_a = {n, plural, one {# а} few {# б} many {# в} other {# г}}\n", ""},
+		{"broken message", testFormats + "msg_a = {0\n", testFormatsRu + "msg_a = {0}\n", "en msg_a: position"},
+		{"other arguments", testFormats + "msg_a = {name}\n", testFormatsRu + "msg_a = {0}\n",
+			"ru msg_a: arguments {0} differ from en {name}"},
+		{"short month", strings.Replace(testFormats, "fmt_date_long = January", "fmt_date_long = Jan", 1),
+			testFormatsRu, "en fmt_date_long: use January instead of Jan"},
+		{"short month with full one", strings.Replace(testFormats, "fmt_date = January 2", "fmt_date = January 2 (Jan)", 1),
+			testFormatsRu, "en fmt_date: use January instead of Jan"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkMessages(newMessages(t, map[string]string{"en": tt.en, "ru": tt.ru}))
+			if tt.problem == "" {
+				if err != nil {
+					t.Errorf("checkMessages() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.problem) {
+				t.Errorf("checkMessages() error = %v, want %q", err, tt.problem)
+			}
+		})
+	}
+}
+
+func TestLangFiles(t *testing.T) {
+	prev := tr()
+	t.Cleanup(func() { translations.Store(prev) })
+	if err := LoadLanguages("../../conf/lang", "en", map[string]string{"en": "English", "ru": "Русский"}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,392 @@
+package bot
+
+import (
+	"context"
+	"github.com/almaznur91/splitty/internal/api"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var greetingTemplates = []string{"tmpl_greeting_1", "tmpl_greeting_2", "tmpl_greeting_3"}
+
+// GreetingSetting shows the room birthday greeting and applies its options, react on viewGreeting and greeting actions
+type GreetingSetting struct {
+	bs  ButtonService
+	rs  RoomService
+	css ChatStateService
+	cfg *Config
+}
+
+// NewGreetingSetting makes a bot for screen greeting settings
+func NewGreetingSetting(bs ButtonService, rs RoomService, css ChatStateService, cfg *Config) *GreetingSetting {
+	return &GreetingSetting{
+		bs:  bs,
+		rs:  rs,
+		css: css,
+		cfg: cfg,
+	}
+}
+
+func (bot GreetingSetting) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && (hasAction(u, viewGreeting) || hasAction(u, activateGreeting) ||
+		hasAction(u, switchGreetingChat) || hasAction(u, switchGreetingPrivate) || hasAction(u, removeGreetingMedia))
+}
+
+func (bot *GreetingSetting) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	defer bot.css.CleanChatState(ctx, u.ChatState)
+
+	roomId := u.Button.CallbackData.RoomId
+	room, err := bot.rs.FindById(ctx, roomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", roomId)
+		return api.TelegramMessage{}, err
+	}
+
+	g := activeGreeting(room)
+	switch u.Button.Action {
+	case activateGreeting:
+		err = bot.rs.ActivateGreeting(ctx, u.User.ID, roomId)
+	case switchGreetingChat:
+		g.ToChat = !g.ToChat
+		err = bot.rs.SetGreeting(ctx, u.User.ID, roomId, &g)
+	case switchGreetingPrivate:
+		g.ToPrivate = !g.ToPrivate
+		err = bot.rs.SetGreeting(ctx, u.User.ID, roomId, &g)
+	case removeGreetingMedia:
+		g.PhotoId, g.VideoId = "", ""
+		err = bot.rs.SetGreeting(ctx, u.User.ID, roomId, &g)
+	}
+	if err != nil {
+		log.Error().Err(err).Msgf("greeting action %v failed", u.Button.Action)
+		return roomErrorMessage(u, err)
+	}
+	if u.Button.Action == activateGreeting {
+		if room, err = bot.rs.FindById(ctx, roomId); err != nil {
+			log.Error().Err(err).Msgf("cannot find room, id:%s", roomId)
+			return api.TelegramMessage{}, err
+		}
+		g = activeGreeting(room)
+	}
+
+	data := &api.CallbackData{RoomId: roomId}
+	chatB := api.NewButton(switchGreetingChat, data)
+	privateB := api.NewButton(switchGreetingPrivate, data)
+	templateB := api.NewButton(chooseGreetingTemplate, data)
+	writeB := api.NewButton(writeGreeting, data)
+	mediaB := api.NewButton(attachGreetingMedia, data)
+	backB := api.NewButton(roomSetting, data)
+	buttons := []*api.Button{chatB, privateB, templateB, writeB, mediaB, backB}
+
+	chatText := I18n(u.User, "btn_greeting_chat_off")
+	if g.ToChat {
+		chatText = I18n(u.User, "btn_greeting_chat_on")
+	}
+	privateText := I18n(u.User, "btn_greeting_private_off")
+	if g.ToPrivate {
+		privateText = I18n(u.User, "btn_greeting_private_on")
+	}
+
+	keyboard := [][]tgbotapi.InlineKeyboardButton{
+		{tgbotapi.NewInlineKeyboardButtonData(chatText, chatB.ID.Hex())},
+		{tgbotapi.NewInlineKeyboardButtonData(privateText, privateB.ID.Hex())},
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_greeting_templates"), templateB.ID.Hex()),
+			tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_greeting_write"), writeB.ID.Hex())},
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_greeting_media"), mediaB.ID.Hex())},
+	}
+	mediaText := I18n(u.User, "msg_greeting_no_media")
+	if g.PhotoId != "" || g.VideoId != "" {
+		mediaText = I18n(u.User, "msg_greeting_has_media")
+		removeB := api.NewButton(removeGreetingMedia, data)
+		buttons = append(buttons, removeB)
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_greeting_remove_media"), removeB.ID.Hex())})
+	}
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_back"), backB.ID.Hex())})
+
+	if _, err := bot.bs.SaveAll(ctx, buttons...); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	text := I18n(u.User, "scrn_greeting", room.Name, greetingTemplate(&g, u.User), mediaText)
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createTextScreen(u, text, &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// GreetingTemplates shows localized greeting templates, react on chooseGreetingTemplate action
+type GreetingTemplates struct {
+	bs  ButtonService
+	cfg *Config
+}
+
+// NewGreetingTemplates makes a bot for screen with greeting templates
+func NewGreetingTemplates(bs ButtonService, cfg *Config) *GreetingTemplates {
+	return &GreetingTemplates{
+		bs:  bs,
+		cfg: cfg,
+	}
+}
+
+func (bot GreetingTemplates) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && hasAction(u, chooseGreetingTemplate)
+}
+
+func (bot *GreetingTemplates) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	roomId := u.Button.CallbackData.RoomId
+
+	text := I18n(u.User, "scrn_greeting_templates")
+	var buttons []*api.Button
+	var keyboardButtons []tgbotapi.InlineKeyboardButton
+	for i, key := range greetingTemplates {
+		num := strconv.Itoa(i + 1)
+		text += "\n\n" + string(Bold(num+".")) + " " + I18n(u.User, key, greetingPlaceholders)
+		b := api.NewButton(previewGreeting, &api.CallbackData{RoomId: roomId, ExternalData: key})
+		buttons = append(buttons, b)
+		keyboardButtons = append(keyboardButtons, tgbotapi.NewInlineKeyboardButtonData(num, b.ID.Hex()))
+	}
+	backB := api.NewButton(viewGreeting, &api.CallbackData{RoomId: roomId})
+	buttons = append(buttons, backB)
+
+	if _, err := bot.bs.SaveAll(ctx, buttons...); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	keyboard := [][]tgbotapi.InlineKeyboardButton{keyboardButtons,
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_back"), backB.ID.Hex())}}
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createScreen(u, text, &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// GreetingInput asks organizer to write own greeting or send a photo or video, react on writeGreeting
+// and attachGreetingMedia actions
+type GreetingInput struct {
+	css ChatStateService
+	bs  ButtonService
+	cfg *Config
+}
+
+// NewGreetingInput makes a bot for greeting input screens
+func NewGreetingInput(s ChatStateService, bs ButtonService, cfg *Config) *GreetingInput {
+	return &GreetingInput{
+		css: s,
+		bs:  bs,
+		cfg: cfg,
+	}
+}
+
+func (bot GreetingInput) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && (hasAction(u, writeGreeting) || hasAction(u, attachGreetingMedia))
+}
+
+func (bot *GreetingInput) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	data := &api.CallbackData{RoomId: u.Button.CallbackData.RoomId}
+	cs := &api.ChatState{UserId: getChatID(u), Action: u.Button.Action, CallbackData: data}
+	if err := bot.css.Save(ctx, cs); err != nil {
+		log.Error().Err(err).Msg("create chat state failed")
+		return api.TelegramMessage{}, err
+	}
+
+	cb := api.NewButton(viewGreeting, data)
+	if _, err := bot.bs.SaveAll(ctx, cb); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	text := I18n(u.User, "scrn_write_greeting")
+	if u.Button.Action == attachGreetingMedia {
+		text = I18n(u.User, "scrn_send_greeting_media")
+	}
+	screen := createScreen(u, text, &[][]tgbotapi.InlineKeyboardButton{
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_cancel"), cb.ID.Hex())},
+	})
+
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{screen},
+		Send:      true,
+	}, nil
+}
+
+// GreetingPreview saves the greeting draft and shows how it looks, react on previewGreeting action
+// and on text or media sent in greeting input chat states
+type GreetingPreview struct {
+	css ChatStateService
+	bs  ButtonService
+	rs  RoomService
+	cfg *Config
+}
+
+// NewGreetingPreview makes a bot for greeting preview
+func NewGreetingPreview(s ChatStateService, bs ButtonService, rs RoomService, cfg *Config) *GreetingPreview {
+	return &GreetingPreview{
+		css: s,
+		bs:  bs,
+		rs:  rs,
+		cfg: cfg,
+	}
+}
+
+func (bot GreetingPreview) HasReact(u *api.Update) bool {
+	if !isPrivate(u) {
+		return false
+	}
+	if isButton(u) {
+		return hasAction(u, previewGreeting)
+	}
+	if u.Message == nil || u.ChatState == nil {
+		return false
+	}
+	return u.ChatState.Action == writeGreeting && hasMessage(u) ||
+		u.ChatState.Action == attachGreetingMedia && (u.Message.Image != nil || u.Message.Video != nil)
+}
+
+func (bot *GreetingPreview) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	data := u.ChatState.CallbackData
+	if isButton(u) {
+		data = u.Button.CallbackData
+	} else {
+		defer bot.css.CleanChatState(ctx, u.ChatState)
+	}
+
+	room, err := bot.rs.FindById(ctx, data.RoomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", data.RoomId)
+		return api.TelegramMessage{}, err
+	}
+
+	// the draft collects the text and the media sent one by one until it is activated
+	draft := activeGreeting(room)
+	if room.GreetingDraft != nil {
+		draft = *room.GreetingDraft
+	}
+	switch {
+	case isButton(u):
+		draft.TemplateKey, draft.Text = data.ExternalData, ""
+	case u.Message.Image != nil:
+		draft.PhotoId, draft.VideoId = u.Message.Image.FileID, ""
+	case u.Message.Video != nil:
+		draft.PhotoId, draft.VideoId = "", u.Message.Video.FileID
+	default:
+		draft.TemplateKey, draft.Text = "", u.Message.Text
+	}
+
+	if err := bot.rs.SetGreetingDraft(ctx, u.User.ID, room.ID.Hex(), &draft); err != nil {
+		log.Error().Err(err).Msgf("save greeting draft failed %v", room.ID.Hex())
+		return roomErrorMessage(u, err)
+	}
+
+	roomData := &api.CallbackData{RoomId: room.ID.Hex()}
+	activateB := api.NewButton(activateGreeting, roomData)
+	cancelB := api.NewButton(viewGreeting, roomData)
+	if _, err := bot.bs.SaveAll(ctx, activateB, cancelB); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+	keyboard := [][]tgbotapi.InlineKeyboardButton{
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_greeting_activate"), activateB.ID.Hex()),
+			tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_cancel"), cancelB.ID.Hex())},
+	}
+
+	text := I18n(u.User, "scrn_greeting_preview") + "\n\n" + renderGreeting(&draft, room, u.User, u.User, time.Now())
+	var screen tgbotapi.Chattable
+	if draft.PhotoId == "" && draft.VideoId == "" {
+		screen = createTextScreen(u, text, &keyboard)
+	} else {
+		screen = greetingMessage(getChatID(u), text, &draft, &keyboard)
+	}
+
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{screen},
+		Send:      true,
+	}, nil
+}
+
+// BirthdayGreetings makes greeting messages of the celebrants for the room chat and private chats of other members
+func BirthdayGreetings(room *api.Room, members []api.User, celebrants []api.User, now time.Time) []tgbotapi.Chattable {
+	g := activeGreeting(room)
+	var messages []tgbotapi.Chattable
+	for i := range celebrants {
+		c := &celebrants[i]
+		if g.ToChat && room.Chat.ID != 0 {
+			messages = append(messages, greetingMessage(room.Chat.ID, renderGreeting(&g, room, c, roomReader(room, c), now), &g, nil))
+		}
+		if !g.ToPrivate {
+			continue
+		}
+		for j := range members {
+			m := &members[j]
+			if m.ID == c.ID || m.NotificationOn != nil && !*m.NotificationOn {
+				continue
+			}
+			messages = append(messages, greetingMessage(m.ID, renderGreeting(&g, room, c, m, now), &g, nil))
+		}
+	}
+	return messages
+}
+
+func activeGreeting(r *api.Room) api.Greeting {
+	if r.Greeting != nil {
+		return *r.Greeting
+	}
+	return api.Greeting{TemplateKey: greetingTemplates[0], ToChat: true}
+}
+
+// greetingPlaceholders keep placeholders of templates as they are, so templates are shown and filled
+// the same way as greetings written by admins
+var greetingPlaceholders = Args{"name": "{name}", "age": "{age}", "room": "{room}"}
+
+// greetingTemplate returns the marked up template or the greeting written by the admin, which is escaped
+func greetingTemplate(g *api.Greeting, reader *api.User) Text {
+	if g.TemplateKey != "" {
+		return Text(I18n(reader, g.TemplateKey, greetingPlaceholders))
+	}
+	return Escape(g.Text)
+}
+
+// renderGreeting fills {name}, {age} and {room} placeholders in the reader language, templates get the age
+// with the plural word, e.g. "31 год". A template with age falls back to the default one when the celebrant hides the age
+func renderGreeting(g *api.Greeting, room *api.Room, celebrant *api.User, reader *api.User, now time.Time) string {
+	text := string(greetingTemplate(g, reader))
+	age := ""
+	if celebrant.BirtDate != nil && !celebrant.HideYear {
+		years := now.Year() - celebrant.BirtDate.Year()
+		age = strconv.Itoa(years)
+		if g.TemplateKey != "" {
+			age = I18n(reader, "msg_age", years)
+		}
+	}
+	if age == "" && strings.Contains(text, "{age}") {
+		text = I18n(reader, greetingTemplates[0], greetingPlaceholders)
+	}
+	return strings.NewReplacer("{name}", string(Mention(celebrant)), "{age}", age, "{room}", string(Escape(room.Name))).Replace(text)
+}
+
+func greetingMessage(chatId int64, text string, g *api.Greeting, keyboard *[][]tgbotapi.InlineKeyboardButton) tgbotapi.Chattable {
+	var markup interface{}
+	if keyboard != nil {
+		markup = tgbotapi.NewInlineKeyboardMarkup(*keyboard...)
+	}
+	switch {
+	case g.PhotoId != "":
+		msg := NewPhotoMessage(chatId, text, tgbotapi.FileID(g.PhotoId))
+		msg.ReplyMarkup = markup
+		return msg
+	case g.VideoId != "":
+		msg := NewVideoMessage(chatId, text, g.VideoId)
+		msg.ReplyMarkup = markup
+		return msg
+	default:
+		msg := tgbotapi.NewMessage(chatId, text)
+		msg.ParseMode = parseMode
+		msg.ReplyMarkup = markup
+		return msg
+	}
+}
@@ -0,0 +1,196 @@
+package bot
+
+import (
+	"context"
+	"github.com/almaznur91/splitty/internal/api"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+	"strings"
+)
+
+const bind string = "/bind"
+
+// BindRoom offers rooms to bind to the group chat, react on /bind command in a group
+type BindRoom struct {
+	bs  ButtonService
+	rs  RoomService
+	cfg *Config
+}
+
+// NewBindRoom makes a bot for /bind command
+func NewBindRoom(bs ButtonService, rs RoomService, cfg *Config) *BindRoom {
+	return &BindRoom{
+		bs:  bs,
+		rs:  rs,
+		cfg: cfg,
+	}
+}
+
+func (bot BindRoom) HasReact(u *api.Update) bool {
+	return isGroup(u) && hasMessage(u) &&
+		(u.Message.Text == bind || u.Message.Text == bind+"@"+bot.cfg.BotName)
+}
+
+func (bot *BindRoom) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	rooms, err := bot.rs.FindRoomsByUserId(ctx, u.User.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("find rooms failed")
+		return api.TelegramMessage{}, err
+	}
+
+	var buttons []*api.Button
+	var keyboardButtons []tgbotapi.InlineKeyboardButton
+	for _, r := range *rooms {
+		if !r.IsAdmin(u.User.ID) {
+			continue
+		}
+		b := api.NewButton(bindRoom, &api.CallbackData{RoomId: r.ID.Hex(), UserId: int(u.User.ID)})
+		buttons = append(buttons, b)
+		keyboardButtons = append(keyboardButtons, tgbotapi.NewInlineKeyboardButtonData(r.Name, b.ID.Hex()))
+	}
+	if len(buttons) == 0 {
+		return api.TelegramMessage{
+			Chattable: []tgbotapi.Chattable{tgbotapi.NewMessage(getChatID(u), I18n(u.User, "msg_no_rooms_to_bind"))},
+			Send:      true,
+		}, nil
+	}
+
+	if _, err := bot.bs.SaveAll(ctx, buttons...); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{NewMessage(getChatID(u), I18n(u.User, "scrn_choose_room_to_bind"),
+			optimizeKeyboardButtons(keyboardButtons))},
+		Send: true,
+	}, nil
+}
+
+// BindRoomChoose binds the chosen room to the group chat, react on bindRoom action
+type BindRoomChoose struct {
+	bs  ButtonService
+	rs  RoomService
+	cfg *Config
+}
+
+// NewBindRoomChoose makes a bot for binding the room to the group chat
+func NewBindRoomChoose(bs ButtonService, rs RoomService, cfg *Config) *BindRoomChoose {
+	return &BindRoomChoose{
+		bs:  bs,
+		rs:  rs,
+		cfg: cfg,
+	}
+}
+
+func (bot BindRoomChoose) HasReact(u *api.Update) bool {
+	return isButton(u) && isGroup(u) && hasAction(u, bindRoom)
+}
+
+func (bot *BindRoomChoose) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	data := u.Button.CallbackData
+	if int64(data.UserId) != u.User.ID {
+		return roomErrorMessage(u, api.ErrForbidden)
+	}
+
+	if err := bot.rs.BindChat(ctx, u.User.ID, data.RoomId, *u.CallbackQuery.Message.Chat); err != nil {
+		log.Error().Err(err).Msgf("bind chat to room %v failed", data.RoomId)
+		return roomErrorMessage(u, err)
+	}
+
+	room, err := bot.rs.FindById(ctx, data.RoomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", data.RoomId)
+		return api.TelegramMessage{}, err
+	}
+
+	joinB := api.NewButton(joinRoom, &api.CallbackData{RoomId: data.RoomId})
+	if _, err := bot.bs.SaveAll(ctx, joinB); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	reader := roomReader(room, u.User)
+	keyboard := [][]tgbotapi.InlineKeyboardButton{
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(reader, "btn_join"), joinB.ID.Hex())},
+	}
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createScreen(u, I18n(reader, "scrn_room_bound", room.Name), &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// GroupMembers syncs members of the group chat into the bound room, react on any group message
+type GroupMembers struct {
+	rs  RoomService
+	cfg *Config
+}
+
+// NewGroupMembers makes a bot for group members sync
+func NewGroupMembers(rs RoomService, cfg *Config) *GroupMembers {
+	return &GroupMembers{
+		rs:  rs,
+		cfg: cfg,
+	}
+}
+
+func (bot GroupMembers) HasReact(u *api.Update) bool {
+	return isGroup(u) && u.Message != nil
+}
+
+func (bot *GroupMembers) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	chatId := u.Message.Chat.ID
+	var response api.TelegramMessage
+
+	if u.Message.NewChatMembers != nil {
+		for _, m := range *u.Message.NewChatMembers {
+			if bot.isSelf(&m) {
+				response = api.TelegramMessage{
+					Chattable: []tgbotapi.Chattable{tgbotapi.NewMessage(chatId, I18n(u.User, "msg_bot_added"))},
+					Send:      true,
+				}
+				continue
+			}
+			if err := bot.rs.SyncChatMember(ctx, chatId, m); err != nil {
+				log.Error().Err(err).Msgf("sync chat member %v failed", m.ID)
+				return api.TelegramMessage{}, err
+			}
+		}
+	}
+
+	if left := u.Message.LeftChatMember; left != nil {
+		var err error
+		if bot.isSelf(left) {
+			err = bot.rs.UnbindChat(ctx, chatId)
+		} else {
+			err = bot.rs.RemoveChatMember(ctx, chatId, left.ID)
+		}
+		if err != nil {
+			log.Error().Err(err).Msgf("remove chat member %v failed", left.ID)
+			return api.TelegramMessage{}, err
+		}
+		if left.ID == u.Message.From.ID {
+			return response, nil
+		}
+	}
+
+	if err := bot.rs.SyncChatMember(ctx, chatId, u.Message.From); err != nil {
+		log.Error().Err(err).Msgf("sync chat member %v failed", u.Message.From.ID)
+		return api.TelegramMessage{}, err
+	}
+	return response, nil
+}
+
+func (bot *GroupMembers) isSelf(u *api.User) bool {
+	return strings.EqualFold(u.Username, bot.cfg.BotName)
+}
+
+func isGroup(u *api.Update) bool {
+	var chat *api.Chat
+	if u.Message != nil {
+		chat = u.Message.Chat
+	} else if u.CallbackQuery != nil && u.CallbackQuery.Message != nil {
+		chat = u.CallbackQuery.Message.Chat
+	}
+	return chat != nil && (chat.Type == "group" || chat.Type == "supergroup")
+}
@@ -0,0 +1,327 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"github.com/almaznur91/splitty/internal/api"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+	"strings"
+	"time"
+)
+
+// joinPrefix starts the deep link parameter with the invite code, /start join_<code>
+const joinPrefix = "join_"
+
+// invitePresets are kinds of invites offered to admins
+var invitePresets = []struct {
+	name     string
+	ttl      time.Duration
+	maxUses  int
+	approval bool
+}{
+	{"day", 24 * time.Hour, 0, false},
+	{"week", 7 * 24 * time.Hour, 0, false},
+	{"once", 7 * 24 * time.Hour, 1, false},
+	{"approval", 0, 0, true},
+}
+
+// RoomInvites shows invites and join requests of the room, react on viewInvites action
+type RoomInvites struct {
+	bs  ButtonService
+	rs  RoomService
+	is  InviteService
+	css ChatStateService
+	cfg *Config
+}
+
+// NewRoomInvites makes a bot for screen room invites
+func NewRoomInvites(bs ButtonService, rs RoomService, is InviteService, css ChatStateService, cfg *Config) *RoomInvites {
+	return &RoomInvites{
+		bs:  bs,
+		rs:  rs,
+		is:  is,
+		css: css,
+		cfg: cfg,
+	}
+}
+
+func (bot RoomInvites) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && hasAction(u, viewInvites)
+}
+
+func (bot *RoomInvites) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	defer bot.css.CleanChatState(ctx, u.ChatState)
+
+	roomId := u.Button.CallbackData.RoomId
+	room, err := bot.rs.FindById(ctx, roomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", roomId)
+		return api.TelegramMessage{}, err
+	}
+	invites, err := bot.is.FindInvites(ctx, u.User.ID, roomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("find invites of room %v failed", roomId)
+		return roomErrorMessage(u, err)
+	}
+
+	text := I18n(u.User, "scrn_room_invites", room.Name)
+	if len(*invites) == 0 {
+		text += I18n(u.User, "msg_no_invites")
+	}
+	var buttons []*api.Button
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	var revokeRow []tgbotapi.InlineKeyboardButton
+	for n, i := range *invites {
+		text += fmt.Sprintf("%d. %s\n%s\n\n", n+1, Code(inviteLink(bot.cfg, &i)), inviteInfo(u.User, &i))
+		b := api.NewButton(revokeInvite, &api.CallbackData{RoomId: roomId, ExternalId: i.ID.Hex()})
+		buttons = append(buttons, b)
+		revokeRow = append(revokeRow, tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_revoke_invite", n+1), b.ID.Hex()))
+	}
+	if len(revokeRow) > 0 {
+		keyboard = append(keyboard, revokeRow)
+	}
+
+	var createRow []tgbotapi.InlineKeyboardButton
+	for _, p := range invitePresets {
+		b := api.NewButton(createInvite, &api.CallbackData{RoomId: roomId, ExternalData: p.name})
+		buttons = append(buttons, b)
+		createRow = append(createRow, tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_invite_"+p.name), b.ID.Hex()))
+		if len(createRow) == 2 {
+			keyboard = append(keyboard, createRow)
+			createRow = nil
+		}
+	}
+
+	for _, r := range room.JoinRequests {
+		data := &api.CallbackData{RoomId: roomId, UserId: int(r.ID), ExternalData: string(viewInvites)}
+		approveB := api.NewButton(approveJoin, data)
+		declineB := api.NewButton(declineJoin, data)
+		buttons = append(buttons, approveB, declineB)
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(I18nPlain(u.User, "btn_approve_join", r.DisplayName), approveB.ID.Hex()),
+			tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_decline_join"), declineB.ID.Hex())})
+	}
+
+	backB := api.NewButton(roomSetting, &api.CallbackData{RoomId: roomId})
+	buttons = append(buttons, backB)
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_back"), backB.ID.Hex())})
+
+	if _, err := bot.bs.SaveAll(ctx, buttons...); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createScreen(u, text, &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// InviteSetting creates or revokes invites of the room, react on createInvite and revokeInvite actions
+type InviteSetting struct {
+	is  InviteService
+	cfg *Config
+}
+
+// NewInviteSetting makes a bot for creating and revoking invites
+func NewInviteSetting(is InviteService, cfg *Config) *InviteSetting {
+	return &InviteSetting{
+		is:  is,
+		cfg: cfg,
+	}
+}
+
+func (bot InviteSetting) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && (hasAction(u, createInvite) || hasAction(u, revokeInvite))
+}
+
+func (bot *InviteSetting) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	data := u.Button.CallbackData
+
+	var err error
+	if u.Button.Action == revokeInvite {
+		_, err = bot.is.RevokeInvite(ctx, u.User.ID, data.ExternalId)
+	} else {
+		for _, p := range invitePresets {
+			if p.name == data.ExternalData {
+				_, err = bot.is.CreateInvite(ctx, u.User.ID, data.RoomId, p.ttl, p.maxUses, p.approval)
+			}
+		}
+	}
+	if err != nil {
+		log.Error().Err(err).Msgf("change invites of room %v failed", data.RoomId)
+		return roomErrorMessage(u, err)
+	}
+
+	return api.TelegramMessage{
+		Redirect: &api.Update{CallbackQuery: u.CallbackQuery, User: u.User, Button: api.NewButton(viewInvites, &api.CallbackData{RoomId: data.RoomId})},
+	}, nil
+}
+
+// InviteJoin adds the user to the room by the invite deep link, react on /start join_<code>
+type InviteJoin struct {
+	bs  ButtonService
+	is  InviteService
+	us  UserService
+	cfg *Config
+}
+
+// NewInviteJoin makes a bot for joining rooms by invites
+func NewInviteJoin(bs ButtonService, is InviteService, us UserService, cfg *Config) *InviteJoin {
+	return &InviteJoin{
+		bs:  bs,
+		is:  is,
+		us:  us,
+		cfg: cfg,
+	}
+}
+
+func (bot InviteJoin) HasReact(u *api.Update) bool {
+	return isPrivate(u) && hasMessage(u) && strings.HasPrefix(u.Message.Text, start+" "+joinPrefix)
+}
+
+func (bot *InviteJoin) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	code := strings.TrimPrefix(u.Message.Text, start+" "+joinPrefix)
+	room, pending, err := bot.is.JoinByInvite(ctx, u.Message.From, code)
+	if err != nil {
+		log.Error().Err(err).Msgf("join by invite %v failed", code)
+		return roomErrorMessage(u, err)
+	}
+
+	if pending {
+		notices, err := bot.requestNotices(ctx, room, &u.Message.From)
+		if err != nil {
+			return api.TelegramMessage{}, err
+		}
+		msg := tgbotapi.NewMessage(getChatID(u), I18n(u.User, "msg_join_requested", room.Name))
+		msg.ParseMode = parseMode
+		return api.TelegramMessage{Chattable: append([]tgbotapi.Chattable{msg}, notices...), Send: true}, nil
+	}
+
+	viewB := api.NewButton(viewRoom, &api.CallbackData{RoomId: room.ID.Hex()})
+	if _, err := bot.bs.SaveAll(ctx, viewB); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+	text := I18n(u.User, "msg_joined_room", room.Name)
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{NewMessage(getChatID(u), text, [][]tgbotapi.InlineKeyboardButton{
+			{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_open_room"), viewB.ID.Hex())}})},
+		Send: true,
+	}, nil
+}
+
+// requestNotices asks admins of the room to approve the user
+func (bot *InviteJoin) requestNotices(ctx context.Context, room *api.Room, requester *api.User) ([]tgbotapi.Chattable, error) {
+	admins, err := bot.us.FindByIds(ctx, append([]int64{room.Owner}, room.Admins...))
+	if err != nil {
+		log.Error().Err(err).Msgf("find admins of room %v failed", room.ID.Hex())
+		return nil, err
+	}
+
+	var notices []tgbotapi.Chattable
+	var buttons []*api.Button
+	for _, admin := range *admins {
+		admin := admin
+		data := &api.CallbackData{RoomId: room.ID.Hex(), UserId: int(requester.ID)}
+		approveB := api.NewButton(approveJoin, data)
+		declineB := api.NewButton(declineJoin, data)
+		buttons = append(buttons, approveB, declineB)
+		text := I18n(&admin, "msg_join_request", Mention(requester), room.Name)
+		notices = append(notices, NewMessage(admin.ID, text, [][]tgbotapi.InlineKeyboardButton{{
+			tgbotapi.NewInlineKeyboardButtonData(I18nPlain(&admin, "btn_approve_join", requester.DisplayName), approveB.ID.Hex()),
+			tgbotapi.NewInlineKeyboardButtonData(I18n(&admin, "btn_decline_join"), declineB.ID.Hex()),
+		}}))
+	}
+	if len(buttons) > 0 {
+		if _, err := bot.bs.SaveAll(ctx, buttons...); err != nil {
+			log.Error().Err(err).Msg("create btn failed")
+			return nil, err
+		}
+	}
+	return notices, nil
+}
+
+// JoinRequestReview lets the user who came by the invite with approval into the room or rejects the request.
+// React on approveJoin and declineJoin actions
+type JoinRequestReview struct {
+	is  InviteService
+	rs  RoomService
+	us  UserService
+	cfg *Config
+}
+
+// NewJoinRequestReview makes a bot for reviewing join requests
+func NewJoinRequestReview(is InviteService, rs RoomService, us UserService, cfg *Config) *JoinRequestReview {
+	return &JoinRequestReview{
+		is:  is,
+		rs:  rs,
+		us:  us,
+		cfg: cfg,
+	}
+}
+
+func (bot JoinRequestReview) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && (hasAction(u, approveJoin) || hasAction(u, declineJoin))
+}
+
+func (bot *JoinRequestReview) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	data := u.Button.CallbackData
+	approve := u.Button.Action == approveJoin
+	requester, err := bot.is.ReviewJoinRequest(ctx, u.User.ID, data.RoomId, int64(data.UserId), approve)
+	if err != nil {
+		log.Error().Err(err).Msgf("review join request to room %v failed", data.RoomId)
+		return roomErrorMessage(u, err)
+	}
+	room, err := bot.rs.FindById(ctx, data.RoomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", data.RoomId)
+		return api.TelegramMessage{}, err
+	}
+
+	// the saved user keeps the selected language, the request has only the telegram one
+	if user, err := bot.us.FindById(ctx, requester.ID); err == nil && user != nil {
+		requester = user
+	}
+	resultKey, noticeKey := "msg_join_declined", "msg_join_request_declined"
+	if approve {
+		resultKey, noticeKey = "msg_join_approved", "msg_join_request_approved"
+	}
+	notice := tgbotapi.NewMessage(requester.ID, I18n(requester, noticeKey, room.Name))
+	notice.ParseMode = parseMode
+
+	if data.ExternalData == string(viewInvites) {
+		return api.TelegramMessage{
+			Chattable: []tgbotapi.Chattable{notice},
+			Send:      true,
+			Redirect:  &api.Update{CallbackQuery: u.CallbackQuery, User: u.User, Button: api.NewButton(viewInvites, &api.CallbackData{RoomId: data.RoomId})},
+		}, nil
+	}
+	text := I18n(u.User, resultKey, Mention(requester), room.Name)
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createScreen(u, text, &[][]tgbotapi.InlineKeyboardButton{}), notice},
+		Send:      true,
+	}, nil
+}
+
+func inviteLink(cfg *Config, i *api.Invite) string {
+	return "http://t.me/" + cfg.BotName + "?start=" + joinPrefix + i.Code
+}
+
+// inviteInfo describes limits of the invite
+func inviteInfo(user *api.User, i *api.Invite) string {
+	var parts []string
+	if i.ExpiresAt != nil {
+		parts = append(parts, I18n(user, "msg_invite_expires", i.ExpiresAt.In(userLocation(user))))
+	}
+	if i.MaxUses > 0 {
+		parts = append(parts, I18n(user, "msg_invite_uses", i.Uses, i.MaxUses))
+	} else {
+		parts = append(parts, I18n(user, "msg_invite_used", i.Uses))
+	}
+	if i.Approval {
+		parts = append(parts, I18n(user, "msg_invite_approval"))
+	}
+	return strings.Join(parts, ", ")
+}
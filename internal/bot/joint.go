@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"context"
+	"github.com/almaznur91/splitty/internal/api"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// JointMerge merges the collection into the joint collection of another room or keeps it separate,
+// react on mergeCollections and keepSeparate actions
+type JointMerge struct {
+	bs  ButtonService
+	rs  RoomService
+	cs  CollectionService
+	us  UserService
+	cfg *Config
+}
+
+// NewJointMerge makes a bot for answers to joint collection offers
+func NewJointMerge(bs ButtonService, rs RoomService, cs CollectionService, us UserService, cfg *Config) *JointMerge {
+	return &JointMerge{
+		bs:  bs,
+		rs:  rs,
+		cs:  cs,
+		us:  us,
+		cfg: cfg,
+	}
+}
+
+func (bot JointMerge) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && (hasAction(u, mergeCollections) || hasAction(u, keepSeparate))
+}
+
+func (bot *JointMerge) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	data := u.Button.CallbackData
+	empty := &[][]tgbotapi.InlineKeyboardButton{}
+	if u.Button.Action == keepSeparate {
+		return api.TelegramMessage{
+			Chattable: []tgbotapi.Chattable{createScreen(u, I18n(u.User, "msg_joint_kept"), empty)},
+			Send:      true,
+		}, nil
+	}
+
+	joint, err := bot.cs.MergeCollections(ctx, u.User.ID, data.ExternalId, data.ExternalData)
+	if err != nil {
+		log.Error().Err(err).Msgf("merge collection %v into %v failed", data.ExternalId, data.ExternalData)
+		return roomErrorMessage(u, err)
+	}
+	room, err := bot.rs.FindById(ctx, data.RoomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", data.RoomId)
+		return api.TelegramMessage{}, err
+	}
+	jointRoom, err := bot.rs.FindById(ctx, joint.RoomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", joint.RoomId)
+		return api.TelegramMessage{}, err
+	}
+	organizer, err := bot.us.FindById(ctx, joint.Organizer)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find user, id:%v", joint.Organizer)
+		return api.TelegramMessage{}, err
+	}
+
+	debtsB := api.NewButton(chooseDebts, &api.CallbackData{RoomId: data.RoomId})
+	if _, err := bot.bs.SaveAll(ctx, debtsB); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+	text := I18n(u.User, "msg_joint_merged", collectionTitle(u.User, joint), jointRoom.Name, Mention(organizer))
+	notice := tgbotapi.NewMessage(organizer.ID, I18n(organizer, "msg_joint_joined", room.Name, collectionTitle(organizer, joint)))
+	notice.ParseMode = parseMode
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{
+			createScreen(u, text, &[][]tgbotapi.InlineKeyboardButton{
+				{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_debts"), debtsB.ID.Hex())}}),
+			notice,
+		},
+		Send: true,
+	}, nil
+}
+
+// JointOffer offers the organizer to merge the collection into the joint collection of another room.
+// Returned buttons must be saved before the message is sent
+func JointOffer(organizer *api.User, collection *api.Collection, joint *api.Collection, jointRoom *api.Room) (tgbotapi.Chattable, []*api.Button) {
+	mergeB := api.NewButton(mergeCollections, &api.CallbackData{RoomId: collection.RoomId, ExternalId: collection.ID.Hex(), ExternalData: joint.ID.Hex()})
+	keepB := api.NewButton(keepSeparate, &api.CallbackData{RoomId: collection.RoomId})
+	text := I18n(organizer, "msg_joint_offer", jointRoom.Name, collectionTitle(organizer, joint))
+	return NewMessage(organizer.ID, text, [][]tgbotapi.InlineKeyboardButton{
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(organizer, "btn_merge_collections"), mergeB.ID.Hex())},
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(organizer, "btn_keep_separate"), keepB.ID.Hex())},
+	}), []*api.Button{mergeB, keepB}
+}
+
+// jointOffer makes the offer to merge the new collection if the celebrant has an active collection in another room, nil otherwise
+func jointOffer(ctx context.Context, bs ButtonService, rs RoomService, cs CollectionService, organizer *api.User, collection *api.Collection) (tgbotapi.Chattable, error) {
+	joint, err := cs.FindJointCandidate(ctx, collection)
+	if err != nil || joint == nil {
+		return nil, err
+	}
+	jointRoom, err := rs.FindById(ctx, joint.RoomId)
+	if err != nil {
+		return nil, err
+	}
+	msg, buttons := JointOffer(organizer, collection, joint, jointRoom)
+	if _, err := bs.SaveAll(ctx, buttons...); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
@@ -0,0 +1,251 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"github.com/gookit/i18n"
+	"github.com/rs/zerolog/log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// langCheckInterval is how often lang files are checked for changes
+const langCheckInterval = 2 * time.Second
+
+// translations holds *i18n.I18n, it is replaced as a whole when lang files are reloaded
+var translations atomic.Value
+
+// langFiles are lang files of the last LoadLanguages call, WatchLanguages reloads them
+var langFiles *languages
+
+type languages struct {
+	dir      string
+	defLang  string
+	names    map[string]string
+	modTimes map[string]time.Time
+}
+
+// LoadLanguages loads <dir>/<lang>.ini for every language, names are language names by codes.
+// Every language must have every key, so a missing translation is found at the start, not by the user
+func LoadLanguages(dir string, defLang string, names map[string]string) error {
+	l := &languages{dir: dir, defLang: defLang, names: names}
+	t, modTimes, err := l.load()
+	if err != nil {
+		return err
+	}
+	l.modTimes = modTimes
+	translations.Store(t)
+	langFiles = l
+	return nil
+}
+
+// WatchLanguages reloads lang files when they change on disk, blocked call. Broken files are logged and
+// the previous translations are kept until the files are fixed
+func WatchLanguages(ctx context.Context) {
+	if langFiles == nil {
+		return
+	}
+	ticker := time.NewTicker(langCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		modTimes, err := langFiles.stat()
+		if err != nil {
+			log.Error().Err(err).Msg("check lang files failed")
+			continue
+		}
+		if !langFiles.changed(modTimes) {
+			continue
+		}
+		// files are remembered even if they are broken, so the error is logged once per change
+		langFiles.modTimes = modTimes
+		t, _, err := langFiles.load()
+		if err != nil {
+			log.Error().Err(err).Msg("reload lang files failed, previous translations are kept")
+			continue
+		}
+		translations.Store(t)
+		log.Info().Msgf("lang files reloaded from %s", langFiles.dir)
+	}
+}
+
+// tr returns current translations, the default instance is used until LoadLanguages is called
+func tr() *i18n.I18n {
+	if t, ok := translations.Load().(*i18n.I18n); ok {
+		return t
+	}
+	return i18n.Default()
+}
+
+func (l *languages) file(lang string) string {
+	return filepath.Join(l.dir, lang+".ini")
+}
+
+// load reads all lang files and checks them, modification times are taken before reading,
+// so a change made during the load is loaded again
+func (l *languages) load() (*i18n.I18n, map[string]time.Time, error) {
+	modTimes, err := l.stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	t := i18n.NewEmpty()
+	t.DefaultLang = l.defLang
+	for lang, name := range l.names {
+		t.NewLang(lang, name)
+		if err := t.LoadFile(lang, l.file(lang)); err != nil {
+			return nil, nil, fmt.Errorf("lang file %s: %v", l.file(lang), err)
+		}
+	}
+	if !t.HasLang(l.defLang) {
+		return nil, nil, fmt.Errorf("default language %q has no lang file", l.defLang)
+	}
+	if err := checkKeys(t); err != nil {
+		return nil, nil, err
+	}
+	if err := checkMessages(t); err != nil {
+		return nil, nil, err
+	}
+	return t, modTimes, nil
+}
+
+func (l *languages) stat() (map[string]time.Time, error) {
+	modTimes := make(map[string]time.Time, len(l.names))
+	for lang := range l.names {
+		fi, err := os.Stat(l.file(lang))
+		if err != nil {
+			return nil, err
+		}
+		modTimes[lang] = fi.ModTime()
+	}
+	return modTimes, nil
+}
+
+func (l *languages) changed(modTimes map[string]time.Time) bool {
+	for lang, mt := range modTimes {
+		if !mt.Equal(l.modTimes[lang]) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkKeys reports keys which are in some languages but not in others
+func checkKeys(t *i18n.I18n) error {
+	keys := make(map[string]map[string]bool)
+	all := make(map[string]bool)
+	for lang := range t.Languages() {
+		keys[lang] = make(map[string]bool)
+		for k := range langMessages(t, lang) {
+			keys[lang][k] = true
+		}
+		for k := range keys[lang] {
+			all[k] = true
+		}
+	}
+
+	var problems []string
+	for lang, has := range keys {
+		var missing []string
+		for k := range all {
+			if !has[k] {
+				missing = append(missing, k)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			problems = append(problems, fmt.Sprintf("%s misses %s", lang, strings.Join(missing, ", ")))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("lang files are incomplete: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// checkMessages parses every message, translations must use the same arguments as the default language.
+// Date layouts may have only the month token which is localized
+func checkMessages(t *i18n.I18n) error {
+	args := make(map[string]map[string]map[string]bool)
+	var problems []string
+	for lang := range t.Languages() {
+		args[lang] = make(map[string]map[string]bool)
+		for key, msg := range langMessages(t, lang) {
+			parts, err := parseMessage(msg)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s %s: %v", lang, key, err))
+				continue
+			}
+			args[lang][key] = make(map[string]bool)
+			messageArgs(parts, args[lang][key])
+		}
+	}
+	for lang := range t.Languages() {
+		for _, key := range dateStyles {
+			// only the full month name is replaced by fmt_months, Jan would be shown in English
+			if strings.Contains(strings.ReplaceAll(t.Tr(lang, key), "January", ""), "Jan") {
+				problems = append(problems, fmt.Sprintf("%s %s: use January instead of Jan", lang, key))
+			}
+		}
+	}
+	for lang, keys := range args {
+		for key, names := range keys {
+			def, ok := args[t.DefaultLang][key]
+			if ok && !sameNames(names, def) {
+				problems = append(problems, fmt.Sprintf("%s %s: arguments %s differ from %s %s", lang, key,
+					joinNames(names), t.DefaultLang, joinNames(def)))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("lang files have broken messages: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// langMessages returns messages of the language by keys, keys of sections are prefixed with the section name
+func langMessages(t *i18n.I18n, lang string) map[string]string {
+	messages := make(map[string]string)
+	data := t.Lang(lang)
+	for name, section := range data.Data() {
+		for k, v := range section {
+			if name != data.DefSection() {
+				k = name + "." + k
+			}
+			messages[k] = v
+		}
+	}
+	return messages
+}
+
+func sameNames(a, b map[string]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for n := range a {
+		if !b[n] {
+			return false
+		}
+	}
+	return true
+}
+
+func joinNames(names map[string]bool) string {
+	s := make([]string, 0, len(names))
+	for n := range names {
+		s = append(s, "{"+n+"}")
+	}
+	sort.Strings(s)
+	return strings.Join(s, " ")
+}
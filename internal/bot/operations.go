@@ -0,0 +1,308 @@
+package bot
+
+import (
+	"context"
+	"github.com/almaznur91/splitty/internal/api"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OperationHistory shows operations of the room page by page, react on chooseOperations action
+type OperationHistory struct {
+	bs  ButtonService
+	rs  RoomService
+	cs  CollectionService
+	css ChatStateService
+	cfg *Config
+}
+
+// NewOperationHistory makes a bot for screen with history of operations
+func NewOperationHistory(bs ButtonService, rs RoomService, cs CollectionService, css ChatStateService, cfg *Config) *OperationHistory {
+	return &OperationHistory{
+		bs:  bs,
+		rs:  rs,
+		cs:  cs,
+		css: css,
+		cfg: cfg,
+	}
+}
+
+func (bot OperationHistory) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && hasAction(u, chooseOperations)
+}
+
+func (bot *OperationHistory) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	defer bot.css.CleanChatState(ctx, u.ChatState)
+
+	data := u.Button.CallbackData
+	room, err := bot.rs.FindById(ctx, data.RoomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", data.RoomId)
+		return api.TelegramMessage{}, err
+	}
+	ops, err := bot.cs.FindOperations(ctx, u.User.ID, data.RoomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("find operations of room %v failed", data.RoomId)
+		return roomErrorMessage(u, err)
+	}
+
+	reverted := make(map[primitive.ObjectID]bool)
+	for _, op := range *ops {
+		if op.RevertOf != nil {
+			reverted[*op.RevertOf] = true
+		}
+	}
+
+	page, pages, from, to := pageBounds(len(*ops), data.Page, u.User.CountInPage)
+	var buttons []*api.Button
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	for _, op := range (*ops)[from:to] {
+		b := api.NewButton(viewOperation, &api.CallbackData{RoomId: data.RoomId, OperationId: op.ID, Page: page})
+		buttons = append(buttons, b)
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(operationTitle(u.User, &op, reverted[op.ID]), b.ID.Hex())})
+	}
+
+	var pagination []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		prevB := api.NewButton(chooseOperations, &api.CallbackData{RoomId: data.RoomId, Page: page - 1})
+		pagination = append(pagination, tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_prev_page"), prevB.ID.Hex()))
+		buttons = append(buttons, prevB)
+	}
+	if page < pages-1 {
+		nextB := api.NewButton(chooseOperations, &api.CallbackData{RoomId: data.RoomId, Page: page + 1})
+		pagination = append(pagination, tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_next_page"), nextB.ID.Hex()))
+		buttons = append(buttons, nextB)
+	}
+	if len(pagination) > 0 {
+		keyboard = append(keyboard, pagination)
+	}
+
+	backB := api.NewButton(viewRoom, &api.CallbackData{RoomId: data.RoomId})
+	buttons = append(buttons, backB)
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_back"), backB.ID.Hex())})
+
+	if _, err := bot.bs.SaveAll(ctx, buttons...); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	text := I18n(u.User, "scrn_operations", room.Name)
+	if len(*ops) == 0 {
+		text += "\n\n" + I18n(u.User, "msg_no_operations")
+	} else if pages > 1 {
+		text += "\n\n" + I18n(u.User, "msg_page", page+1, pages)
+	}
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createTextScreen(u, text, &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// OperationView shows the operation, undoes it and asks the correct amount, react on viewOperation, undoOperation
+// and editOperation actions
+type OperationView struct {
+	bs  ButtonService
+	rs  RoomService
+	cs  CollectionService
+	css ChatStateService
+	cfg *Config
+}
+
+// NewOperationView makes a bot for screen with operation
+func NewOperationView(bs ButtonService, rs RoomService, cs CollectionService, css ChatStateService, cfg *Config) *OperationView {
+	return &OperationView{
+		bs:  bs,
+		rs:  rs,
+		cs:  cs,
+		css: css,
+		cfg: cfg,
+	}
+}
+
+func (bot OperationView) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) &&
+		(hasAction(u, viewOperation) || hasAction(u, undoOperation) || hasAction(u, editOperation))
+}
+
+func (bot *OperationView) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	data := u.Button.CallbackData
+	viewData := &api.CallbackData{RoomId: data.RoomId, OperationId: data.OperationId, Page: data.Page}
+
+	switch u.Button.Action {
+	case undoOperation:
+		if _, err := bot.cs.UndoOperation(ctx, u.User.ID, data.OperationId.Hex()); err != nil {
+			log.Error().Err(err).Msgf("undo operation %v failed", data.OperationId.Hex())
+			return roomErrorMessage(u, err)
+		}
+		return api.TelegramMessage{
+			CallbackConfig: createCallback(u, I18n(u.User, "msg_operation_undone"), false),
+			Redirect:       &api.Update{CallbackQuery: u.CallbackQuery, User: u.User, Button: api.NewButton(viewOperation, viewData)},
+			Send:           true,
+		}, nil
+	case editOperation:
+		op, err := bot.cs.FindOperationById(ctx, data.OperationId.Hex())
+		if err != nil {
+			log.Error().Err(err).Msgf("cannot find operation, id:%s", data.OperationId.Hex())
+			return api.TelegramMessage{}, err
+		}
+		cs := &api.ChatState{UserId: getChatID(u), Action: writeOperationAmount, CallbackData: viewData}
+		if err := bot.css.Save(ctx, cs); err != nil {
+			log.Error().Err(err).Msg("create chat state failed")
+			return api.TelegramMessage{}, err
+		}
+		cb := api.NewButton(viewOperation, viewData)
+		if _, err := bot.bs.SaveAll(ctx, cb); err != nil {
+			log.Error().Err(err).Msg("create btn failed")
+			return api.TelegramMessage{}, err
+		}
+		keyboard := [][]tgbotapi.InlineKeyboardButton{
+			{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_cancel"), cb.ID.Hex())},
+		}
+		key := "scrn_write_operation_amount"
+		if op.Type == api.OperationTarget {
+			key = "scrn_write_collection_amount"
+		}
+		return api.TelegramMessage{
+			Chattable: []tgbotapi.Chattable{createScreen(u, I18n(u.User, key, op.Amount.Currency), &keyboard)},
+			Send:      true,
+		}, nil
+	}
+	defer bot.css.CleanChatState(ctx, u.ChatState)
+
+	op, err := bot.cs.FindOperationById(ctx, data.OperationId.Hex())
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find operation, id:%s", data.OperationId.Hex())
+		return api.TelegramMessage{}, err
+	}
+	room, err := bot.rs.FindById(ctx, op.RoomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", op.RoomId)
+		return api.TelegramMessage{}, err
+	}
+	if !room.IsMember(u.User.ID) {
+		return roomErrorMessage(u, api.ErrNotMember)
+	}
+	collection, err := bot.cs.FindCollectionById(ctx, op.CollectionId.Hex())
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find collection, id:%s", op.CollectionId.Hex())
+		return api.TelegramMessage{}, err
+	}
+	reverted, err := bot.cs.IsOperationReverted(ctx, op.ID)
+	if err != nil {
+		log.Error().Err(err).Msgf("check operation %v undone failed", op.ID.Hex())
+		return api.TelegramMessage{}, err
+	}
+
+	var buttons []*api.Button
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	if !reverted && op.IsEditable(collection) && (collection.Organizer == u.User.ID || room.IsAdmin(u.User.ID)) {
+		undoB := api.NewButton(undoOperation, viewData)
+		editB := api.NewButton(editOperation, viewData)
+		buttons = append(buttons, undoB, editB)
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_undo_operation"), undoB.ID.Hex()),
+			tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_edit_operation"), editB.ID.Hex())})
+	}
+	backB := api.NewButton(chooseOperations, &api.CallbackData{RoomId: op.RoomId, Page: data.Page})
+	buttons = append(buttons, backB)
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_back"), backB.ID.Hex())})
+
+	if _, err := bot.bs.SaveAll(ctx, buttons...); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	text := I18n(u.User, "scrn_operation", I18n(u.User, "msg_operation_"+string(op.Type)), collectionTitle(u.User, collection),
+		formatMoney(u.User, op.Amount), Mention(&op.Author), op.CreateAt.In(userLocation(u.User)))
+	if op.User != nil {
+		text += "\n" + I18n(u.User, "msg_operation_member", Mention(op.User))
+	}
+	if op.Receipt != nil {
+		text += "\n" + I18n(u.User, "msg_has_receipt")
+	}
+	if op.RevertOf != nil {
+		text += "\n\n" + I18n(u.User, "msg_operation_compensation")
+	} else if reverted {
+		text += "\n\n" + I18n(u.User, "msg_operation_reverted")
+	}
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createTextScreen(u, text, &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// OperationAmount corrects the operation amount written by the organizer, react on writeOperationAmount chat state
+type OperationAmount struct {
+	cs  CollectionService
+	css ChatStateService
+	cfg *Config
+}
+
+// NewOperationAmount makes a bot for operation editing
+func NewOperationAmount(cs CollectionService, css ChatStateService, cfg *Config) *OperationAmount {
+	return &OperationAmount{
+		cs:  cs,
+		css: css,
+		cfg: cfg,
+	}
+}
+
+func (bot OperationAmount) HasReact(u *api.Update) bool {
+	return isPrivate(u) && hasMessage(u) && u.ChatState != nil && u.ChatState.Action == writeOperationAmount
+}
+
+func (bot *OperationAmount) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	data := u.ChatState.CallbackData
+	op, err := bot.cs.FindOperationById(ctx, data.OperationId.Hex())
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find operation, id:%s", data.OperationId.Hex())
+		return api.TelegramMessage{}, err
+	}
+
+	amount, err := api.ParseMoney(u.Message.Text, op.Amount.Currency)
+	if err != nil {
+		return api.TelegramMessage{
+			Chattable: []tgbotapi.Chattable{tgbotapi.NewMessage(getChatID(u), I18n(u.User, "msg_wrong_amount"))},
+			Send:      true,
+		}, nil
+	}
+	defer bot.css.CleanChatState(ctx, u.ChatState)
+
+	if _, err := bot.cs.EditOperation(ctx, u.User.ID, data.OperationId.Hex(), amount); err != nil {
+		log.Error().Err(err).Msgf("edit operation %v failed", data.OperationId.Hex())
+		return roomErrorMessage(u, err)
+	}
+	historyB := api.NewButton(chooseOperations, &api.CallbackData{RoomId: data.RoomId})
+	return api.TelegramMessage{
+		Redirect: &api.Update{Message: u.Message, User: u.User, Button: historyB},
+		Send:     true,
+	}, nil
+}
+
+// operationTitle is a short line of the history, e.g. "💵 12.10 500,00 ₽ Ivan"
+func operationTitle(user *api.User, op *api.Operation, reverted bool) string {
+	var mark string
+	switch {
+	case op.RevertOf != nil:
+		mark = "↩️"
+	case reverted:
+		mark = "🚫"
+	case op.Type == api.OperationContribution:
+		mark = "💵"
+	case op.Type == api.OperationRefund:
+		mark = "💸"
+	case op.Type == api.OperationExpense:
+		mark = "🛒"
+	default:
+		mark = "🎯"
+	}
+	text := mark + " " + formatDate(api.DefineLang(user), op.CreateAt.In(userLocation(user)), "") + " " + formatMoney(user, op.Amount)
+	if op.User != nil {
+		text += " " + op.User.DisplayName
+	}
+	return text
+}
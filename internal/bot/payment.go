@@ -0,0 +1,213 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"github.com/almaznur91/splitty/internal/api"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/skip2/go-qrcode"
+	"strings"
+)
+
+const qrSize = 512
+
+// PaymentDetailsSetting shows payment details of the user and asks to write one of them,
+// react on viewPaymentDetails and editPaymentField actions
+type PaymentDetailsSetting struct {
+	css ChatStateService
+	bs  ButtonService
+	cfg *Config
+}
+
+// NewPaymentDetailsSetting makes a bot for screen payment details
+func NewPaymentDetailsSetting(s ChatStateService, bs ButtonService, cfg *Config) *PaymentDetailsSetting {
+	return &PaymentDetailsSetting{
+		css: s,
+		bs:  bs,
+		cfg: cfg,
+	}
+}
+
+func (s PaymentDetailsSetting) HasReact(u *api.Update) bool {
+	return isPrivate(u) && u.Button != nil && (hasAction(u, viewPaymentDetails) || hasAction(u, editPaymentField))
+}
+
+func (s *PaymentDetailsSetting) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	if u.Button.Action == editPaymentField {
+		field := u.Button.CallbackData.ExternalData
+		cs := &api.ChatState{UserId: getChatID(u), Action: setPaymentField, CallbackData: &api.CallbackData{ExternalData: field}}
+		if err := s.css.Save(ctx, cs); err != nil {
+			log.Error().Err(err).Msg("create chat state failed")
+			return api.TelegramMessage{}, err
+		}
+		cb := api.NewButton(viewPaymentDetails, new(api.CallbackData))
+		if _, err := s.bs.SaveAll(ctx, cb); err != nil {
+			log.Error().Err(err).Msg("create btn failed")
+			return api.TelegramMessage{}, err
+		}
+		keyboard := [][]tgbotapi.InlineKeyboardButton{
+			{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_cancel"), cb.ID.Hex())},
+		}
+		return api.TelegramMessage{
+			Chattable: []tgbotapi.Chattable{createScreen(u, I18n(u.User, "scrn_write_payment_"+field), &keyboard)},
+			Send:      true,
+		}, nil
+	}
+	defer s.css.CleanChatState(ctx, u.ChatState)
+
+	details := u.User.PaymentDetails
+	if details == nil {
+		details = &api.PaymentDetails{}
+	}
+	buttons := make([]*api.Button, 0, len(api.PaymentFields)+1)
+	var keyboardButtons []tgbotapi.InlineKeyboardButton
+	for _, f := range api.PaymentFields {
+		b := api.NewButton(editPaymentField, &api.CallbackData{ExternalData: f})
+		buttons = append(buttons, b)
+		keyboardButtons = append(keyboardButtons, tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_payment_"+f), b.ID.Hex()))
+	}
+	backB := api.NewButton(viewUserSetting, new(api.CallbackData))
+	buttons = append(buttons, backB)
+	if _, err := s.bs.SaveAll(ctx, buttons...); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	keyboard := optimizeKeyboardButtons(keyboardButtons)
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_back"), backB.ID.Hex())})
+
+	text := I18n(u.User, "scrn_payment_details")
+	if details.IsEmpty() {
+		text += "\n\n" + I18n(u.User, "msg_no_payment_details")
+	} else {
+		text += "\n\n" + paymentDetailsText(u.User, details)
+	}
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createScreen(u, text, &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// PaymentFieldInput saves payment details written by the user, react on setPaymentField chat state
+type PaymentFieldInput struct {
+	css ChatStateService
+	us  UserService
+	cfg *Config
+}
+
+// NewPaymentFieldInput makes a bot for saving payment details
+func NewPaymentFieldInput(s ChatStateService, us UserService, cfg *Config) *PaymentFieldInput {
+	return &PaymentFieldInput{
+		css: s,
+		us:  us,
+		cfg: cfg,
+	}
+}
+
+func (s PaymentFieldInput) HasReact(u *api.Update) bool {
+	return isPrivate(u) && hasMessage(u) && u.ChatState != nil && u.ChatState.Action == setPaymentField
+}
+
+func (s *PaymentFieldInput) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	value := u.Message.Text
+	if strings.TrimSpace(value) == "-" {
+		value = ""
+	}
+
+	field := u.ChatState.CallbackData.ExternalData
+	if err := s.us.SetPaymentField(ctx, u.User.ID, field, value); errors.Is(err, api.ErrWrongPaymentField) {
+		return api.TelegramMessage{
+			Chattable: []tgbotapi.Chattable{tgbotapi.NewMessage(getChatID(u), I18n(u.User, "msg_wrong_payment_"+field))},
+			Send:      true,
+		}, nil
+	} else if err != nil {
+		log.Error().Err(err).Msgf("set payment field %v failed", field)
+		return api.TelegramMessage{}, err
+	}
+	defer s.css.CleanChatState(ctx, u.ChatState)
+
+	user, err := s.us.FindById(ctx, u.User.ID)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find user, id:%v", u.User.ID)
+		return api.TelegramMessage{}, err
+	}
+	return api.TelegramMessage{
+		Redirect: &api.Update{Message: u.Message, User: user, Button: api.NewButton(viewPaymentDetails, new(api.CallbackData))},
+		Send:     true,
+	}, nil
+}
+
+// PaymentQR sends a QR code paying the contribution to the organizer, react on payByQR action
+type PaymentQR struct {
+	cs  CollectionService
+	us  UserService
+	cfg *Config
+}
+
+// NewPaymentQR makes a bot for payment QR codes
+func NewPaymentQR(cs CollectionService, us UserService, cfg *Config) *PaymentQR {
+	return &PaymentQR{
+		cs:  cs,
+		us:  us,
+		cfg: cfg,
+	}
+}
+
+func (bot PaymentQR) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && hasAction(u, payByQR)
+}
+
+func (bot *PaymentQR) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	contributionId := u.Button.CallbackData.ExternalId
+	c, err := bot.cs.FindContributionById(ctx, contributionId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find contribution, id:%s", contributionId)
+		return api.TelegramMessage{}, err
+	}
+	if c.User.ID != u.User.ID {
+		return roomErrorMessage(u, api.ErrForbidden)
+	}
+	collection, err := bot.cs.FindCollectionById(ctx, c.CollectionId.Hex())
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find collection, id:%s", c.CollectionId.Hex())
+		return api.TelegramMessage{}, err
+	}
+	payee, err := bot.us.FindById(ctx, c.Payee(collection))
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find user, id:%v", c.Payee(collection))
+		return api.TelegramMessage{}, err
+	}
+
+	var payload string
+	if payee.PaymentDetails != nil {
+		payload = payee.PaymentDetails.QRPayload(c.Amount, contributionTitle(payee, c, collection))
+	}
+	if payload == "" {
+		return api.TelegramMessage{CallbackConfig: createCallback(u, I18n(u.User, "msg_no_payment_qr"), true), Send: true}, nil
+	}
+	png, err := qrcode.Encode(payload, qrcode.Medium, qrSize)
+	if err != nil {
+		log.Error().Err(err).Msgf("make payment qr of contribution %v failed", contributionId)
+		return api.TelegramMessage{}, err
+	}
+
+	caption := I18n(u.User, "msg_payment_qr", formatMoney(u.User, c.Amount), contributionTitle(u.User, c, collection))
+	return api.TelegramMessage{
+		Chattable:      []tgbotapi.Chattable{NewPhotoMessage(getChatID(u), caption, tgbotapi.FileBytes{Name: "qr.png", Bytes: png})},
+		CallbackConfig: createCallback(u, "", false),
+		Send:           true,
+	}, nil
+}
+
+// paymentDetailsText lists filled in payment details, values are monospaced to be copied by a tap
+func paymentDetailsText(user *api.User, details *api.PaymentDetails) string {
+	var lines []string
+	for _, f := range api.PaymentFields {
+		if v := details.Get(f); v != "" {
+			lines = append(lines, I18n(user, "msg_payment_"+f, v))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
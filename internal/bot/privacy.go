@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/almaznur91/splitty/internal/api"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	myData   string = "/mydata"
+	forgetMe string = "/forgetme"
+)
+
+// MyData sends everything stored about the user as a JSON document, react on /mydata command
+type MyData struct {
+	ps  PrivacyService
+	cfg *Config
+}
+
+// NewMyData makes a bot for /mydata command
+func NewMyData(ps PrivacyService, cfg *Config) *MyData {
+	return &MyData{
+		ps:  ps,
+		cfg: cfg,
+	}
+}
+
+func (bot MyData) HasReact(u *api.Update) bool {
+	return isPrivate(u) && hasMessage(u) && u.Message.Text == myData
+}
+
+func (bot *MyData) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	data, err := bot.ps.ExportUserData(ctx, u.User.ID)
+	if err != nil {
+		log.Error().Err(err).Msgf("export data of user %v failed", u.User.ID)
+		return api.TelegramMessage{}, err
+	}
+	body, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return api.TelegramMessage{}, err
+	}
+
+	file := tgbotapi.FileBytes{Name: "mydata.json", Bytes: body}
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{NewDocumentMessage(getChatID(u), I18n(u.User, "msg_my_data"), file)},
+		Send:      true,
+	}, nil
+}
+
+// ForgetMeConfirm asks to confirm deleting the data of the user, react on /forgetme command
+type ForgetMeConfirm struct {
+	bs  ButtonService
+	cfg *Config
+}
+
+// NewForgetMeConfirm makes a bot for /forgetme command
+func NewForgetMeConfirm(bs ButtonService, cfg *Config) *ForgetMeConfirm {
+	return &ForgetMeConfirm{
+		bs:  bs,
+		cfg: cfg,
+	}
+}
+
+func (bot ForgetMeConfirm) HasReact(u *api.Update) bool {
+	return isPrivate(u) && hasMessage(u) && u.Message.Text == forgetMe
+}
+
+func (bot *ForgetMeConfirm) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	yesB := api.NewButton(forgetUser, &api.CallbackData{UserId: int(u.User.ID)})
+	noB := api.NewButton(viewStart, new(api.CallbackData))
+	if _, err := bot.bs.SaveAll(ctx, yesB, noB); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	keyboard := [][]tgbotapi.InlineKeyboardButton{
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_forget_me"), yesB.ID.Hex())},
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_no"), noB.ID.Hex())},
+	}
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{NewMessage(getChatID(u), I18n(u.User, "scrn_confirm_forget_me"), keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// ForgetMe deletes the data of the user, react on forgetUser action
+type ForgetMe struct {
+	ps  PrivacyService
+	cfg *Config
+}
+
+// NewForgetMe makes a bot for deleting the data of the user
+func NewForgetMe(ps PrivacyService, cfg *Config) *ForgetMe {
+	return &ForgetMe{
+		ps:  ps,
+		cfg: cfg,
+	}
+}
+
+func (bot ForgetMe) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && hasAction(u, forgetUser) &&
+		int64(u.Button.CallbackData.UserId) == u.User.ID
+}
+
+func (bot *ForgetMe) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	if err := bot.ps.ForgetUser(ctx, u.User.ID); err != nil {
+		log.Error().Err(err).Msgf("forget user %v failed", u.User.ID)
+		return api.TelegramMessage{}, err
+	}
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createScreen(u, I18n(u.User, "msg_forgotten"), &[][]tgbotapi.InlineKeyboardButton{})},
+		Send:      true,
+	}, nil
+}
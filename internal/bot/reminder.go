@@ -0,0 +1,185 @@
+package bot
+
+import (
+	"context"
+	"github.com/almaznur91/splitty/internal/api"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+	"strconv"
+	"time"
+)
+
+// overdueEscalationDays is a count of overdue days after which reminders become strict
+const overdueEscalationDays = 3
+
+var deadlineVariants = []int{3, 7, 14, 30}
+
+var snoozeVariants = []int{1, 3}
+
+// CollectionDeadline asks the organizer when the collection should be paid, react on chooseDeadline and setDeadline actions
+type CollectionDeadline struct {
+	bs  ButtonService
+	cs  CollectionService
+	cfg *Config
+}
+
+// NewCollectionDeadline makes a bot for screen collection deadline
+func NewCollectionDeadline(bs ButtonService, cs CollectionService, cfg *Config) *CollectionDeadline {
+	return &CollectionDeadline{
+		bs:  bs,
+		cs:  cs,
+		cfg: cfg,
+	}
+}
+
+func (bot CollectionDeadline) HasReact(u *api.Update) bool {
+	return isPrivate(u) && u.Button != nil && (hasAction(u, chooseDeadline) || hasAction(u, setDeadline))
+}
+
+func (bot *CollectionDeadline) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	data := u.Button.CallbackData
+	debtsB := api.NewButton(chooseDebts, &api.CallbackData{RoomId: data.RoomId})
+
+	if u.Button.Action == setDeadline {
+		var deadline *time.Time
+		if days, _ := strconv.Atoi(data.ExternalData); days > 0 {
+			t := endOfDay(time.Now().In(userLocation(u.User))).AddDate(0, 0, days)
+			deadline = &t
+		}
+		if err := bot.cs.SetDeadline(ctx, u.User.ID, data.ExternalId, deadline); err != nil {
+			log.Error().Err(err).Msgf("set deadline of collection %v failed", data.ExternalId)
+			return roomErrorMessage(u, err)
+		}
+		return api.TelegramMessage{
+			Redirect: &api.Update{CallbackQuery: u.CallbackQuery, Message: u.Message, User: u.User, Button: debtsB},
+			Send:     true,
+		}, nil
+	}
+
+	buttons := make([]*api.Button, 0, len(deadlineVariants)+2)
+	var keyboardButtons []tgbotapi.InlineKeyboardButton
+	for _, days := range deadlineVariants {
+		b := api.NewButton(setDeadline, &api.CallbackData{RoomId: data.RoomId, ExternalId: data.ExternalId, ExternalData: strconv.Itoa(days)})
+		buttons = append(buttons, b)
+		keyboardButtons = append(keyboardButtons, tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_deadline_days", days), b.ID.Hex()))
+	}
+	noB := api.NewButton(setDeadline, &api.CallbackData{RoomId: data.RoomId, ExternalId: data.ExternalId})
+	buttons = append(buttons, noB, debtsB)
+	if _, err := bot.bs.SaveAll(ctx, buttons...); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	keyboard := optimizeKeyboardButtons(keyboardButtons)
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_no_deadline"), noB.ID.Hex())})
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createScreen(u, I18n(u.User, "scrn_choose_deadline"), &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// SnoozeReminder postpones reminders about the debt, react on snoozeReminder action
+type SnoozeReminder struct {
+	cs  CollectionService
+	cfg *Config
+}
+
+// NewSnoozeReminder makes a bot for snoozing debt reminders
+func NewSnoozeReminder(cs CollectionService, cfg *Config) *SnoozeReminder {
+	return &SnoozeReminder{
+		cs:  cs,
+		cfg: cfg,
+	}
+}
+
+func (bot SnoozeReminder) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && hasAction(u, snoozeReminder)
+}
+
+func (bot *SnoozeReminder) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	data := u.Button.CallbackData
+	days, err := strconv.Atoi(data.ExternalData)
+	if err != nil {
+		return api.TelegramMessage{}, err
+	}
+	if err := bot.cs.SnoozeReminders(ctx, u.User.ID, data.ExternalId, time.Now().AddDate(0, 0, days)); err != nil {
+		log.Error().Err(err).Msgf("snooze reminders of contribution %v failed", data.ExternalId)
+		return roomErrorMessage(u, err)
+	}
+	return api.TelegramMessage{CallbackConfig: createCallback(u, I18n(u.User, "msg_reminder_snoozed", days), true), Send: true}, nil
+}
+
+// DebtReminder makes a reminder about the debt: gentle before the deadline and escalating after it.
+// Returned buttons must be saved before the message is sent
+func DebtReminder(user *api.User, collection *api.Collection, c *api.Contribution, now time.Time) (tgbotapi.Chattable, []*api.Button) {
+	title := collectionTitle(user, collection)
+	amount := formatMoney(user, c.Amount)
+
+	var text string
+	switch overdue := int(now.Sub(*collection.Deadline).Hours() / 24); {
+	case now.Before(*collection.Deadline):
+		text = I18n(user, "msg_debt_reminder", title, amount, collection.Deadline.In(userLocation(user)))
+	case overdue < overdueEscalationDays:
+		text = I18n(user, "msg_debt_overdue", title, amount)
+	default:
+		text = I18n(user, "msg_debt_overdue_strong", title, amount, overdue)
+	}
+
+	data := &api.CallbackData{RoomId: c.RoomId, ExternalId: c.ID.Hex()}
+	payB := api.NewButton(viewContribution, data)
+	buttons := []*api.Button{payB}
+	var snoozeButtons []tgbotapi.InlineKeyboardButton
+	for _, days := range snoozeVariants {
+		b := api.NewButton(snoozeReminder, &api.CallbackData{RoomId: c.RoomId, ExternalId: c.ID.Hex(), ExternalData: strconv.Itoa(days)})
+		buttons = append(buttons, b)
+		snoozeButtons = append(snoozeButtons, tgbotapi.NewInlineKeyboardButtonData(I18n(user, "btn_snooze_days", days), b.ID.Hex()))
+	}
+
+	keyboard := [][]tgbotapi.InlineKeyboardButton{
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(user, "btn_pay"), payB.ID.Hex())},
+		snoozeButtons,
+	}
+	return NewMessage(user.ID, text, keyboard), buttons
+}
+
+// OutstandingDigest makes a daily digest for the organizer with members who haven't paid yet
+func OutstandingDigest(organizer *api.User, collection *api.Collection, debts []api.Contribution) tgbotapi.Chattable {
+	text := I18n(organizer, "msg_outstanding_digest", collectionTitle(organizer, collection), len(debts))
+	amounts := make([]api.Money, 0, len(debts))
+	for _, c := range debts {
+		text += "\n- " + string(Mention(&c.User)) + " — " + formatMoney(organizer, c.Amount)
+		amounts = append(amounts, c.Amount)
+	}
+	text += "\n\n" + I18n(organizer, "msg_outstanding_total", formatTotals(organizer, amounts))
+
+	msg := tgbotapi.NewMessage(organizer.ID, text)
+	msg.ParseMode = parseMode
+	return msg
+}
+
+// CollectionOpened tells the member about the collection opened by the room rule, the organizer is asked to buy the gift.
+// Returned buttons must be saved before the message is sent
+func CollectionOpened(user *api.User, collection *api.Collection) (tgbotapi.Chattable, []*api.Button) {
+	title := collectionTitle(user, collection)
+	text := I18n(user, "msg_collection_opened", title, formatMoney(user, collection.Amount), collection.Birthday)
+	if user.ID == collection.Organizer {
+		text = I18n(user, "msg_collection_opened_organizer", title, collection.Birthday)
+	}
+
+	b := api.NewButton(chooseDebts, &api.CallbackData{RoomId: collection.RoomId})
+	return NewMessage(user.ID, text, [][]tgbotapi.InlineKeyboardButton{
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(user, "btn_debts"), b.ID.Hex())},
+	}), []*api.Button{b}
+}
+
+func userLocation(u *api.User) *time.Location {
+	if loc, err := time.LoadLocation(timeZoneName(u)); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+func endOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 23, 59, 59, 0, t.Location())
+}
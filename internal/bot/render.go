@@ -0,0 +1,66 @@
+package bot
+
+import (
+	"fmt"
+	"github.com/almaznur91/splitty/internal/api"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"strings"
+)
+
+// parseMode of every message sent by the bot, texts in lang files are marked up with <b>, <i>, <code> and <a> tags
+const parseMode = tgbotapi.ModeHTML
+
+var htmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// Text is a fragment of the message which is already marked up and escaped, it is sent as is.
+// Plain strings passed to I18n are escaped, so names of rooms and users can't break the message
+type Text string
+
+// Escape makes the plain string safe to put into the message
+func Escape(s string) Text {
+	return Text(htmlEscaper.Replace(s))
+}
+
+func Bold(s string) Text {
+	return "<b>" + Escape(s) + "</b>"
+}
+
+func Italic(s string) Text {
+	return "<i>" + Escape(s) + "</i>"
+}
+
+// Code shows the string in monospace, Telegram lets users copy it by a tap
+func Code(s string) Text {
+	return "<code>" + Escape(s) + "</code>"
+}
+
+func Link(text string, url string) Text {
+	return Text(fmt.Sprintf(`<a href="%s">%s</a>`, Escape(url), Escape(text)))
+}
+
+// Mention links the user name to the profile, it works even without a username
+func Mention(user *api.User) Text {
+	return Link(user.DisplayName, fmt.Sprintf("tg://user?id=%d", user.ID))
+}
+
+// escapeArgs escapes plain strings of I18n args and Args, fragments and other values are left as is
+func escapeArgs(args []interface{}) []interface{} {
+	escaped := make([]interface{}, len(args))
+	for i, a := range args {
+		switch v := a.(type) {
+		case string:
+			escaped[i] = string(Escape(v))
+		case Text:
+			escaped[i] = string(v)
+		case Args:
+			named := make(Args, len(v))
+			for k, a := range v {
+				named[k] = escapeArgs([]interface{}{a})[0]
+			}
+			escaped[i] = named
+		default:
+			escaped[i] = a
+		}
+	}
+	return escaped
+}
@@ -0,0 +1,84 @@
+package bot
+
+import (
+	"github.com/almaznur91/splitty/internal/api"
+	"github.com/gookit/i18n"
+	"testing"
+)
+
+// useMessages replaces translations with messages of the ini data by languages until the test ends
+func useMessages(t *testing.T, langs map[string]string) {
+	t.Helper()
+	l := newMessages(t, langs)
+	prev := tr()
+	translations.Store(l)
+	t.Cleanup(func() { translations.Store(prev) })
+}
+
+func newMessages(t *testing.T, langs map[string]string) *i18n.I18n {
+	t.Helper()
+	l := i18n.NewEmpty()
+	l.DefaultLang = "en"
+	for lang, data := range langs {
+		l.NewLang(lang, lang)
+		if err := l.LoadString(lang, data); err != nil {
+			t.Fatalf("load %s messages: %v", lang, err)
+		}
+	}
+	return l
+}
+
+func TestI18nEscapesHostileNames(t *testing.T) {
+	useMessages(t, map[string]string{"en": `
+msg_joined = {0} joined <b>{room}</b>
+msg_debts = {count, plural, one {{name} owes # share} other {{name} owes # shares}}
+msg_role = {role, select, admin {<i>{name}</i> is an admin} other {{name} is a member}}
+`})
+	u := &api.User{UserLang: "en"}
+
+	tests := []struct {
+		name string
+		key  string
+		args []interface{}
+		want string
+	}{
+		{"tag", "msg_joined", []interface{}{"<b>Bob</b>", Args{"room": "Work"}},
+			"&lt;b&gt;Bob&lt;/b&gt; joined <b>Work</b>"},
+		{"ampersand", "msg_joined", []interface{}{"Tom & Jerry", Args{"room": "R&D"}},
+			"Tom &amp; Jerry joined <b>R&amp;D</b>"},
+		{"quote", "msg_joined", []interface{}{`"Ann"`, Args{"room": `"Home"`}},
+			"&quot;Ann&quot; joined <b>&quot;Home&quot;</b>"},
+		{"markdown is plain text", "msg_joined", []interface{}{"*bold*", Args{"room": "_room_"}},
+			"*bold* joined <b>_room_</b>"},
+		{"mention", "msg_joined", []interface{}{Mention(&api.User{ID: 7, DisplayName: `<a href="x">&_*`}), Args{"room": "R"}},
+			`<a href="tg://user?id=7">&lt;a href=&quot;x&quot;&gt;&amp;_*</a> joined <b>R</b>`},
+		{"link", "msg_joined", []interface{}{Link("A & <B>", `https://t.me/x?a="1"&b=2`), Args{"room": "R"}},
+			`<a href="https://t.me/x?a=&quot;1&quot;&amp;b=2">A &amp; &lt;B&gt;</a> joined <b>R</b>`},
+		{"plural one", "msg_debts", []interface{}{Args{"count": 1, "name": "<Bob & *Co*>"}},
+			"&lt;Bob &amp; *Co*&gt; owes 1 share"},
+		{"plural other", "msg_debts", []interface{}{Args{"count": 3, "name": `"_Ann_"`}},
+			"&quot;_Ann_&quot; owes 3 shares"},
+		{"select admin", "msg_role", []interface{}{Args{"role": "admin", "name": "</i><b>x"}},
+			"<i>&lt;/i&gt;&lt;b&gt;x</i> is an admin"},
+		{"select other", "msg_role", []interface{}{Args{"role": "<admin>", "name": "A&B"}},
+			"A&amp;B is a member"},
+		{"mention in plural", "msg_debts", []interface{}{Args{"count": 2, "name": Mention(&api.User{ID: 1, DisplayName: "<x>"})}},
+			`<a href="tg://user?id=1">&lt;x&gt;</a> owes 2 shares`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := I18n(u, tt.key, tt.args...); got != tt.want {
+				t.Errorf("I18n() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestI18nPlainKeepsNames(t *testing.T) {
+	useMessages(t, map[string]string{"en": "msg_joined = {0} joined {room}\n"})
+	u := &api.User{UserLang: "en"}
+	want := `<b>Bob</b> & "Ann" joined *_room_*`
+	if got := I18nPlain(u, "msg_joined", `<b>Bob</b> & "Ann"`, Args{"room": "*_room_*"}); got != want {
+		t.Errorf("I18nPlain() = %q, want %q", got, want)
+	}
+}
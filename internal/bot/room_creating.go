@@ -79,7 +79,7 @@ func NewRoomSetName(s ChatStateService, bs ButtonService, rs RoomService, cfg *C
 
 // ReactOn keys
 func (rs RoomSetName) HasReact(u *api.Update) bool {
-	if u.ChatState == nil || u.Message == nil {
+	if u.ChatState == nil || u.Message == nil || !isPrivate(u) {
 		return false
 	}
 	return u.ChatState.Action == createRoom
@@ -92,6 +92,7 @@ func (rs RoomSetName) OnMessage(ctx context.Context, u *api.Update) (api.Telegra
 	r := &api.Room{
 		Members:  &[]api.User{u.Message.From},
 		Name:     u.Message.Text,
+		Currency: defaultCurrency(&u.Message.From),
 		CreateAt: time.Now(),
 	}
 
@@ -102,7 +103,7 @@ func (rs RoomSetName) OnMessage(ctx context.Context, u *api.Update) (api.Telegra
 	}
 
 	tbMsg := tgbotapi.NewMessage(getChatID(u), I18n(u.User, "scrn_room_created", room.Name))
-	tbMsg.ParseMode = tgbotapi.ModeMarkdown
+	tbMsg.ParseMode = parseMode
 
 	shareRoomBtn := []tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardButtonSwitch(I18n(u.User, "btn_share_room"), room.Name)}
 
@@ -0,0 +1,267 @@
+package bot
+
+import (
+	"context"
+	"github.com/almaznur91/splitty/internal/api"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// AllRooms shows the user rooms page by page, react on viewAllRooms, viewArchivedRooms and search actions
+type AllRooms struct {
+	css ChatStateService
+	bs  ButtonService
+	rs  RoomService
+	cfg *Config
+}
+
+// NewAllRooms makes a bot for screen with list of rooms
+func NewAllRooms(s ChatStateService, bs ButtonService, rs RoomService, cfg *Config) *AllRooms {
+	return &AllRooms{
+		css: s,
+		bs:  bs,
+		rs:  rs,
+		cfg: cfg,
+	}
+}
+
+func (s AllRooms) HasReact(u *api.Update) bool {
+	if !isPrivate(u) {
+		return false
+	}
+	if isButton(u) {
+		return hasAction(u, viewAllRooms) || hasAction(u, viewArchivedRooms) || hasAction(u, viewFoundRooms) ||
+			hasAction(u, archiveRoom) || hasAction(u, unArchiveRoom)
+	}
+	return hasMessage(u) && u.ChatState != nil && u.ChatState.Action == viewFoundRooms
+}
+
+func (s *AllRooms) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	defer s.css.CleanChatState(ctx, u.ChatState)
+
+	action, data := viewFoundRooms, &api.CallbackData{}
+	if u.Button != nil {
+		action = u.Button.Action
+		if u.Button.CallbackData != nil {
+			data = u.Button.CallbackData
+		}
+	} else {
+		data.ExternalData = u.Message.Text
+	}
+
+	var err error
+	switch action {
+	case archiveRoom:
+		err = s.rs.ArchiveRoom(ctx, u.User.ID, data.RoomId)
+		action = viewAllRooms
+	case unArchiveRoom:
+		err = s.rs.UnArchiveRoom(ctx, u.User.ID, data.RoomId)
+		action = viewArchivedRooms
+	}
+	if err != nil {
+		log.Error().Err(err).Msgf("change room archive state failed %v", data.RoomId)
+		return api.TelegramMessage{}, err
+	}
+
+	var rooms *[]api.Room
+	var title string
+	switch action {
+	case viewArchivedRooms:
+		rooms, err = s.rs.FindArchivedRoomsByUserId(ctx, u.User.ID)
+		title = I18n(u.User, "scrn_archived_rooms")
+	case viewFoundRooms:
+		rooms, err = s.rs.FindRoomsByLikeName(ctx, u.User.ID, data.ExternalData)
+		title = I18n(u.User, "scrn_found_rooms", data.ExternalData)
+	default:
+		rooms, err = s.rs.FindRoomsByUserId(ctx, u.User.ID)
+		title = I18n(u.User, "scrn_all_rooms")
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("find rooms failed")
+		return api.TelegramMessage{}, err
+	}
+
+	page, pages, pageRooms := paginateRooms(*rooms, data.Page, u.User.CountInPage)
+
+	var buttons []*api.Button
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	for _, r := range pageRooms {
+		viewB := api.NewButton(viewRoom, &api.CallbackData{RoomId: r.ID.Hex()})
+		line := []tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardButtonData(r.Name, viewB.ID.Hex())}
+		buttons = append(buttons, viewB)
+
+		switch action {
+		case viewAllRooms:
+			archB := api.NewButton(archiveRoom, &api.CallbackData{RoomId: r.ID.Hex(), Page: page})
+			line = append(line, tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_archive"), archB.ID.Hex()))
+			buttons = append(buttons, archB)
+		case viewArchivedRooms:
+			unArchB := api.NewButton(unArchiveRoom, &api.CallbackData{RoomId: r.ID.Hex(), Page: page})
+			line = append(line, tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_unarchive"), unArchB.ID.Hex()))
+			buttons = append(buttons, unArchB)
+		}
+		keyboard = append(keyboard, line)
+	}
+
+	var pagination []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		prevB := api.NewButton(action, &api.CallbackData{Page: page - 1, ExternalData: data.ExternalData})
+		pagination = append(pagination, tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_prev_page"), prevB.ID.Hex()))
+		buttons = append(buttons, prevB)
+	}
+	if page < pages-1 {
+		nextB := api.NewButton(action, &api.CallbackData{Page: page + 1, ExternalData: data.ExternalData})
+		pagination = append(pagination, tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_next_page"), nextB.ID.Hex()))
+		buttons = append(buttons, nextB)
+	}
+	if len(pagination) > 0 {
+		keyboard = append(keyboard, pagination)
+	}
+
+	tabB := api.NewButton(viewArchivedRooms, new(api.CallbackData))
+	tabText := I18n(u.User, "btn_archived_rooms")
+	if action != viewAllRooms {
+		tabB = api.NewButton(viewAllRooms, new(api.CallbackData))
+		tabText = I18n(u.User, "btn_all_rooms")
+	}
+	searchB := api.NewButton(searchRoom, new(api.CallbackData))
+	backB := api.NewButton(viewStart, new(api.CallbackData))
+	buttons = append(buttons, tabB, searchB, backB)
+	keyboard = append(keyboard,
+		[]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardButtonData(tabText, tabB.ID.Hex()),
+			tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_search"), searchB.ID.Hex())},
+		[]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_back"), backB.ID.Hex())})
+
+	if _, err := s.bs.SaveAll(ctx, buttons...); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	text := title
+	if len(pageRooms) == 0 {
+		text += "\n\n" + I18n(u.User, "msg_no_rooms")
+	} else if pages > 1 {
+		text += "\n\n" + I18n(u.User, "msg_page", page+1, pages)
+	}
+
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createScreen(u, text, &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// SearchRoom asks user to write a part of room name, react on searchRoom action
+type SearchRoom struct {
+	css ChatStateService
+	bs  ButtonService
+	cfg *Config
+}
+
+// NewSearchRoom makes a bot for screen room search
+func NewSearchRoom(s ChatStateService, bs ButtonService, cfg *Config) *SearchRoom {
+	return &SearchRoom{
+		css: s,
+		bs:  bs,
+		cfg: cfg,
+	}
+}
+
+func (s SearchRoom) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && hasAction(u, searchRoom)
+}
+
+func (s *SearchRoom) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	cs := &api.ChatState{UserId: getChatID(u), Action: viewFoundRooms}
+	if err := s.css.Save(ctx, cs); err != nil {
+		log.Error().Err(err).Msg("create chat state failed")
+		return api.TelegramMessage{}, err
+	}
+
+	cb := api.NewButton(viewAllRooms, new(api.CallbackData))
+	if _, err := s.bs.SaveAll(ctx, cb); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+	screen := createScreen(u, I18n(u.User, "scrn_write_search"),
+		&[][]tgbotapi.InlineKeyboardButton{
+			{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_cancel"), cb.ID.Hex())},
+		})
+
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{screen},
+		Send:      true,
+	}, nil
+}
+
+// inlineResultsLimit is the most results Telegram accepts in the answer to an inline query
+const inlineResultsLimit = 50
+
+// InlineRoomSearch answers inline queries with rooms of the user found by the query, react on any inline query
+type InlineRoomSearch struct {
+	rs  RoomService
+	cfg *Config
+}
+
+// NewInlineRoomSearch makes a bot for inline room search
+func NewInlineRoomSearch(rs RoomService, cfg *Config) *InlineRoomSearch {
+	return &InlineRoomSearch{
+		rs:  rs,
+		cfg: cfg,
+	}
+}
+
+func (s InlineRoomSearch) HasReact(u *api.Update) bool {
+	return u.InlineQuery != nil
+}
+
+func (s *InlineRoomSearch) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	rooms, err := s.rs.FindRoomsByLikeName(ctx, u.InlineQuery.From.ID, u.InlineQuery.Query)
+	if err != nil {
+		log.Error().Err(err).Msg("find rooms failed")
+		return api.TelegramMessage{}, err
+	}
+
+	results := make([]interface{}, 0, inlineResultsLimit)
+	for i := range *rooms {
+		if len(results) == inlineResultsLimit {
+			break
+		}
+		r := &(*rooms)[i]
+		link := "http://t.me/" + s.cfg.BotName + "?start=" + string(viewRoom) + r.ID.Hex()
+		keyboard := [][]tgbotapi.InlineKeyboardButton{
+			{tgbotapi.NewInlineKeyboardButtonURL(I18n(u.User, "btn_start"), link)},
+		}
+		results = append(results, NewInlineResultArticle(r.Name, I18nPlain(u.User, "msg_room_members_count", len(*r.Members)),
+			createRoomInfoText(r, u), keyboard))
+	}
+	return api.TelegramMessage{
+		InlineConfig: NewInlineConfig(u.InlineQuery.ID, results),
+		Send:         true,
+	}, nil
+}
+
+// paginateRooms returns the current page number corrected to the range of pages, count of pages and rooms on the page
+func paginateRooms(rooms []api.Room, page, countInPage int) (int, int, []api.Room) {
+	page, pages, from, to := pageBounds(len(rooms), page, countInPage)
+	return page, pages, rooms[from:to]
+}
+
+// pageBounds corrects the page number to the range of pages, returns count of pages and bounds of the page items
+func pageBounds(count, page, countInPage int) (int, int, int, int) {
+	if countInPage <= 0 {
+		countInPage = 5
+	}
+	pages := (count + countInPage - 1) / countInPage
+	if page >= pages {
+		page = pages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+	from := page * countInPage
+	to := from + countInPage
+	if to > count {
+		to = count
+	}
+	return page, pages, from, to
+}
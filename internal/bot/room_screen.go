@@ -8,7 +8,8 @@ import (
 	"strings"
 )
 
-// send /room, after click on the button 'Присоединиться'
+// JoinRoom adds the member of the group chat to the bound room, after click on the button 'Присоединиться'.
+// Users outside of the group join by invites
 type JoinRoom struct {
 	css ChatStateService
 	bs  ButtonService
@@ -31,23 +32,22 @@ func (bot JoinRoom) HasReact(u *api.Update) bool {
 	if u.Button == nil {
 		return false
 	}
-	return u.Button.Action == joinRoom
+	return isGroup(u) && u.Button.Action == joinRoom
 }
 
 // OnMessage returns one entry
-func (bot JoinRoom) OnMessage(ctx context.Context, u *api.Update) (response api.TelegramMessage) {
+func (bot JoinRoom) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
 	roomId := u.Button.CallbackData.RoomId
 
-	err := bot.rs.JoinToRoom(ctx, u.CallbackQuery.From, roomId)
-	if err != nil {
+	if err := bot.rs.SyncChatMember(ctx, getChatID(u), u.CallbackQuery.From); err != nil {
 		log.Error().Err(err).Msgf("join room failed %v", roomId)
-		return
+		return api.TelegramMessage{}, err
 	}
 
 	room, err := bot.rs.FindById(ctx, roomId)
 	if err != nil {
 		log.Error().Err(err).Msgf("get room failed %v", roomId)
-		return
+		return api.TelegramMessage{}, err
 	}
 
 	data := &api.CallbackData{RoomId: room.ID.Hex()}
@@ -56,19 +56,22 @@ func (bot JoinRoom) OnMessage(ctx context.Context, u *api.Update) (response api.
 
 	if _, err := bot.bs.SaveAll(ctx, joinB); err != nil {
 		log.Error().Err(err).Msg("create btn failed")
-		return
+		return api.TelegramMessage{}, err
 	}
 
-	text := createRoomInfoText(room, u)
+	// the message is shared by the group chat, so it is in the room language if the room has one
+	ru := *u
+	ru.User = roomReader(room, u.User)
+	text := createRoomInfoText(room, &ru)
 	link := "http://t.me/" + bot.cfg.BotName + "?start=" + string(viewRoom) + room.ID.Hex()
 	keyboard := [][]tgbotapi.InlineKeyboardButton{
-		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_join"), joinB.ID.Hex())},
-		{tgbotapi.NewInlineKeyboardButtonURL(I18n(u.User, "btn_start"), link)},
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(ru.User, "btn_join"), joinB.ID.Hex())},
+		{tgbotapi.NewInlineKeyboardButtonURL(I18n(ru.User, "btn_start"), link)},
 	}
 	return api.TelegramMessage{
 		Chattable: []tgbotapi.Chattable{createScreen(u, text, &keyboard)},
 		Send:      true,
-	}
+	}, nil
 }
 
 // ViewRoom send /room, after click on the button 'Присоединиться'
@@ -96,7 +99,7 @@ func (bot ViewRoom) HasReact(u *api.Update) bool {
 }
 
 // OnMessage returns one entry
-func (bot *ViewRoom) OnMessage(ctx context.Context, u *api.Update) (response api.TelegramMessage) {
+func (bot *ViewRoom) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
 	defer bot.css.CleanChatState(ctx, u.ChatState)
 
 	var roomId string
@@ -109,14 +112,14 @@ func (bot *ViewRoom) OnMessage(ctx context.Context, u *api.Update) (response api
 	room, err := bot.rs.FindById(ctx, roomId)
 	if err != nil {
 		log.Error().Err(err).Stack().Msgf("cannot find room, id:%s", roomId)
-		return
+		return api.TelegramMessage{}, err
 	}
 
 	if !containsUserId(room.Members, getFrom(u).ID) {
 		return api.TelegramMessage{
 			Chattable: []tgbotapi.Chattable{tgbotapi.NewMessage(getChatID(u), I18n(u.User, "msg_not_be_in_rooms"))},
 			Send:      true,
-		}
+		}, nil
 	}
 
 	data := &api.CallbackData{RoomId: roomId}
@@ -140,19 +143,19 @@ func (bot *ViewRoom) OnMessage(ctx context.Context, u *api.Update) (response api
 
 	if _, err = bot.bs.SaveAll(ctx, viewOpsB, viewDbtB, viewRoomsB, startOpB, staticsB, settB); err != nil {
 		log.Error().Err(err).Msg("create btn failed")
-		return
+		return api.TelegramMessage{}, err
 	}
 	return api.TelegramMessage{
 		Chattable: []tgbotapi.Chattable{createScreen(u, text, &keyboard)},
 		Send:      true,
-	}
+	}, nil
 }
 
 func createRoomInfoText(r *api.Room, u *api.Update) string {
 	text := I18n(u.User, "scrn_room", r.Name, "")
 
 	for _, v := range *r.Members {
-		text += "- " + userLink(&v)
+		text += "- " + string(Mention(&v))
 		text += "\n"
 	}
 	return text
@@ -0,0 +1,658 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"github.com/almaznur91/splitty/internal/api"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+	"sort"
+)
+
+// RoomSetting shows room settings, react on roomSetting action
+type RoomSetting struct {
+	bs  ButtonService
+	rs  RoomService
+	css ChatStateService
+	cfg *Config
+}
+
+// NewRoomSetting makes a bot for screen room settings
+func NewRoomSetting(bs ButtonService, rs RoomService, css ChatStateService, cfg *Config) *RoomSetting {
+	return &RoomSetting{
+		bs:  bs,
+		rs:  rs,
+		css: css,
+		cfg: cfg,
+	}
+}
+
+func (bot RoomSetting) HasReact(u *api.Update) bool {
+	return isPrivate(u) && hasAction(u, roomSetting)
+}
+
+func (bot *RoomSetting) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	defer bot.css.CleanChatState(ctx, u.ChatState)
+
+	roomId := u.Button.CallbackData.RoomId
+	room, err := bot.rs.FindById(ctx, roomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", roomId)
+		return api.TelegramMessage{}, err
+	}
+	if !room.IsMember(u.User.ID) {
+		return roomErrorMessage(u, api.ErrNotMember)
+	}
+
+	data := &api.CallbackData{RoomId: roomId}
+	membersB := api.NewButton(viewRoomMembers, data)
+	leaveB := api.NewButton(confirmLeaveRoom, data)
+	optOutB := api.NewButton(switchOrganizerOptOut, data)
+	backB := api.NewButton(viewRoom, data)
+	buttons := []*api.Button{membersB, leaveB, optOutB, backB}
+
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	if room.IsAdmin(u.User.ID) {
+		renameB := api.NewButton(renameRoom, data)
+		greetingB := api.NewButton(viewGreeting, data)
+		currencyB := api.NewButton(chooseCurrency, data)
+		ruleB := api.NewButton(viewCollectionRule, data)
+		invitesB := api.NewButton(viewInvites, data)
+		langB := api.NewButton(chooseRoomLang, data)
+		buttons = append(buttons, renameB, greetingB, currencyB, ruleB, invitesB, langB)
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_rename_room"), renameB.ID.Hex()),
+			tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_greeting"), greetingB.ID.Hex())})
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_currency", room.CurrencyCode()), currencyB.ID.Hex()),
+			tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_collection_rule"), ruleB.ID.Hex())})
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_invites", len(room.JoinRequests)), invitesB.ID.Hex()),
+			tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_room_language", roomLangName(u.User, room)), langB.ID.Hex())})
+	}
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_room_members"), membersB.ID.Hex())})
+	optOutKey := "btn_organizing_on"
+	if room.IsOptedOut(u.User.ID) {
+		optOutKey = "btn_organizing_off"
+	}
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, optOutKey), optOutB.ID.Hex())})
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_leave_room"), leaveB.ID.Hex())})
+	if room.IsOwner(u.User.ID) {
+		deleteB := api.NewButton(confirmDeleteRoom, data)
+		buttons = append(buttons, deleteB)
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_delete_room"), deleteB.ID.Hex())})
+	}
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_back"), backB.ID.Hex())})
+
+	if _, err := bot.bs.SaveAll(ctx, buttons...); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createScreen(u, I18n(u.User, "scrn_room_setting", room.Name), &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// RoomRenaming asks admin to write a new room name, react on renameRoom action
+type RoomRenaming struct {
+	css ChatStateService
+	bs  ButtonService
+	cfg *Config
+}
+
+// NewRoomRenaming makes a bot for screen room renaming
+func NewRoomRenaming(s ChatStateService, bs ButtonService, cfg *Config) *RoomRenaming {
+	return &RoomRenaming{
+		css: s,
+		bs:  bs,
+		cfg: cfg,
+	}
+}
+
+func (bot RoomRenaming) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && hasAction(u, renameRoom)
+}
+
+func (bot *RoomRenaming) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	data := &api.CallbackData{RoomId: u.Button.CallbackData.RoomId}
+	cs := &api.ChatState{UserId: getChatID(u), Action: renameRoom, CallbackData: data}
+	if err := bot.css.Save(ctx, cs); err != nil {
+		log.Error().Err(err).Msg("create chat state failed")
+		return api.TelegramMessage{}, err
+	}
+
+	cb := api.NewButton(roomSetting, data)
+	if _, err := bot.bs.SaveAll(ctx, cb); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+	screen := createScreen(u, I18n(u.User, "scrn_write_room_name"),
+		&[][]tgbotapi.InlineKeyboardButton{
+			{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_cancel"), cb.ID.Hex())},
+		})
+
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{screen},
+		Send:      true,
+	}, nil
+}
+
+// RoomRename saves the room name written by admin, react on renameRoom chat state
+type RoomRename struct {
+	css ChatStateService
+	rs  RoomService
+	cfg *Config
+}
+
+// NewRoomRename makes a bot for saving room name
+func NewRoomRename(s ChatStateService, rs RoomService, cfg *Config) *RoomRename {
+	return &RoomRename{
+		css: s,
+		rs:  rs,
+		cfg: cfg,
+	}
+}
+
+func (bot RoomRename) HasReact(u *api.Update) bool {
+	return isPrivate(u) && hasMessage(u) && u.ChatState != nil && u.ChatState.Action == renameRoom
+}
+
+func (bot *RoomRename) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	defer bot.css.CleanChatState(ctx, u.ChatState)
+
+	roomId := u.ChatState.CallbackData.RoomId
+	if err := bot.rs.RenameRoom(ctx, u.User.ID, roomId, u.Message.Text); err != nil {
+		log.Error().Err(err).Msgf("rename room failed %v", roomId)
+		return roomErrorMessage(u, err)
+	}
+
+	return api.TelegramMessage{
+		Redirect: &api.Update{Message: u.Message, User: u.User, Button: api.NewButton(roomSetting, &api.CallbackData{RoomId: roomId})},
+		Send:     true,
+	}, nil
+}
+
+// RoomCurrency shows currencies and sets the room currency, react on chooseCurrency and setCurrency actions
+type RoomCurrency struct {
+	bs  ButtonService
+	rs  RoomService
+	cfg *Config
+}
+
+// NewRoomCurrency makes a bot for screen room currency
+func NewRoomCurrency(bs ButtonService, rs RoomService, cfg *Config) *RoomCurrency {
+	return &RoomCurrency{
+		bs:  bs,
+		rs:  rs,
+		cfg: cfg,
+	}
+}
+
+func (bot RoomCurrency) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && (hasAction(u, chooseCurrency) || hasAction(u, setCurrency))
+}
+
+func (bot *RoomCurrency) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	roomId := u.Button.CallbackData.RoomId
+
+	if u.Button.Action == setCurrency {
+		if err := bot.rs.SetCurrency(ctx, u.User.ID, roomId, u.Button.CallbackData.ExternalData); err != nil {
+			log.Error().Err(err).Msgf("set currency of room %v failed", roomId)
+			return roomErrorMessage(u, err)
+		}
+		return api.TelegramMessage{
+			Redirect: &api.Update{CallbackQuery: u.CallbackQuery, User: u.User, Button: api.NewButton(roomSetting, &api.CallbackData{RoomId: roomId})},
+			Send:     true,
+		}, nil
+	}
+
+	room, err := bot.rs.FindById(ctx, roomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", roomId)
+		return api.TelegramMessage{}, err
+	}
+	if !room.IsAdmin(u.User.ID) {
+		return roomErrorMessage(u, api.ErrForbidden)
+	}
+
+	buttons := make([]*api.Button, 0, len(api.Currencies)+1)
+	var keyboardButtons []tgbotapi.InlineKeyboardButton
+	for _, c := range api.Currencies {
+		b := api.NewButton(setCurrency, &api.CallbackData{RoomId: roomId, ExternalData: c.Code})
+		buttons = append(buttons, b)
+		name := c.Symbol + " " + c.Code
+		if c.Code == room.CurrencyCode() {
+			name = "✅ " + name
+		}
+		keyboardButtons = append(keyboardButtons, tgbotapi.NewInlineKeyboardButtonData(name, b.ID.Hex()))
+	}
+	backB := api.NewButton(roomSetting, &api.CallbackData{RoomId: roomId})
+	buttons = append(buttons, backB)
+
+	if _, err := bot.bs.SaveAll(ctx, buttons...); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	keyboard := optimizeKeyboardButtons(keyboardButtons)
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_back"), backB.ID.Hex())})
+
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createScreen(u, I18n(u.User, "scrn_choose_currency", room.Name), &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// RoomLanguage shows languages and sets the language of group chat posts of the room,
+// react on chooseRoomLang and setRoomLang actions
+type RoomLanguage struct {
+	bs  ButtonService
+	rs  RoomService
+	cfg *Config
+}
+
+// NewRoomLanguage makes a bot for screen room language
+func NewRoomLanguage(bs ButtonService, rs RoomService, cfg *Config) *RoomLanguage {
+	return &RoomLanguage{
+		bs:  bs,
+		rs:  rs,
+		cfg: cfg,
+	}
+}
+
+func (bot RoomLanguage) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && (hasAction(u, chooseRoomLang) || hasAction(u, setRoomLang))
+}
+
+func (bot *RoomLanguage) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	roomId := u.Button.CallbackData.RoomId
+
+	if u.Button.Action == setRoomLang {
+		lang := u.Button.CallbackData.ExternalData
+		if lang != "" && !tr().HasLang(lang) {
+			return api.TelegramMessage{}, errors.New("unknown language " + lang)
+		}
+		if err := bot.rs.SetLang(ctx, u.User.ID, roomId, lang); err != nil {
+			log.Error().Err(err).Msgf("set language of room %v failed", roomId)
+			return roomErrorMessage(u, err)
+		}
+		return api.TelegramMessage{
+			Redirect: &api.Update{CallbackQuery: u.CallbackQuery, User: u.User, Button: api.NewButton(roomSetting, &api.CallbackData{RoomId: roomId})},
+			Send:     true,
+		}, nil
+	}
+
+	room, err := bot.rs.FindById(ctx, roomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", roomId)
+		return api.TelegramMessage{}, err
+	}
+	if !room.IsAdmin(u.User.ID) {
+		return roomErrorMessage(u, api.ErrForbidden)
+	}
+
+	// the empty language lets every reader see posts in their own language
+	variants := []string{""}
+	for lang := range tr().Languages() {
+		variants = append(variants, lang)
+	}
+	sort.Strings(variants)
+
+	buttons := make([]*api.Button, 0, len(variants)+1)
+	var keyboardButtons []tgbotapi.InlineKeyboardButton
+	for _, lang := range variants {
+		b := api.NewButton(setRoomLang, &api.CallbackData{RoomId: roomId, ExternalData: lang})
+		buttons = append(buttons, b)
+		name := roomLangName(u.User, &api.Room{Lang: lang})
+		if lang == room.Lang {
+			name = "✅ " + name
+		}
+		keyboardButtons = append(keyboardButtons, tgbotapi.NewInlineKeyboardButtonData(name, b.ID.Hex()))
+	}
+	backB := api.NewButton(roomSetting, &api.CallbackData{RoomId: roomId})
+	buttons = append(buttons, backB)
+
+	if _, err := bot.bs.SaveAll(ctx, buttons...); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	keyboard := optimizeKeyboardButtons(keyboardButtons)
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_back"), backB.ID.Hex())})
+
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createScreen(u, I18n(u.User, "scrn_choose_room_lang", room.Name), &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// roomLangName is the name of the room language for the user, the room without the language follows readers
+func roomLangName(u *api.User, room *api.Room) string {
+	if room.Lang == "" {
+		return I18nPlain(u, "btn_room_lang_auto")
+	}
+	return langName(room.Lang)
+}
+
+// roomReader is the user whose language is used for posts of the room in the group chat,
+// the room language overrides the language of the user
+func roomReader(room *api.Room, u *api.User) *api.User {
+	if room == nil || room.Lang == "" {
+		return u
+	}
+	reader := *u
+	reader.SelectedLang = room.Lang
+	return &reader
+}
+
+// RoomMembers shows room members and applies admin actions on them, react on viewRoomMembers and member actions
+type RoomMembers struct {
+	bs  ButtonService
+	rs  RoomService
+	cfg *Config
+}
+
+// NewRoomMembers makes a bot for screen with room members
+func NewRoomMembers(bs ButtonService, rs RoomService, cfg *Config) *RoomMembers {
+	return &RoomMembers{
+		bs:  bs,
+		rs:  rs,
+		cfg: cfg,
+	}
+}
+
+func (bot RoomMembers) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && (hasAction(u, viewRoomMembers) || hasAction(u, removeMember) ||
+		hasAction(u, switchAdmin) || hasAction(u, transferOwnership))
+}
+
+func (bot *RoomMembers) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	roomId := u.Button.CallbackData.RoomId
+	memberId := int64(u.Button.CallbackData.UserId)
+
+	var err error
+	switch u.Button.Action {
+	case removeMember:
+		err = bot.rs.RemoveMember(ctx, u.User.ID, roomId, memberId)
+	case switchAdmin:
+		err = bot.rs.SwitchAdmin(ctx, u.User.ID, roomId, memberId)
+	case transferOwnership:
+		err = bot.rs.TransferOwnership(ctx, u.User.ID, roomId, memberId)
+	}
+	if err != nil {
+		log.Error().Err(err).Msgf("room member action %v failed", u.Button.Action)
+		return roomErrorMessage(u, err)
+	}
+
+	room, err := bot.rs.FindById(ctx, roomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", roomId)
+		return api.TelegramMessage{}, err
+	}
+	if !room.IsMember(u.User.ID) {
+		return roomErrorMessage(u, api.ErrNotMember)
+	}
+
+	var buttons []*api.Button
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	for _, m := range *room.Members {
+		b := api.NewButton(viewRoomMember, &api.CallbackData{RoomId: roomId, UserId: int(m.ID)})
+		buttons = append(buttons, b)
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(memberRoleMark(room, m.ID)+m.DisplayName, b.ID.Hex())})
+	}
+	backB := api.NewButton(roomSetting, &api.CallbackData{RoomId: roomId})
+	buttons = append(buttons, backB)
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_back"), backB.ID.Hex())})
+
+	if _, err := bot.bs.SaveAll(ctx, buttons...); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createScreen(u, I18n(u.User, "scrn_room_members", room.Name), &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// RoomMember shows a room member with actions allowed to the current user, react on viewRoomMember action
+type RoomMember struct {
+	bs  ButtonService
+	rs  RoomService
+	cfg *Config
+}
+
+// NewRoomMember makes a bot for screen with room member
+func NewRoomMember(bs ButtonService, rs RoomService, cfg *Config) *RoomMember {
+	return &RoomMember{
+		bs:  bs,
+		rs:  rs,
+		cfg: cfg,
+	}
+}
+
+func (bot RoomMember) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && hasAction(u, viewRoomMember)
+}
+
+func (bot *RoomMember) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	roomId := u.Button.CallbackData.RoomId
+	memberId := int64(u.Button.CallbackData.UserId)
+
+	room, err := bot.rs.FindById(ctx, roomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", roomId)
+		return api.TelegramMessage{}, err
+	}
+	member := findUser(room.Members, memberId)
+	if member == nil || !room.IsMember(u.User.ID) {
+		return roomErrorMessage(u, api.ErrNotMember)
+	}
+
+	data := &api.CallbackData{RoomId: roomId, UserId: int(memberId)}
+	backB := api.NewButton(viewRoomMembers, &api.CallbackData{RoomId: roomId})
+	buttons := []*api.Button{backB}
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+
+	userId := u.User.ID
+	if room.IsAdmin(userId) && memberId != userId && !room.IsOwner(memberId) &&
+		(room.IsOwner(userId) || !room.IsAdmin(memberId)) {
+		removeB := api.NewButton(removeMember, data)
+		buttons = append(buttons, removeB)
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_remove_member"), removeB.ID.Hex())})
+	}
+	if room.IsOwner(userId) && memberId != userId {
+		adminText := I18n(u.User, "btn_make_admin")
+		if room.IsAdmin(memberId) {
+			adminText = I18n(u.User, "btn_revoke_admin")
+		}
+		adminB := api.NewButton(switchAdmin, data)
+		ownerB := api.NewButton(transferOwnership, data)
+		buttons = append(buttons, adminB, ownerB)
+		keyboard = append(keyboard,
+			[]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardButtonData(adminText, adminB.ID.Hex())},
+			[]tgbotapi.InlineKeyboardButton{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_transfer_ownership"), ownerB.ID.Hex())})
+	}
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_back"), backB.ID.Hex())})
+
+	if _, err := bot.bs.SaveAll(ctx, buttons...); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	text := I18n(u.User, "scrn_room_member", Mention(member), room.Name, I18n(u.User, memberRoleKey(room, memberId)))
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createScreen(u, text, &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// RoomConfirm asks to confirm leaving or deleting the room, react on confirmLeaveRoom and confirmDeleteRoom actions
+type RoomConfirm struct {
+	bs  ButtonService
+	rs  RoomService
+	cfg *Config
+}
+
+// NewRoomConfirm makes a bot for confirmation screen
+func NewRoomConfirm(bs ButtonService, rs RoomService, cfg *Config) *RoomConfirm {
+	return &RoomConfirm{
+		bs:  bs,
+		rs:  rs,
+		cfg: cfg,
+	}
+}
+
+func (bot RoomConfirm) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && (hasAction(u, confirmLeaveRoom) || hasAction(u, confirmDeleteRoom))
+}
+
+func (bot *RoomConfirm) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	roomId := u.Button.CallbackData.RoomId
+	room, err := bot.rs.FindById(ctx, roomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", roomId)
+		return api.TelegramMessage{}, err
+	}
+
+	data := &api.CallbackData{RoomId: roomId}
+	yesB, text := api.NewButton(leaveRoom, data), I18n(u.User, "scrn_confirm_leave_room", room.Name)
+	if u.Button.Action == confirmDeleteRoom {
+		yesB, text = api.NewButton(deleteRoom, data), I18n(u.User, "scrn_confirm_delete_room", room.Name)
+	}
+	noB := api.NewButton(roomSetting, data)
+	if _, err := bot.bs.SaveAll(ctx, yesB, noB); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	keyboard := [][]tgbotapi.InlineKeyboardButton{
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_yes"), yesB.ID.Hex()),
+			tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_no"), noB.ID.Hex())},
+	}
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createScreen(u, text, &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// RoomExit leaves or deletes the room, react on leaveRoom and deleteRoom actions
+type RoomExit struct {
+	rs  RoomService
+	cfg *Config
+}
+
+// NewRoomExit makes a bot for leaving and deleting the room
+func NewRoomExit(rs RoomService, cfg *Config) *RoomExit {
+	return &RoomExit{
+		rs:  rs,
+		cfg: cfg,
+	}
+}
+
+func (bot RoomExit) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && (hasAction(u, leaveRoom) || hasAction(u, deleteRoom))
+}
+
+func (bot *RoomExit) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	roomId := u.Button.CallbackData.RoomId
+
+	var err error
+	if u.Button.Action == deleteRoom {
+		err = bot.rs.DeleteRoom(ctx, u.User.ID, roomId)
+	} else {
+		err = bot.rs.LeaveRoom(ctx, u.User.ID, roomId)
+	}
+	if err != nil {
+		log.Error().Err(err).Msgf("room %v action %v failed", roomId, u.Button.Action)
+		return roomErrorMessage(u, err)
+	}
+
+	return api.TelegramMessage{
+		Redirect: &api.Update{CallbackQuery: u.CallbackQuery, User: u.User, Button: api.NewButton(viewAllRooms, new(api.CallbackData))},
+		Send:     true,
+	}, nil
+}
+
+// roomErrorMessage shows the expected room errors to user, other errors are returned as is
+func roomErrorMessage(u *api.Update, err error) (api.TelegramMessage, error) {
+	var key string
+	switch {
+	case errors.Is(err, api.ErrForbidden):
+		key = "msg_forbidden"
+	case errors.Is(err, api.ErrNotMember):
+		key = "msg_not_be_in_rooms"
+	case errors.Is(err, api.ErrOwnerLeave):
+		key = "msg_owner_leave"
+	case errors.Is(err, api.ErrNotPending):
+		key = "msg_payment_reviewed"
+	case errors.Is(err, api.ErrAlreadyReverted):
+		key = "msg_operation_already_undone"
+	case errors.Is(err, api.ErrAlreadyOpened):
+		key = "msg_assignment_already_opened"
+	case errors.Is(err, api.ErrAlreadySettled):
+		key = "msg_expense_already_recorded"
+	case errors.Is(err, api.ErrInvalidInvite):
+		key = "msg_invite_invalid"
+	case errors.Is(err, api.ErrNoJoinRequest):
+		key = "msg_join_request_reviewed"
+	case errors.Is(err, api.ErrCurrencyMismatch), errors.Is(err, api.ErrWrongAmount):
+		key = "msg_wrong_amount"
+	default:
+		return api.TelegramMessage{}, err
+	}
+	if u.CallbackQuery != nil {
+		return api.TelegramMessage{CallbackConfig: createCallback(u, I18n(u.User, key), true), Send: true}, nil
+	}
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{tgbotapi.NewMessage(getChatID(u), I18n(u.User, key))},
+		Send:      true,
+	}, nil
+}
+
+func memberRoleKey(r *api.Room, userId int64) string {
+	switch {
+	case r.IsOwner(userId):
+		return "msg_role_owner"
+	case r.IsAdmin(userId):
+		return "msg_role_admin"
+	default:
+		return "msg_role_member"
+	}
+}
+
+func memberRoleMark(r *api.Room, userId int64) string {
+	switch {
+	case r.IsOwner(userId):
+		return "👑 "
+	case r.IsAdmin(userId):
+		return "⭐ "
+	default:
+		return ""
+	}
+}
+
+func findUser(users *[]api.User, id int64) *api.User {
+	if users == nil {
+		return nil
+	}
+	for _, u := range *users {
+		if u.ID == id {
+			return &u
+		}
+	}
+	return nil
+}
@@ -56,11 +56,15 @@ func (s *StartScreen) OnMessage(ctx context.Context, u *api.Update) (api.Telegra
 
 	var screen tgbotapi.Chattable
 	cb := api.NewButton(createRoom, new(api.CallbackData))
-	if _, err := s.bs.SaveAll(ctx, cb); err != nil {
+	roomsB := api.NewButton(viewAllRooms, new(api.CallbackData))
+	settB := api.NewButton(viewUserSetting, new(api.CallbackData))
+	if _, err := s.bs.SaveAll(ctx, cb, roomsB, settB); err != nil {
 		return api.TelegramMessage{}, err
 	}
 	screen = createScreen(u, I18n(u.User, "scrn_main"), &[][]tgbotapi.InlineKeyboardButton{
 		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_create_room"), cb.ID.Hex())},
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_all_rooms"), roomsB.ID.Hex())},
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_settings"), settB.ID.Hex())},
 	})
 
 	//config := tgbotapi.ChatMemberConfig{ChatID: getChatID(u), UserID: u.User.ID}
@@ -91,7 +95,7 @@ func NewStartScreenInitPerson(s ChatStateService, bs ButtonService, us UserServi
 }
 
 func (s StartScreenInitPerson) HasReact(u *api.Update) bool {
-	return isPrivate(u) && u.User.BirtDate == nil
+	return isPrivate(u) && u.User.BirtDate == nil && !(hasMessage(u) && hasAction(u, setBirtDate))
 }
 
 func (s *StartScreenInitPerson) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
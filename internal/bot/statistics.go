@@ -0,0 +1,161 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"github.com/almaznur91/splitty/internal/api"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+	"strings"
+	"time"
+)
+
+const (
+	topContributorsCount = 3
+	monthBarWidth        = 10
+)
+
+// Statistics shows collected amounts, payment discipline and birthdays of the room, react on statistics action
+type Statistics struct {
+	bs  ButtonService
+	rs  RoomService
+	cs  CollectionService
+	us  UserService
+	css ChatStateService
+	cfg *Config
+}
+
+// NewStatistics makes a bot for screen with room statistics
+func NewStatistics(bs ButtonService, rs RoomService, cs CollectionService, us UserService, css ChatStateService, cfg *Config) *Statistics {
+	return &Statistics{
+		bs:  bs,
+		rs:  rs,
+		cs:  cs,
+		us:  us,
+		css: css,
+		cfg: cfg,
+	}
+}
+
+func (bot Statistics) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && hasAction(u, statistics)
+}
+
+func (bot *Statistics) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	defer bot.css.CleanChatState(ctx, u.ChatState)
+
+	roomId := u.Button.CallbackData.RoomId
+	room, err := bot.rs.FindById(ctx, roomId)
+	if err != nil {
+		log.Error().Err(err).Msgf("cannot find room, id:%s", roomId)
+		return api.TelegramMessage{}, err
+	}
+	stats, err := bot.cs.Statistics(ctx, u.User.ID, roomId, time.Now())
+	if err != nil {
+		log.Error().Err(err).Msgf("statistics of room %v failed", roomId)
+		return roomErrorMessage(u, err)
+	}
+
+	ids := make([]int64, 0, len(*room.Members))
+	for _, m := range *room.Members {
+		ids = append(ids, m.ID)
+	}
+	members, err := bot.us.FindByIds(ctx, ids)
+	if err != nil {
+		log.Error().Err(err).Msgf("find members of room %v failed", roomId)
+		return api.TelegramMessage{}, err
+	}
+
+	backB := api.NewButton(viewRoom, &api.CallbackData{RoomId: roomId})
+	if _, err := bot.bs.SaveAll(ctx, backB); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+	keyboard := [][]tgbotapi.InlineKeyboardButton{
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_back"), backB.ID.Hex())},
+	}
+
+	text := I18n(u.User, "scrn_statistics", room.Name) + "\n\n" +
+		collectedText(u.User, stats) + "\n" +
+		onTimeText(u.User, stats) + "\n" +
+		topContributorsText(u.User, stats) + "\n" +
+		birthdayChartText(u.User, members)
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createScreen(u, text, &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+func collectedText(user *api.User, stats *api.RoomStatistics) string {
+	text := I18n(user, "msg_stats_collected") + "\n"
+	if len(stats.CollectedByYear) == 0 {
+		return text + I18n(user, "msg_stats_empty") + "\n"
+	}
+	for _, y := range stats.CollectedByYear {
+		text += fmt.Sprintf("%v: %s\n", y.Year, Bold(formatTotals(user, y.Totals)))
+	}
+	return text + I18n(user, "msg_stats_average", formatTotals(user, stats.Average)) + "\n"
+}
+
+func onTimeText(user *api.User, stats *api.RoomStatistics) string {
+	text := I18n(user, "msg_stats_on_time") + "\n"
+	var has bool
+	for _, m := range stats.Members {
+		if m.Due == 0 {
+			continue
+		}
+		has = true
+		text += fmt.Sprintf("- %s: <b>%v%%</b> (%v/%v)\n", Mention(&m.User), m.OnTime*100/m.Due, m.OnTime, m.Due)
+	}
+	if !has {
+		text += I18n(user, "msg_stats_empty") + "\n"
+	}
+	return text
+}
+
+// topContributorsText lists members in order of the statistics, they are sorted by paid amount
+func topContributorsText(user *api.User, stats *api.RoomStatistics) string {
+	text := I18n(user, "msg_stats_top") + "\n"
+	var n int
+	for _, m := range stats.Members {
+		if len(m.Total) == 0 || n == topContributorsCount {
+			continue
+		}
+		n++
+		text += fmt.Sprintf("%v. %s — %s\n", n, Mention(&m.User), Bold(formatTotals(user, m.Total)))
+	}
+	if n == 0 {
+		text += I18n(user, "msg_stats_empty") + "\n"
+	}
+	return text
+}
+
+// birthdayChartText draws count of birthdays in every month as a bar, the longest bar is monthBarWidth
+func birthdayChartText(user *api.User, members *[]api.User) string {
+	var byMonth [12]int
+	var max int
+	for _, m := range *members {
+		if m.BirtDate == nil {
+			continue
+		}
+		i := int(m.BirtDate.Month()) - 1
+		byMonth[i]++
+		if byMonth[i] > max {
+			max = byMonth[i]
+		}
+	}
+
+	text := I18n(user, "msg_stats_birthdays") + "\n"
+	if max == 0 {
+		return text + I18n(user, "msg_stats_empty") + "\n"
+	}
+	for i, count := range byMonth {
+		bar := strings.Repeat("▇", (count*monthBarWidth+max-1)/max)
+		text += stringForAlign(I18n(user, fmt.Sprintf("month_%v", i+1)), 3, true) + " " + bar
+		if count > 0 {
+			text += fmt.Sprintf(" %v", count)
+		}
+		text += "\n"
+	}
+	return text
+}
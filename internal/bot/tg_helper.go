@@ -2,32 +2,90 @@ package bot
 
 import (
 	"context"
-	"fmt"
 	"github.com/almaznur91/splitty/internal/api"
 	"github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"github.com/gookit/i18n"
+	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"regexp"
-	"strconv"
+	"golang.org/x/text/language"
 	"strings"
+	"time"
 )
 
 type UserService interface {
 	FindById(ctx context.Context, id int64) (*api.User, error)
+	FindByIds(ctx context.Context, ids []int64) (*[]api.User, error)
 	SetUserLang(ctx context.Context, userId int64, lang string) error
 	SetCountInPage(ctx context.Context, userId int64, count int) error
 	SetNotificationUser(ctx context.Context, userId int64, notification bool) error
+	SetBirtDate(ctx context.Context, userId int64, date time.Time) error
+	SetHideYear(ctx context.Context, userId int64, hide bool) error
+	SetTimeZone(ctx context.Context, userId int64, tz string) error
+	SetPaymentField(ctx context.Context, userId int64, field string, value string) error
 }
 
 type RoomService interface {
 	CreateRoom(ctx context.Context, u *api.Room) (*api.Room, error)
 	FindById(ctx context.Context, id string) (*api.Room, error)
-	JoinToRoom(ctx context.Context, u api.User, roomId string) error
 	LeaveRoom(ctx context.Context, userId int64, roomId string) error
-	SaveRoom(ctx context.Context, r *api.Room) (primitive.ObjectID, error)
 	FindRoomsByUserId(ctx context.Context, id int64) (*[]api.Room, error)
+	FindArchivedRoomsByUserId(ctx context.Context, id int64) (*[]api.Room, error)
+	FindRoomsByLikeName(ctx context.Context, userId int64, name string) (*[]api.Room, error)
 	ArchiveRoom(ctx context.Context, userId int64, roomId string) error
 	UnArchiveRoom(ctx context.Context, userId int64, roomId string) error
+	RenameRoom(ctx context.Context, userId int64, roomId string, name string) error
+	RemoveMember(ctx context.Context, userId int64, roomId string, memberId int64) error
+	SwitchAdmin(ctx context.Context, userId int64, roomId string, memberId int64) error
+	TransferOwnership(ctx context.Context, userId int64, roomId string, memberId int64) error
+	DeleteRoom(ctx context.Context, userId int64, roomId string) error
+	BindChat(ctx context.Context, userId int64, roomId string, chat api.Chat) error
+	UnbindChat(ctx context.Context, chatId int64) error
+	SyncChatMember(ctx context.Context, chatId int64, u api.User) error
+	RemoveChatMember(ctx context.Context, chatId int64, userId int64) error
+	SetGreeting(ctx context.Context, userId int64, roomId string, g *api.Greeting) error
+	SetGreetingDraft(ctx context.Context, userId int64, roomId string, g *api.Greeting) error
+	ActivateGreeting(ctx context.Context, userId int64, roomId string) error
+	SetCurrency(ctx context.Context, userId int64, roomId string, currency string) error
+	SetLang(ctx context.Context, userId int64, roomId string, lang string) error
+	SetCollectionRule(ctx context.Context, userId int64, roomId string, rule *api.CollectionRule) error
+	SwitchOrganizerOptOut(ctx context.Context, userId int64, roomId string) error
+}
+
+type PrivacyService interface {
+	ExportUserData(ctx context.Context, userId int64) (*api.UserData, error)
+	ForgetUser(ctx context.Context, userId int64) error
+}
+
+type InviteService interface {
+	CreateInvite(ctx context.Context, userId int64, roomId string, ttl time.Duration, maxUses int, approval bool) (*api.Invite, error)
+	FindInvites(ctx context.Context, userId int64, roomId string) (*[]api.Invite, error)
+	RevokeInvite(ctx context.Context, userId int64, inviteId string) (*api.Invite, error)
+	JoinByInvite(ctx context.Context, u api.User, code string) (*api.Room, bool, error)
+	ReviewJoinRequest(ctx context.Context, userId int64, roomId string, memberId int64, approve bool) (*api.User, error)
+}
+
+type CollectionService interface {
+	CreateCollection(ctx context.Context, userId int64, roomId string, celebrantId int64, amount api.Money) (*api.Collection, error)
+	FindCollectionById(ctx context.Context, id string) (*api.Collection, error)
+	FindCollectionsByRoomId(ctx context.Context, roomId string) (*[]api.Collection, error)
+	FindContributionById(ctx context.Context, id string) (*api.Contribution, error)
+	FindContributionsByUserId(ctx context.Context, roomId string, userId int64) (*[]api.Contribution, error)
+	MarkPaid(ctx context.Context, userId int64, contributionId string, receipt *api.Receipt) (*api.Contribution, error)
+	FindReviewQueue(ctx context.Context, userId int64, roomId string) (*[]api.Contribution, error)
+	ReviewPayment(ctx context.Context, userId int64, contributionId string, approve bool) (*api.Contribution, error)
+	SetDeadline(ctx context.Context, userId int64, collectionId string, deadline *time.Time) error
+	SnoozeReminders(ctx context.Context, userId int64, contributionId string, until time.Time) error
+	Statistics(ctx context.Context, userId int64, roomId string, now time.Time) (*api.RoomStatistics, error)
+	FindOperations(ctx context.Context, userId int64, roomId string) (*[]api.Operation, error)
+	FindJointCandidate(ctx context.Context, collection *api.Collection) (*api.Collection, error)
+	MergeCollections(ctx context.Context, userId int64, collectionId string, jointId string) (*api.Collection, error)
+	AcceptAssignment(ctx context.Context, userId int64, assignmentId string) (*api.Assignment, error)
+	DeclineAssignment(ctx context.Context, userId int64, assignmentId string) (*api.Assignment, error)
+	FindOperationById(ctx context.Context, id string) (*api.Operation, error)
+	IsOperationReverted(ctx context.Context, id primitive.ObjectID) (bool, error)
+	UndoOperation(ctx context.Context, userId int64, operationId string) (*api.Operation, error)
+	EditOperation(ctx context.Context, userId int64, operationId string, amount api.Money) (*api.Operation, error)
+	RecordExpense(ctx context.Context, userId int64, collectionId string, spent api.Money, receipt *api.Receipt) (*[]api.Contribution, error)
+	FindRefundsByRecipient(ctx context.Context, roomId string, recipientId int64) (*[]api.Contribution, error)
 }
 
 type Config struct {
@@ -36,7 +94,7 @@ type Config struct {
 }
 
 func NewInlineResultArticle(title, descr, text string, keyboard [][]tgbotapi.InlineKeyboardButton) tgbotapi.InlineQueryResultArticle {
-	article := tgbotapi.NewInlineQueryResultArticleMarkdown(primitive.NewObjectID().Hex(), title, text)
+	article := tgbotapi.NewInlineQueryResultArticleHTML(primitive.NewObjectID().Hex(), title, text)
 	article.Description = descr
 	article.ReplyMarkup = &tgbotapi.InlineKeyboardMarkup{InlineKeyboard: keyboard}
 	return article
@@ -54,7 +112,7 @@ func NewInlineConfig(inlid string, results []interface{}) *tgbotapi.InlineConfig
 func NewEditInlineMessage(inlId string, text string, keyboard [][]tgbotapi.InlineKeyboardButton) tgbotapi.EditMessageTextConfig {
 	tbMsg := tgbotapi.EditMessageTextConfig{
 		Text:      text,
-		ParseMode: tgbotapi.ModeMarkdown,
+		ParseMode: parseMode,
 	}
 	tbMsg.InlineMessageID = inlId
 	tbMsg.ReplyMarkup = &tgbotapi.InlineKeyboardMarkup{InlineKeyboard: keyboard}
@@ -64,7 +122,7 @@ func NewEditInlineMessage(inlId string, text string, keyboard [][]tgbotapi.Inlin
 func NewEditMessage(chatId int64, msgId int, text string, keyboard [][]tgbotapi.InlineKeyboardButton) tgbotapi.EditMessageTextConfig {
 	tbMsg := tgbotapi.EditMessageTextConfig{
 		Text:      text,
-		ParseMode: tgbotapi.ModeMarkdown,
+		ParseMode: parseMode,
 	}
 	tbMsg.ChatID = chatId
 	tbMsg.MessageID = msgId
@@ -75,28 +133,30 @@ func NewEditMessage(chatId int64, msgId int, text string, keyboard [][]tgbotapi.
 
 func NewMessage(chatId int64, text string, keyboard [][]tgbotapi.InlineKeyboardButton) tgbotapi.MessageConfig {
 	tbMsg := tgbotapi.NewMessage(chatId, text)
-	tbMsg.ParseMode = tgbotapi.ModeMarkdown
+	tbMsg.ParseMode = parseMode
 	tbMsg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
 	return tbMsg
 }
 
-func NewDocumentMessage(chatId int64, text string, fileId string) tgbotapi.DocumentConfig {
-	docMsd := tgbotapi.NewDocument(chatId, tgbotapi.FileID(fileId))
-	docMsd.ParseMode = tgbotapi.ModeMarkdown
+// NewDocumentMessage makes a document message, file is tgbotapi.FileID of uploaded document or tgbotapi.FileBytes of a generated one
+func NewDocumentMessage(chatId int64, text string, file tgbotapi.RequestFileData) tgbotapi.DocumentConfig {
+	docMsd := tgbotapi.NewDocument(chatId, file)
+	docMsd.ParseMode = parseMode
 	docMsd.Caption = text
 	return docMsd
 }
 
-func NewPhotoMessage(chatId int64, text string, fileId string) tgbotapi.PhotoConfig {
-	imageMsg := tgbotapi.NewPhoto(chatId, tgbotapi.FileID(fileId))
-	imageMsg.ParseMode = tgbotapi.ModeMarkdown
+// NewPhotoMessage makes a photo message, file is tgbotapi.FileID of uploaded photo or tgbotapi.FileBytes of a generated one
+func NewPhotoMessage(chatId int64, text string, file tgbotapi.RequestFileData) tgbotapi.PhotoConfig {
+	imageMsg := tgbotapi.NewPhoto(chatId, file)
+	imageMsg.ParseMode = parseMode
 	imageMsg.Caption = text
 	return imageMsg
 }
 
 func NewVideoMessage(chatId int64, text string, fileId string) tgbotapi.VideoConfig {
 	imageMsg := tgbotapi.NewVideo(chatId, tgbotapi.FileID(fileId))
-	imageMsg.ParseMode = tgbotapi.ModeMarkdown
+	imageMsg.ParseMode = parseMode
 	imageMsg.Caption = text
 	return imageMsg
 }
@@ -169,6 +229,16 @@ func createScreen(u *api.Update, text string, keyboard *[][]tgbotapi.InlineKeybo
 	}
 }
 
+// createTextScreen sends a new message when the pressed button belongs to a photo, video or document message,
+// such messages can't be edited into a text
+func createTextScreen(u *api.Update, text string, keyboard *[][]tgbotapi.InlineKeyboardButton) tgbotapi.Chattable {
+	if isButton(u) && u.CallbackQuery.Message != nil && (u.CallbackQuery.Message.Image != nil ||
+		u.CallbackQuery.Message.Video != nil || u.CallbackQuery.Message.Document != nil) {
+		return NewMessage(getChatID(u), text, *keyboard)
+	}
+	return createScreen(u, text, keyboard)
+}
+
 func createCallback(u *api.Update, text string, showAlert bool) *tgbotapi.CallbackConfig {
 	return &tgbotapi.CallbackConfig{
 		CallbackQueryID: u.CallbackQuery.ID,
@@ -194,18 +264,26 @@ func shortName(user *api.User) string {
 	return string(sn)
 }
 
-func userLink(user *api.User) string {
-	return fmt.Sprintf("[%s](tg://user?id=%d)", user.DisplayName, user.ID)
+// defaultCurrency suggests the currency of a new room by the creator language, admins can change it later
+func defaultCurrency(user *api.User) string {
+	if api.DefineLang(user) == language.Russian.String() {
+		return "RUB"
+	}
+	return "USD"
+}
+
+func formatMoney(user *api.User, m api.Money) string {
+	return m.Format(api.DefineLang(user))
 }
 
-func moneySpace(sum int) string {
-	s := strconv.Itoa(sum)
-	re := regexp.MustCompile("(\\d+)(\\d{3})")
-	for n := ""; n != s; {
-		n = s
-		s = re.ReplaceAllString(s, "$1 $2")
+// formatTotals formats amounts of every currency separately, e.g. "1 500,00 ₽ · $20.00"
+func formatTotals(user *api.User, ms []api.Money) string {
+	totals := api.SumByCurrency(ms)
+	s := make([]string, 0, len(totals))
+	for _, m := range totals {
+		s = append(s, formatMoney(user, m))
 	}
-	return s
+	return strings.Join(s, " · ")
 }
 
 func stringForAlign(s string, width int, spacesToEnd bool) string {
@@ -258,10 +336,20 @@ func optimizeKeyboardButtons(buttons []tgbotapi.InlineKeyboardButton) [][]tgbota
 	}
 }
 
-// I18n define text by user lang
+// I18n define text of the message by user lang, plain string args are escaped
 func I18n(u *api.User, text string, args ...interface{}) string {
-	tr := i18n.Tr(api.DefineLang(u), text, args...)
-	return strings.ReplaceAll(tr, "\\n", "\n")
+	return I18nPlain(u, text, escapeArgs(args)...)
+}
+
+// I18nPlain define text by user lang without escaping, for buttons, alerts and other texts Telegram doesn't parse.
+// Args are filled by the message format, see msgPart
+func I18nPlain(u *api.User, text string, args ...interface{}) string {
+	lang := api.DefineLang(u)
+	s, err := formatMessage(lang, tr().Tr(lang, text), args)
+	if err != nil {
+		log.Error().Err(err).Msgf("format message %s failed", text)
+	}
+	return strings.ReplaceAll(s, "\\n", "\n")
 }
 func contains(s []string, e string) bool {
 	e = strings.TrimSpace(e)
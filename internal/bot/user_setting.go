@@ -0,0 +1,340 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"github.com/almaznur91/splitty/internal/api"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var countInPageVariants = []int{5, 10, 15, 20}
+
+var timeZoneVariants = []string{
+	"UTC", "Europe/London", "Europe/Berlin", "Europe/Kaliningrad", "Europe/Moscow", "Europe/Samara",
+	"Asia/Yekaterinburg", "Asia/Omsk", "Asia/Novosibirsk", "Asia/Krasnoyarsk", "Asia/Irkutsk",
+	"Asia/Yakutsk", "Asia/Vladivostok", "Asia/Magadan", "Asia/Kamchatka",
+}
+
+// UserSetting shows user settings and applies the chosen option, react on viewUserSetting and set actions
+type UserSetting struct {
+	css ChatStateService
+	bs  ButtonService
+	us  UserService
+	cfg *Config
+}
+
+// NewUserSetting makes a bot for screen user settings
+func NewUserSetting(s ChatStateService, bs ButtonService, us UserService, cfg *Config) *UserSetting {
+	return &UserSetting{
+		css: s,
+		bs:  bs,
+		us:  us,
+		cfg: cfg,
+	}
+}
+
+// HasReact reacts on buttons only, the redirect after the typed birth date comes without a callback query
+func (s UserSetting) HasReact(u *api.Update) bool {
+	return (isButton(u) || u.FromRedirect) && isPrivate(u) && (hasAction(u, viewUserSetting) || hasAction(u, setLang) ||
+		hasAction(u, switchNotification) || hasAction(u, setCountInPage) ||
+		hasAction(u, switchHideYear) || hasAction(u, setTimeZone))
+}
+
+func (s *UserSetting) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	defer s.css.CleanChatState(ctx, u.ChatState)
+
+	if err := s.applySetting(ctx, u); err != nil {
+		log.Error().Err(err).Msg("apply user setting failed")
+		return api.TelegramMessage{}, err
+	}
+
+	langB := api.NewButton(chooseLang, new(api.CallbackData))
+	notifyB := api.NewButton(switchNotification, new(api.CallbackData))
+	countB := api.NewButton(chooseCountInPage, new(api.CallbackData))
+	birtDateB := api.NewButton(editBirtDate, new(api.CallbackData))
+	hideYearB := api.NewButton(switchHideYear, new(api.CallbackData))
+	tzB := api.NewButton(chooseTimeZone, new(api.CallbackData))
+	paymentB := api.NewButton(viewPaymentDetails, new(api.CallbackData))
+	backB := api.NewButton(viewStart, new(api.CallbackData))
+	if _, err := s.bs.SaveAll(ctx, langB, notifyB, countB, birtDateB, hideYearB, tzB, paymentB, backB); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	notifyText := I18n(u.User, "btn_notification_off")
+	if u.User.NotificationOn == nil || *u.User.NotificationOn {
+		notifyText = I18n(u.User, "btn_notification_on")
+	}
+	hideYearText := I18n(u.User, "btn_show_year")
+	if u.User.HideYear {
+		hideYearText = I18n(u.User, "btn_hide_year")
+	}
+
+	text := I18n(u.User, "scrn_user_setting", langName(api.DefineLang(u.User)), u.User.CountInPage,
+		birtDateText(u.User), timeZoneName(u.User))
+	keyboard := [][]tgbotapi.InlineKeyboardButton{
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_language"), langB.ID.Hex()),
+			tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_count_in_page"), countB.ID.Hex())},
+		{tgbotapi.NewInlineKeyboardButtonData(notifyText, notifyB.ID.Hex())},
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_birt_date"), birtDateB.ID.Hex()),
+			tgbotapi.NewInlineKeyboardButtonData(hideYearText, hideYearB.ID.Hex())},
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_time_zone"), tzB.ID.Hex())},
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_payment_details"), paymentB.ID.Hex())},
+		{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_back"), backB.ID.Hex())},
+	}
+
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createScreen(u, text, &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// applySetting saves the option carried by the pressed button and updates u.User accordingly
+func (s *UserSetting) applySetting(ctx context.Context, u *api.Update) error {
+	if u.Button == nil {
+		return nil
+	}
+	userId := u.User.ID
+	switch u.Button.Action {
+	case setLang:
+		lang := u.Button.CallbackData.ExternalData
+		if !tr().HasLang(lang) {
+			return errors.New("unknown language " + lang)
+		}
+		u.User.SelectedLang = lang
+		return s.us.SetUserLang(ctx, userId, lang)
+	case switchNotification:
+		on := u.User.NotificationOn != nil && !*u.User.NotificationOn
+		u.User.NotificationOn = &on
+		return s.us.SetNotificationUser(ctx, userId, on)
+	case setCountInPage:
+		count, err := strconv.Atoi(u.Button.CallbackData.ExternalData)
+		if err != nil {
+			return err
+		}
+		u.User.CountInPage = count
+		return s.us.SetCountInPage(ctx, userId, count)
+	case switchHideYear:
+		u.User.HideYear = !u.User.HideYear
+		return s.us.SetHideYear(ctx, userId, u.User.HideYear)
+	case setTimeZone:
+		tz := u.Button.CallbackData.ExternalData
+		if _, err := time.LoadLocation(tz); err != nil {
+			return err
+		}
+		u.User.TimeZone = tz
+		return s.us.SetTimeZone(ctx, userId, tz)
+	}
+	return nil
+}
+
+// UserSettingChoose shows variants for language, count in page and time zone settings
+type UserSettingChoose struct {
+	bs  ButtonService
+	cfg *Config
+}
+
+// NewUserSettingChoose makes a bot for screens with setting variants
+func NewUserSettingChoose(bs ButtonService, cfg *Config) *UserSettingChoose {
+	return &UserSettingChoose{
+		bs:  bs,
+		cfg: cfg,
+	}
+}
+
+func (s UserSettingChoose) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) &&
+		(hasAction(u, chooseLang) || hasAction(u, chooseCountInPage) || hasAction(u, chooseTimeZone))
+}
+
+func (s *UserSettingChoose) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	var action api.Action
+	var title string
+	var variants, names []string
+
+	switch u.Button.Action {
+	case chooseLang:
+		action, title = setLang, "scrn_choose_lang"
+		for lang := range tr().Languages() {
+			variants = append(variants, lang)
+		}
+		sort.Strings(variants)
+		for _, v := range variants {
+			names = append(names, langName(v))
+		}
+	case chooseCountInPage:
+		action, title = setCountInPage, "scrn_choose_count_in_page"
+		for _, v := range countInPageVariants {
+			variants = append(variants, strconv.Itoa(v))
+		}
+		names = variants
+	case chooseTimeZone:
+		action, title = setTimeZone, "scrn_choose_time_zone"
+		variants = timeZoneVariants
+		names = variants
+	}
+
+	buttons := make([]*api.Button, 0, len(variants)+1)
+	var keyboardButtons []tgbotapi.InlineKeyboardButton
+	for i, v := range variants {
+		b := api.NewButton(action, &api.CallbackData{ExternalData: v})
+		buttons = append(buttons, b)
+		keyboardButtons = append(keyboardButtons, tgbotapi.NewInlineKeyboardButtonData(names[i], b.ID.Hex()))
+	}
+	backB := api.NewButton(viewUserSetting, new(api.CallbackData))
+	buttons = append(buttons, backB)
+
+	if _, err := s.bs.SaveAll(ctx, buttons...); err != nil {
+		log.Error().Err(err).Msg("create btn failed")
+		return api.TelegramMessage{}, err
+	}
+
+	keyboard := optimizeKeyboardButtons(keyboardButtons)
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_back"), backB.ID.Hex())})
+
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{createScreen(u, I18n(u.User, title), &keyboard)},
+		Send:      true,
+	}, nil
+}
+
+// UserSettingBirtDate asks user to write new birth date, react on editBirtDate action
+type UserSettingBirtDate struct {
+	css ChatStateService
+	bs  ButtonService
+	cfg *Config
+}
+
+// NewUserSettingBirtDate makes a bot for screen birth date editing
+func NewUserSettingBirtDate(s ChatStateService, bs ButtonService, cfg *Config) *UserSettingBirtDate {
+	return &UserSettingBirtDate{
+		css: s,
+		bs:  bs,
+		cfg: cfg,
+	}
+}
+
+func (s UserSettingBirtDate) HasReact(u *api.Update) bool {
+	return isButton(u) && isPrivate(u) && hasAction(u, editBirtDate)
+}
+
+func (s *UserSettingBirtDate) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	cs := &api.ChatState{UserId: getChatID(u), Action: setBirtDate}
+	if err := s.css.Save(ctx, cs); err != nil {
+		log.Error().Err(err).Msg("create chat state failed")
+		return api.TelegramMessage{}, err
+	}
+
+	cb := api.NewButton(viewUserSetting, new(api.CallbackData))
+	if _, err := s.bs.SaveAll(ctx, cb); err != nil {
+		return api.TelegramMessage{}, err
+	}
+	screen := createScreen(u, I18n(u.User, "scrn_write_birt_date"),
+		&[][]tgbotapi.InlineKeyboardButton{
+			{tgbotapi.NewInlineKeyboardButtonData(I18n(u.User, "btn_cancel"), cb.ID.Hex())},
+		})
+
+	return api.TelegramMessage{
+		Chattable: []tgbotapi.Chattable{screen},
+		Send:      true,
+	}, nil
+}
+
+// SetBirtDate saves birth date written by user, react on setBirtDate chat state
+type SetBirtDate struct {
+	css ChatStateService
+	us  UserService
+	cfg *Config
+}
+
+// NewSetBirtDate makes a bot for saving birth date
+func NewSetBirtDate(s ChatStateService, us UserService, cfg *Config) *SetBirtDate {
+	return &SetBirtDate{
+		css: s,
+		us:  us,
+		cfg: cfg,
+	}
+}
+
+func (s SetBirtDate) HasReact(u *api.Update) bool {
+	return isPrivate(u) && hasMessage(u) && u.ChatState != nil && u.ChatState.Action == setBirtDate
+}
+
+func (s *SetBirtDate) OnMessage(ctx context.Context, u *api.Update) (api.TelegramMessage, error) {
+	date, err := parseBirtDate(u.Message.Text)
+	if err != nil {
+		return api.TelegramMessage{
+			Chattable: []tgbotapi.Chattable{tgbotapi.NewMessage(getChatID(u), I18n(u.User, "msg_wrong_birt_date"))},
+			Send:      true,
+		}, nil
+	}
+	defer s.css.CleanChatState(ctx, u.ChatState)
+
+	if err := s.us.SetBirtDate(ctx, u.User.ID, date); err != nil {
+		log.Error().Err(err).Msg("set birt date failed")
+		return api.TelegramMessage{}, err
+	}
+
+	// the first date comes from the init screen, so go on to the main screen, otherwise back to settings
+	next := viewUserSetting
+	if u.User.BirtDate == nil {
+		next = viewStart
+	}
+	u.User.BirtDate = &date
+
+	return api.TelegramMessage{
+		Redirect: &api.Update{Message: u.Message, User: u.User, Button: api.NewButton(next, new(api.CallbackData))},
+		Send:     true,
+	}, nil
+}
+
+// parseBirtDate parses date in format "DD MM YYYY", dots, slashes and dashes are allowed as separators
+func parseBirtDate(text string) (time.Time, error) {
+	fields := strings.Fields(strings.NewReplacer(".", " ", "/", " ", "-", " ").Replace(text))
+	if len(fields) != 3 {
+		return time.Time{}, errors.New("wrong birt date format")
+	}
+	var parts [3]int
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return time.Time{}, err
+		}
+		parts[i] = n
+	}
+	date := time.Date(parts[2], time.Month(parts[1]), parts[0], 0, 0, 0, 0, time.UTC)
+	if date.Day() != parts[0] || int(date.Month()) != parts[1] || parts[2] < 1900 || date.After(time.Now()) {
+		return time.Time{}, errors.New("wrong birt date")
+	}
+	return date, nil
+}
+
+func birtDateText(u *api.User) string {
+	if u.BirtDate == nil {
+		return "-"
+	}
+	if u.HideYear {
+		return formatDate(api.DefineLang(u), *u.BirtDate, "")
+	}
+	return formatDate(api.DefineLang(u), *u.BirtDate, "long")
+}
+
+func timeZoneName(u *api.User) string {
+	if u.TimeZone == "" {
+		return "UTC"
+	}
+	return u.TimeZone
+}
+
+func langName(lang string) string {
+	if name, ok := tr().Languages()[lang]; ok {
+		return name
+	}
+	return lang
+}
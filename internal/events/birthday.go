@@ -0,0 +1,118 @@
+package events
+
+import (
+	"context"
+	"github.com/almaznur91/splitty/internal/api"
+	"github.com/almaznur91/splitty/internal/bot"
+	"github.com/almaznur91/splitty/internal/handler"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+const birthdayCheckInterval = 10 * time.Minute
+
+type RoomService interface {
+	FindRoomsToGreet(ctx context.Context) (*[]api.Room, error)
+	SetGreetedAt(ctx context.Context, roomId string, t time.Time) error
+	FindRoomsWithCollectionRule(ctx context.Context) (*[]api.Room, error)
+	FindById(ctx context.Context, id string) (*api.Room, error)
+}
+
+type MemberService interface {
+	FindByIds(ctx context.Context, ids []int64) (*[]api.User, error)
+}
+
+// BirthdayNotifier posts birthday greetings of the rooms into bound group chats and private chats once a day
+type BirthdayNotifier struct {
+	TbAPI         tbAPI
+	RoomService   RoomService
+	MemberService MemberService
+	ErrorHandler  *handler.ErrorHandler
+	GreetingHour  int
+}
+
+// Do checks birthdays periodically, blocked call
+func (n *BirthdayNotifier) Do(ctx context.Context) {
+	ticker := time.NewTicker(birthdayCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := n.greet(ctx, time.Now()); err != nil {
+			n.ErrorHandler.HandleErrorWithMsg(err, "failed to send birthday greetings")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (n *BirthdayNotifier) greet(ctx context.Context, now time.Time) error {
+	if now.Hour() < n.GreetingHour {
+		return nil
+	}
+	rooms, err := n.RoomService.FindRoomsToGreet(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to find rooms to greet")
+	}
+
+	for _, r := range *rooms {
+		if r.GreetedAt != nil && sameDay(r.GreetedAt.In(now.Location()), now) || r.Members == nil {
+			continue
+		}
+
+		ids := make([]int64, 0, len(*r.Members))
+		for _, m := range *r.Members {
+			ids = append(ids, m.ID)
+		}
+		// a broken room is logged, so other rooms are still greeted
+		users, err := n.MemberService.FindByIds(ctx, ids)
+		if err != nil {
+			log.Error().Err(err).Msgf("failed to find members of room %v", r.ID.Hex())
+			continue
+		}
+
+		var celebrants []api.User
+		for _, u := range *users {
+			if u.BirtDate != nil && isBirthday(*u.BirtDate, now) {
+				celebrants = append(celebrants, u)
+			}
+		}
+
+		if len(celebrants) > 0 {
+			r := r
+			for _, msg := range bot.BirthdayGreetings(&r, *users, celebrants, now) {
+				// a member may have blocked the bot, so one failed message doesn't stop others
+				if _, err := n.TbAPI.Send(msg); err != nil {
+					log.Warn().Err(err).Msgf("can't send birthday greeting of room %v", r.ID.Hex())
+				}
+			}
+			log.Debug().Msgf("birthday greetings sent, room %v", r.ID.Hex())
+		}
+
+		if err := n.RoomService.SetGreetedAt(ctx, r.ID.Hex(), now); err != nil {
+			log.Error().Err(err).Msgf("failed to mark room %v greeted", r.ID.Hex())
+		}
+	}
+	return nil
+}
+
+// isBirthday checks day and month of the birth date, 29 February is celebrated on 28 February in non-leap years
+func isBirthday(birtDate time.Time, now time.Time) bool {
+	month, day := birtDate.Month(), birtDate.Day()
+	if month == time.February && day == 29 && !isLeap(now.Year()) {
+		day = 28
+	}
+	return now.Month() == month && now.Day() == day
+}
+
+func isLeap(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
@@ -0,0 +1,204 @@
+package events
+
+import (
+	"context"
+	"github.com/almaznur91/splitty/internal/api"
+	"github.com/almaznur91/splitty/internal/bot"
+	"github.com/almaznur91/splitty/internal/handler"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+const collectionCheckInterval = 10 * time.Minute
+
+// CollectionScheduler assigns organizers and opens collections for upcoming birthdays by collection rules of rooms,
+// members are notified about both
+type CollectionScheduler struct {
+	TbAPI             tbAPI
+	RoomService       RoomService
+	CollectionService CollectionService
+	MemberService     MemberService
+	ButtonService     ButtonService
+	ErrorHandler      *handler.ErrorHandler
+	// OpenHour is an hour after which collections are opened, so members are not notified at night
+	OpenHour int
+	// AssignDays is a count of days before the collection opens when the organizer is assigned, so there is time to decline
+	AssignDays int
+}
+
+// Do checks birthdays of rooms with collection rules periodically, blocked call
+func (s *CollectionScheduler) Do(ctx context.Context) {
+	ticker := time.NewTicker(collectionCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.open(ctx, time.Now()); err != nil {
+			s.ErrorHandler.HandleErrorWithMsg(err, "failed to open birthday collections")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *CollectionScheduler) open(ctx context.Context, now time.Time) error {
+	if now.Hour() < s.OpenHour {
+		return nil
+	}
+	rooms, err := s.RoomService.FindRoomsWithCollectionRule(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to find rooms with collection rule")
+	}
+
+	for _, r := range *rooms {
+		if r.Members == nil || r.CollectionRule == nil {
+			continue
+		}
+		ids := make([]int64, 0, len(*r.Members))
+		for _, m := range *r.Members {
+			ids = append(ids, m.ID)
+		}
+		// a broken room or member is logged, so others still get collections
+		users, err := s.MemberService.FindByIds(ctx, ids)
+		if err != nil {
+			log.Error().Err(err).Msgf("failed to find members of room %v", r.ID.Hex())
+			continue
+		}
+
+		for _, u := range *users {
+			if u.BirtDate == nil {
+				continue
+			}
+			birthday := nextBirthday(*u.BirtDate, now)
+			days := int(birthday.Sub(date(now)).Hours() / 24)
+			if days > r.CollectionRule.DaysBefore+s.AssignDays {
+				continue
+			}
+			assignment, err := s.CollectionService.AssignOrganizer(ctx, r.ID.Hex(), u.ID, birthday)
+			if err != nil {
+				log.Error().Err(err).Msgf("failed to assign organizer for %v in room %v", u.ID, r.ID.Hex())
+				continue
+			}
+			if assignment != nil {
+				r := r
+				if err := s.notifyAssignment(ctx, &r, *users, assignment); err != nil {
+					log.Error().Err(err).Msgf("failed to notify about assignment %v", assignment.ID.Hex())
+				}
+			}
+			if days > r.CollectionRule.DaysBefore {
+				continue
+			}
+			collection, err := s.CollectionService.OpenBirthdayCollection(ctx, r.ID.Hex(), u.ID, birthday)
+			if err != nil {
+				log.Error().Err(err).Msgf("failed to open collection for %v in room %v", u.ID, r.ID.Hex())
+				continue
+			}
+			if collection == nil {
+				continue
+			}
+			log.Debug().Msgf("birthday collection opened, room %v, collection %v", r.ID.Hex(), collection.ID.Hex())
+			if err := s.notify(ctx, *users, collection); err != nil {
+				log.Error().Err(err).Msgf("failed to notify about collection %v", collection.ID.Hex())
+			}
+			if err := s.offerJoint(ctx, *users, collection); err != nil {
+				log.Error().Err(err).Msgf("failed to offer joint collection for %v", collection.ID.Hex())
+			}
+		}
+	}
+	return nil
+}
+
+// notify sends the news to every member except the celebrant, the gift is a surprise
+func (s *CollectionScheduler) notify(ctx context.Context, users []api.User, collection *api.Collection) error {
+	for _, u := range users {
+		if u.ID == collection.Celebrant.ID {
+			continue
+		}
+		u := u
+		msg, buttons := bot.CollectionOpened(&u, collection)
+		if _, err := s.ButtonService.SaveAll(ctx, buttons...); err != nil {
+			return errors.Wrap(err, "failed to save collection buttons")
+		}
+		// a member may have blocked the bot, so one failed message doesn't stop others
+		if _, err := s.TbAPI.Send(msg); err != nil {
+			log.Warn().Err(err).Msgf("can't notify about collection %v", collection.ID.Hex())
+		}
+	}
+	return nil
+}
+
+// offerJoint offers the organizer to merge the collection if the celebrant has an active collection in another room
+func (s *CollectionScheduler) offerJoint(ctx context.Context, users []api.User, collection *api.Collection) error {
+	joint, err := s.CollectionService.FindJointCandidate(ctx, collection)
+	if err != nil || joint == nil {
+		return errors.Wrapf(err, "failed to find joint collection for %v", collection.ID.Hex())
+	}
+	jointRoom, err := s.RoomService.FindById(ctx, joint.RoomId)
+	if err != nil {
+		return errors.Wrapf(err, "failed to find room %v", joint.RoomId)
+	}
+	for _, u := range users {
+		if u.ID != collection.Organizer {
+			continue
+		}
+		u := u
+		msg, buttons := bot.JointOffer(&u, collection, joint, jointRoom)
+		if _, err := s.ButtonService.SaveAll(ctx, buttons...); err != nil {
+			return errors.Wrap(err, "failed to save joint offer buttons")
+		}
+		if _, err := s.TbAPI.Send(msg); err != nil {
+			log.Warn().Err(err).Msgf("can't offer joint collection %v", joint.ID.Hex())
+		}
+	}
+	return nil
+}
+
+// notifyAssignment asks the assigned organizer to accept, admins are told when nobody is left to organize
+func (s *CollectionScheduler) notifyAssignment(ctx context.Context, room *api.Room, users []api.User, a *api.Assignment) error {
+	for _, u := range users {
+		u := u
+		var msg tbapi.Chattable
+		var buttons []*api.Button
+		switch {
+		case a.Status == api.AssignmentUnassigned && room.IsAdmin(u.ID) && u.ID != a.Celebrant.ID:
+			msg = bot.AssignmentUnassigned(&u, room, a)
+		case u.ID == a.Organizer:
+			msg, buttons = bot.OrganizerAssignment(&u, room, a)
+		default:
+			continue
+		}
+		if len(buttons) > 0 {
+			if _, err := s.ButtonService.SaveAll(ctx, buttons...); err != nil {
+				return errors.Wrap(err, "failed to save assignment buttons")
+			}
+		}
+		if _, err := s.TbAPI.Send(msg); err != nil {
+			log.Warn().Err(err).Msgf("can't notify about assignment %v", a.ID.Hex())
+		}
+	}
+	log.Debug().Msgf("organizer assigned, room %v, assignment %v", room.ID.Hex(), a.ID.Hex())
+	return nil
+}
+
+// nextBirthday returns the date of the nearest birthday from today in UTC, so the same birthday is always the same time
+func nextBirthday(birtDate time.Time, now time.Time) time.Time {
+	today := date(now)
+	for year := now.Year(); ; year++ {
+		month, day := birtDate.Month(), birtDate.Day()
+		if month == time.February && day == 29 && !isLeap(year) {
+			day = 28
+		}
+		if b := time.Date(year, month, day, 0, 0, 0, 0, time.UTC); !b.Before(today) {
+			return b
+		}
+	}
+}
+
+func date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,189 @@
+package events
+
+import (
+	"context"
+	"github.com/almaznur91/splitty/internal/api"
+	"github.com/almaznur91/splitty/internal/bot"
+	"github.com/almaznur91/splitty/internal/handler"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const reminderCheckInterval = 10 * time.Minute
+
+type CollectionService interface {
+	FindCollectionsWithDeadline(ctx context.Context) (*[]api.Collection, error)
+	FindDebtsByCollectionIds(ctx context.Context, collectionIds []primitive.ObjectID) (*[]api.Contribution, error)
+	SetContributionReminded(ctx context.Context, id primitive.ObjectID, t time.Time) error
+	SetCollectionDigestAt(ctx context.Context, id primitive.ObjectID, t time.Time) error
+	OpenBirthdayCollection(ctx context.Context, roomId string, celebrantId int64, birthday time.Time) (*api.Collection, error)
+	FindJointCandidate(ctx context.Context, collection *api.Collection) (*api.Collection, error)
+	AssignOrganizer(ctx context.Context, roomId string, celebrantId int64, birthday time.Time) (*api.Assignment, error)
+}
+
+// DebtNotifier reminds members about unpaid contributions of collections with a deadline
+// and sends organizers a daily digest of who hasn't paid yet
+type DebtNotifier struct {
+	TbAPI             tbAPI
+	CollectionService CollectionService
+	MemberService     MemberService
+	ButtonService     ButtonService
+	ErrorHandler      *handler.ErrorHandler
+	// ReminderDays is a count of days before the deadline when the first reminder is sent
+	ReminderDays int
+	// QuietHoursFrom and QuietHoursTo are hours in the time zone of the user when nothing is sent
+	QuietHoursFrom int
+	QuietHoursTo   int
+	DigestHour     int
+}
+
+// Do checks debts periodically, blocked call
+func (n *DebtNotifier) Do(ctx context.Context) {
+	ticker := time.NewTicker(reminderCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := n.remind(ctx, time.Now()); err != nil {
+			n.ErrorHandler.HandleErrorWithMsg(err, "failed to send debt reminders")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (n *DebtNotifier) remind(ctx context.Context, now time.Time) error {
+	collections, err := n.CollectionService.FindCollectionsWithDeadline(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to find collections with deadline")
+	}
+	if len(*collections) == 0 {
+		return nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(*collections))
+	for _, c := range *collections {
+		ids = append(ids, c.ID)
+	}
+	debts, err := n.CollectionService.FindDebtsByCollectionIds(ctx, ids)
+	if err != nil {
+		return errors.Wrap(err, "failed to find debts")
+	}
+	debtsByCollection := make(map[primitive.ObjectID][]api.Contribution)
+	userIds := make([]int64, 0, len(*debts)+len(*collections))
+	for _, d := range *debts {
+		// settlement debts appear after the gift is bought, the deadline of the collection is not about them
+		if d.Kind != api.ContributionShare {
+			continue
+		}
+		debtsByCollection[d.CollectionId] = append(debtsByCollection[d.CollectionId], d)
+		userIds = append(userIds, d.User.ID)
+	}
+	for _, c := range *collections {
+		userIds = append(userIds, c.Organizer)
+	}
+
+	// settings of users are taken fresh, members stored in contributions may be outdated
+	found, err := n.MemberService.FindByIds(ctx, userIds)
+	if err != nil {
+		return errors.Wrap(err, "failed to find debtors")
+	}
+	users := make(map[int64]*api.User, len(*found))
+	for i := range *found {
+		users[(*found)[i].ID] = &(*found)[i]
+	}
+
+	for _, c := range *collections {
+		c := c
+		collectionDebts := debtsByCollection[c.ID]
+		for _, d := range collectionDebts {
+			d := d
+			user, ok := users[d.User.ID]
+			if !ok || !n.needRemind(user, &c, &d, now) {
+				continue
+			}
+			if err := n.send(ctx, user, &c, &d, now); err != nil {
+				return err
+			}
+		}
+
+		organizer, ok := users[c.Organizer]
+		if !ok || len(collectionDebts) == 0 || !n.needDigest(organizer, &c, now) {
+			continue
+		}
+		// the organizer may have blocked the bot, the digest is not repeated that day anyway
+		if _, err := n.TbAPI.Send(bot.OutstandingDigest(organizer, &c, collectionDebts)); err != nil {
+			log.Warn().Err(err).Msgf("can't send outstanding digest of collection %v", c.ID.Hex())
+		}
+		if err := n.CollectionService.SetCollectionDigestAt(ctx, c.ID, now); err != nil {
+			return errors.Wrapf(err, "failed to mark digest of collection %v sent", c.ID.Hex())
+		}
+	}
+	return nil
+}
+
+func (n *DebtNotifier) send(ctx context.Context, user *api.User, c *api.Collection, d *api.Contribution, now time.Time) error {
+	msg, buttons := bot.DebtReminder(user, c, d, now)
+	if _, err := n.ButtonService.SaveAll(ctx, buttons...); err != nil {
+		return errors.Wrap(err, "failed to save reminder buttons")
+	}
+	// a member may have blocked the bot, so one failed reminder doesn't stop others
+	if _, err := n.TbAPI.Send(msg); err != nil {
+		log.Warn().Err(err).Msgf("can't send debt reminder of contribution %v", d.ID.Hex())
+	}
+	if err := n.CollectionService.SetContributionReminded(ctx, d.ID, now); err != nil {
+		return errors.Wrapf(err, "failed to mark contribution %v reminded", d.ID.Hex())
+	}
+	log.Debug().Msgf("debt reminder sent, contribution %v", d.ID.Hex())
+	return nil
+}
+
+// needRemind sends one reminder when the deadline is close and one reminder a day after the deadline
+func (n *DebtNotifier) needRemind(user *api.User, c *api.Collection, d *api.Contribution, now time.Time) bool {
+	if user.NotificationOn != nil && !*user.NotificationOn || d.SnoozedUntil != nil && now.Before(*d.SnoozedUntil) {
+		return false
+	}
+	local := now.In(location(user))
+	if n.isQuiet(local) {
+		return false
+	}
+	if now.Before(*c.Deadline) {
+		return d.Reminders == 0 && c.Deadline.Sub(now) <= time.Duration(n.ReminderDays)*24*time.Hour
+	}
+	return d.RemindedAt == nil || d.RemindedAt.Before(*c.Deadline) || !sameDay(d.RemindedAt.In(local.Location()), local)
+}
+
+func (n *DebtNotifier) needDigest(organizer *api.User, c *api.Collection, now time.Time) bool {
+	if organizer.NotificationOn != nil && !*organizer.NotificationOn {
+		return false
+	}
+	local := now.In(location(organizer))
+	if local.Hour() < n.DigestHour || n.isQuiet(local) {
+		return false
+	}
+	return c.DigestAt == nil || !sameDay(c.DigestAt.In(local.Location()), local)
+}
+
+// isQuiet checks quiet hours, the interval may pass midnight
+func (n *DebtNotifier) isQuiet(local time.Time) bool {
+	h := local.Hour()
+	if n.QuietHoursFrom <= n.QuietHoursTo {
+		return h >= n.QuietHoursFrom && h < n.QuietHoursTo
+	}
+	return h >= n.QuietHoursFrom || h < n.QuietHoursTo
+}
+
+func location(u *api.User) *time.Location {
+	if u.TimeZone == "" {
+		return time.UTC
+	}
+	if loc, err := time.LoadLocation(u.TimeZone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
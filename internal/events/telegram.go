@@ -16,6 +16,7 @@ type ChatStateService interface {
 
 type ButtonService interface {
 	FindById(ctx context.Context, id string) (*api.Button, error)
+	SaveAll(ctx context.Context, b ...*api.Button) ([]*api.Button, error)
 }
 
 type UserService interface {
@@ -25,13 +26,16 @@ type UserService interface {
 // TelegramListener listens to tg update, forward to bots and send back responses
 // Not thread safe
 type TelegramListener struct {
-	TbAPI            tbAPI
-	Bots             bot.Interface
-	ErrorHandler     *handler.ErrorHandler
-	ChatStateService ChatStateService
-	ButtonService    ButtonService
-	upds             chan tbapi.Update
-	UserService      UserService
+	TbAPI               tbAPI
+	Bots                bot.Interface
+	ErrorHandler        *handler.ErrorHandler
+	ChatStateService    ChatStateService
+	ButtonService       ButtonService
+	upds                chan tbapi.Update
+	UserService         UserService
+	BirthdayNotifier    *BirthdayNotifier
+	DebtNotifier        *DebtNotifier
+	CollectionScheduler *CollectionScheduler
 }
 
 type tbAPI interface {
@@ -45,6 +49,18 @@ func (l *TelegramListener) Do(ctx context.Context) (err error) {
 
 	go l.ErrorHandler.Do(ctx)
 
+	if l.BirthdayNotifier != nil {
+		go l.BirthdayNotifier.Do(ctx)
+	}
+
+	if l.DebtNotifier != nil {
+		go l.DebtNotifier.Do(ctx)
+	}
+
+	if l.CollectionScheduler != nil {
+		go l.CollectionScheduler.Do(ctx)
+	}
+
 	u := tbapi.NewUpdate(0)
 	u.Timeout = 60
 
@@ -140,7 +156,7 @@ func (l *TelegramListener) sendBotResponse(ctx context.Context, resp api.Telegra
 		if err != nil {
 			return errors.Wrapf(err, "can't send query to telegram %v", response)
 		}
-		log.Debug().Msgf("bot response - %q", resp.InlineConfig)
+		log.Debug().Msgf("bot response - %+v", resp.InlineConfig)
 	}
 
 	if len(resp.Chattable) > 0 {
@@ -184,14 +200,29 @@ func transform(msg *tbapi.Message) *api.Message {
 	}
 
 	message.Chat = &api.Chat{
-		ID:   msg.Chat.ID,
-		Type: msg.Chat.Type,
+		ID:    msg.Chat.ID,
+		Type:  msg.Chat.Type,
+		Title: msg.Chat.Title,
 	}
 
 	if msg.From != nil {
 		message.From = transformUser(msg.From)
 	}
 
+	if len(msg.NewChatMembers) > 0 {
+		members := make([]api.User, 0, len(msg.NewChatMembers))
+		for _, m := range msg.NewChatMembers {
+			m := m
+			members = append(members, transformUser(&m))
+		}
+		message.NewChatMembers = &members
+	}
+
+	if msg.LeftChatMember != nil {
+		left := transformUser(msg.LeftChatMember)
+		message.LeftChatMember = &left
+	}
+
 	switch {
 	case msg.Entities != nil && len(msg.Entities) > 0:
 		message.Entities = transformEntities(msg.Entities)
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"io"
+	"time"
+)
+
+const (
+	backupFormat  = "splitty-backup"
+	backupVersion = 1
+	// restoreBatch is how many documents are inserted at once
+	restoreBatch = 500
+)
+
+// backupCollections are collections owned by the bot. The migrations collection is not saved, it is reset
+// to the schema of the archive and the restored database is migrated again, so indexes are created in the new database too
+var backupCollections = []string{"user", "room", "chat_state", "button", "collection", "contribution", "operation",
+	"assignment", "invite"}
+
+// BackupHeader is the first line of the archive. Schema is the migration version of the saved database
+type BackupHeader struct {
+	Format   string    `json:"format"`
+	Version  int       `json:"version"`
+	Schema   int       `json:"schema"`
+	CreateAt time.Time `json:"createAt"`
+}
+
+// backupLine is a document of the collection in canonical extended JSON, so object ids, dates and int64 are kept
+type backupLine struct {
+	Collection string          `json:"collection"`
+	Document   json.RawMessage `json:"document"`
+}
+
+// BackupStats counts documents by collections
+type BackupStats map[string]int
+
+// Backup dumps collections of the bot to the NDJSON stream and restores them from it, compression is up to the caller
+type Backup struct {
+	db *mongo.Database
+}
+
+func NewBackup(db *mongo.Database) *Backup {
+	return &Backup{db: db}
+}
+
+// Dump writes the header and every document of collections of the bot, one JSON object per line
+func (b *Backup) Dump(ctx context.Context, w io.Writer) (BackupStats, error) {
+	schema, err := NewMigrator(b.db).Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(BackupHeader{backupFormat, backupVersion, schema, time.Now()}); err != nil {
+		return nil, err
+	}
+
+	stats := make(BackupStats)
+	for _, name := range backupCollections {
+		cur, err := b.db.Collection(name).Find(ctx, bson.M{})
+		if err != nil {
+			return stats, err
+		}
+		for cur.Next(ctx) {
+			doc, err := bson.MarshalExtJSON(cur.Current, true, false)
+			if err != nil {
+				_ = cur.Close(ctx)
+				return stats, err
+			}
+			if err := enc.Encode(backupLine{name, doc}); err != nil {
+				_ = cur.Close(ctx)
+				return stats, err
+			}
+			stats[name]++
+		}
+		if err := cur.Err(); err != nil {
+			return stats, err
+		}
+		_ = cur.Close(ctx)
+	}
+	return stats, nil
+}
+
+// ReadBackupHeader checks that the stream is a backup of the known format which the current code can migrate
+func ReadBackupHeader(r *bufio.Reader) (*BackupHeader, error) {
+	line, err := r.ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	h := &BackupHeader{}
+	if err := json.Unmarshal(line, h); err != nil || h.Format != backupFormat {
+		return nil, errors.New("not a backup archive")
+	}
+	if h.Version != backupVersion {
+		return nil, fmt.Errorf("unsupported backup version %v, expected %v", h.Version, backupVersion)
+	}
+	if latest := LatestSchema(); h.Schema > latest {
+		return nil, fmt.Errorf("backup schema %v is newer than %v supported by this build", h.Schema, latest)
+	}
+	return h, nil
+}
+
+// Restore inserts documents of the stream after the header. The database must have no documents in collections
+// of the bot unless drop is set, then they are deleted. Dry run only reads and checks the stream, the stream
+// can't be read twice, so the caller checks it by the dry run before the drop
+func (b *Backup) Restore(ctx context.Context, r *bufio.Reader, dryRun bool, drop bool) (BackupStats, error) {
+	known := make(map[string]bool, len(backupCollections))
+	for _, name := range backupCollections {
+		known[name] = true
+	}
+	if !dryRun {
+		if err := b.prepare(ctx, drop); err != nil {
+			return nil, err
+		}
+	}
+
+	stats := make(BackupStats)
+	batches := make(map[string][]interface{})
+	flush := func(name string) error {
+		batch := batches[name]
+		batches[name] = batch[:0]
+		if dryRun || len(batch) == 0 {
+			return nil
+		}
+		_, err := b.db.Collection(name).InsertMany(ctx, batch)
+		return err
+	}
+
+	for n := 2; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return stats, err
+		}
+		if len(line) > 0 {
+			var l backupLine
+			if err := json.Unmarshal(line, &l); err != nil {
+				return stats, errors.Wrapf(err, "line %v", n)
+			}
+			if !known[l.Collection] {
+				return stats, fmt.Errorf("line %v: unknown collection %q", n, l.Collection)
+			}
+			var doc bson.D
+			if err := bson.UnmarshalExtJSON(l.Document, true, &doc); err != nil {
+				return stats, errors.Wrapf(err, "line %v", n)
+			}
+			batches[l.Collection] = append(batches[l.Collection], doc)
+			stats[l.Collection]++
+			if len(batches[l.Collection]) >= restoreBatch {
+				if err := flush(l.Collection); err != nil {
+					return stats, err
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	for _, name := range backupCollections {
+		if err := flush(name); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// prepare deletes documents of collections of the bot or checks that there are none
+func (b *Backup) prepare(ctx context.Context, drop bool) error {
+	for _, name := range backupCollections {
+		col := b.db.Collection(name)
+		if drop {
+			if _, err := col.DeleteMany(ctx, bson.M{}); err != nil {
+				return err
+			}
+			continue
+		}
+		count, err := col.CountDocuments(ctx, bson.M{})
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("collection %q is not empty, restore with -drop to replace the data", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,488 @@
+package repository
+
+import (
+	"context"
+	"github.com/almaznur91/splitty/internal/api"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+type MongoCollectionRepository struct {
+	col           *mongo.Collection
+	contributions *mongo.Collection
+	operations    *mongo.Collection
+	assignments   *mongo.Collection
+}
+
+func NewCollectionRepository(col *mongo.Database) *MongoCollectionRepository {
+	return &MongoCollectionRepository{
+		col:           col.Collection("collection"),
+		contributions: col.Collection("contribution"),
+		operations:    col.Collection("operation"),
+		assignments:   col.Collection("assignment"),
+	}
+}
+
+type CollectionRepository interface {
+	SaveCollection(ctx context.Context, c *api.Collection) (primitive.ObjectID, error)
+	FindCollectionById(ctx context.Context, id string) (*api.Collection, error)
+	FindCollectionsByRoomId(ctx context.Context, roomId string) (*[]api.Collection, error)
+	FindCollectionsByOrganizer(ctx context.Context, userId int64) (*[]api.Collection, error)
+	SetCollectionOrganizer(ctx context.Context, id primitive.ObjectID, organizer int64) error
+	SaveContributions(ctx context.Context, cs []api.Contribution) error
+	FindContributionById(ctx context.Context, id string) (*api.Contribution, error)
+	FindContributionsByUserId(ctx context.Context, roomId string, userId int64) (*[]api.Contribution, error)
+	FindContributionsByMember(ctx context.Context, userId int64) (*[]api.Contribution, error)
+	FindContributionsByRoomId(ctx context.Context, roomId string) (*[]api.Contribution, error)
+	FindContributionsByCollectionId(ctx context.Context, collectionId primitive.ObjectID) (*[]api.Contribution, error)
+	FindRefundsByRecipient(ctx context.Context, roomId string, recipientId int64) (*[]api.Contribution, error)
+	FindContributionsByStatus(ctx context.Context, collectionIds []primitive.ObjectID, status api.ContributionStatus) (*[]api.Contribution, error)
+	SetContributionPaid(ctx context.Context, id primitive.ObjectID, receipt *api.Receipt, t time.Time) error
+	SetContributionReviewed(ctx context.Context, id primitive.ObjectID, status api.ContributionStatus, reviewer int64, t time.Time) error
+	FindCollectionsWithDeadline(ctx context.Context) (*[]api.Collection, error)
+	FindDebtsByCollectionIds(ctx context.Context, collectionIds []primitive.ObjectID) (*[]api.Contribution, error)
+	SetCollectionDeadline(ctx context.Context, id primitive.ObjectID, deadline *time.Time) error
+	SetCollectionDigestAt(ctx context.Context, id primitive.ObjectID, t time.Time) error
+	SetContributionReminded(ctx context.Context, id primitive.ObjectID, t time.Time) error
+	SetContributionSnoozed(ctx context.Context, id primitive.ObjectID, until time.Time) error
+	ResetContributionPayment(ctx context.Context, id primitive.ObjectID) error
+	SetContributionAmount(ctx context.Context, id primitive.ObjectID, amount api.Money) error
+	SetCollectionAmount(ctx context.Context, id primitive.ObjectID, amount api.Money) error
+	SetCollectionExpense(ctx context.Context, id primitive.ObjectID, expense api.Money, t time.Time) error
+	SaveOperations(ctx context.Context, ops ...api.Operation) error
+	FindOperationById(ctx context.Context, id string) (*api.Operation, error)
+	FindOperationsByRoomId(ctx context.Context, roomId string) (*[]api.Operation, error)
+	FindOperationsByMember(ctx context.Context, userId int64) (*[]api.Operation, error)
+	IsOperationReverted(ctx context.Context, id primitive.ObjectID) (bool, error)
+	FindActiveCollectionsByCelebrant(ctx context.Context, celebrantId int64) (*[]api.Collection, error)
+	SetCollectionJoint(ctx context.Context, id primitive.ObjectID, jointId primitive.ObjectID) error
+	MoveContributions(ctx context.Context, ids []primitive.ObjectID, collectionId primitive.ObjectID) error
+	DeleteContributions(ctx context.Context, ids []primitive.ObjectID) error
+	SaveAssignment(ctx context.Context, a *api.Assignment) (primitive.ObjectID, error)
+	FindAssignmentById(ctx context.Context, id string) (*api.Assignment, error)
+	FindAssignmentsByRoomId(ctx context.Context, roomId string) (*[]api.Assignment, error)
+	FindAssignmentsByMember(ctx context.Context, userId int64) (*[]api.Assignment, error)
+	UpdateAssignment(ctx context.Context, a *api.Assignment) error
+	AnonymizeUser(ctx context.Context, userId int64, name string) error
+}
+
+func (r MongoCollectionRepository) SaveCollection(ctx context.Context, c *api.Collection) (primitive.ObjectID, error) {
+	res, err := r.col.InsertOne(ctx, c)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return res.InsertedID.(primitive.ObjectID), nil
+}
+
+func (r MongoCollectionRepository) FindCollectionById(ctx context.Context, id string) (*api.Collection, error) {
+	hex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	res := r.col.FindOne(ctx, bson.M{"_id": hex})
+	if res.Err() != nil {
+		return nil, res.Err()
+	}
+	c := &api.Collection{}
+	if err := res.Decode(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r MongoCollectionRepository) FindCollectionsByRoomId(ctx context.Context, roomId string) (*[]api.Collection, error) {
+	cur, err := r.col.Find(ctx, bson.M{"room_id": roomId}, getOrderOptions("create_at", -1))
+	if err != nil {
+		return nil, err
+	}
+	var m []api.Collection
+	if err = cur.All(ctx, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r MongoCollectionRepository) FindCollectionsByOrganizer(ctx context.Context, userId int64) (*[]api.Collection, error) {
+	cur, err := r.col.Find(ctx, bson.M{"organizer": userId}, getOrderOptions("create_at", 1))
+	if err != nil {
+		return nil, err
+	}
+	var m []api.Collection
+	if err = cur.All(ctx, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r MongoCollectionRepository) SetCollectionOrganizer(ctx context.Context, id primitive.ObjectID, organizer int64) error {
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"organizer": organizer}})
+	return err
+}
+
+func (r MongoCollectionRepository) SaveContributions(ctx context.Context, cs []api.Contribution) error {
+	if len(cs) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, 0, len(cs))
+	for _, c := range cs {
+		docs = append(docs, c)
+	}
+	_, err := r.contributions.InsertMany(ctx, docs)
+	return err
+}
+
+func (r MongoCollectionRepository) FindContributionById(ctx context.Context, id string) (*api.Contribution, error) {
+	hex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	res := r.contributions.FindOne(ctx, bson.M{"_id": hex})
+	if res.Err() != nil {
+		return nil, res.Err()
+	}
+	c := &api.Contribution{}
+	if err := res.Decode(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (r MongoCollectionRepository) FindContributionsByUserId(ctx context.Context, roomId string, userId int64) (*[]api.Contribution, error) {
+	return r.findContributions(ctx, bson.M{"room_id": roomId, "user._id": userId})
+}
+
+// FindContributionsByMember returns contributions of the user and refunds to the user in every room
+func (r MongoCollectionRepository) FindContributionsByMember(ctx context.Context, userId int64) (*[]api.Contribution, error) {
+	return r.findContributions(ctx, bson.M{"$or": bson.A{bson.M{"user._id": userId}, bson.M{"recipient._id": userId}}})
+}
+
+func (r MongoCollectionRepository) FindContributionsByRoomId(ctx context.Context, roomId string) (*[]api.Contribution, error) {
+	return r.findContributions(ctx, bson.M{"room_id": roomId})
+}
+
+func (r MongoCollectionRepository) FindContributionsByCollectionId(ctx context.Context, collectionId primitive.ObjectID) (*[]api.Contribution, error) {
+	return r.findContributions(ctx, bson.M{"collection_id": collectionId})
+}
+
+func (r MongoCollectionRepository) FindRefundsByRecipient(ctx context.Context, roomId string, recipientId int64) (*[]api.Contribution, error) {
+	return r.findContributions(ctx, bson.M{"room_id": roomId, "kind": api.ContributionRefund, "recipient._id": recipientId})
+}
+
+func (r MongoCollectionRepository) FindContributionsByStatus(ctx context.Context, collectionIds []primitive.ObjectID, status api.ContributionStatus) (*[]api.Contribution, error) {
+	return r.findContributions(ctx, bson.M{"collection_id": bson.M{"$in": collectionIds}, "status": status})
+}
+
+// SetContributionPaid sends the payment for review, the receipt attached earlier is kept when receipt is nil.
+// api.ErrNotPending is returned when the payment is already approved
+func (r MongoCollectionRepository) SetContributionPaid(ctx context.Context, id primitive.ObjectID, receipt *api.Receipt, t time.Time) error {
+	set := bson.M{"status": api.ContributionPending, "paid_at": t}
+	if receipt != nil {
+		set["receipt"] = receipt
+	}
+	res, err := r.contributions.UpdateOne(ctx, bson.M{"_id": id, "status": bson.M{"$ne": api.ContributionApproved}},
+		bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return api.ErrNotPending
+	}
+	return nil
+}
+
+// SetContributionReviewed reviews the pending payment, api.ErrNotPending is returned when it is already reviewed
+func (r MongoCollectionRepository) SetContributionReviewed(ctx context.Context, id primitive.ObjectID, status api.ContributionStatus, reviewer int64, t time.Time) error {
+	res, err := r.contributions.UpdateOne(ctx, bson.M{"_id": id, "status": api.ContributionPending}, bson.M{"$set": bson.M{
+		"status":      status,
+		"reviewed_by": reviewer,
+		"reviewed_at": t,
+	}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return api.ErrNotPending
+	}
+	return nil
+}
+
+func (r MongoCollectionRepository) FindCollectionsWithDeadline(ctx context.Context) (*[]api.Collection, error) {
+	cur, err := r.col.Find(ctx, bson.M{"deadline": bson.M{"$ne": nil}})
+	if err != nil {
+		return nil, err
+	}
+	var m []api.Collection
+	if err = cur.All(ctx, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r MongoCollectionRepository) FindDebtsByCollectionIds(ctx context.Context, collectionIds []primitive.ObjectID) (*[]api.Contribution, error) {
+	return r.findContributions(ctx, bson.M{
+		"collection_id": bson.M{"$in": collectionIds},
+		"status":        bson.M{"$in": bson.A{api.ContributionOutstanding, api.ContributionRejected}},
+	})
+}
+
+func (r MongoCollectionRepository) SetCollectionDeadline(ctx context.Context, id primitive.ObjectID, deadline *time.Time) error {
+	update := bson.M{"$set": bson.M{"deadline": deadline}}
+	if deadline == nil {
+		update = bson.M{"$unset": bson.M{"deadline": ""}}
+	}
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+func (r MongoCollectionRepository) SetCollectionDigestAt(ctx context.Context, id primitive.ObjectID, t time.Time) error {
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"digest_at": t}})
+	return err
+}
+
+func (r MongoCollectionRepository) SetContributionReminded(ctx context.Context, id primitive.ObjectID, t time.Time) error {
+	_, err := r.contributions.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{"reminded_at": t},
+		"$inc": bson.M{"reminders": 1},
+	})
+	return err
+}
+
+func (r MongoCollectionRepository) SetContributionSnoozed(ctx context.Context, id primitive.ObjectID, until time.Time) error {
+	_, err := r.contributions.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"snoozed_until": until}})
+	return err
+}
+
+// ResetContributionPayment returns the contribution to debts, the receipt and the review are removed
+func (r MongoCollectionRepository) ResetContributionPayment(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.contributions.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set":   bson.M{"status": api.ContributionOutstanding},
+		"$unset": bson.M{"receipt": "", "paid_at": "", "reviewed_by": "", "reviewed_at": ""},
+	})
+	return err
+}
+
+func (r MongoCollectionRepository) SetContributionAmount(ctx context.Context, id primitive.ObjectID, amount api.Money) error {
+	_, err := r.contributions.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"amount": amount}})
+	return err
+}
+
+// SetCollectionAmount changes the contribution amount of the collection and of debts, paid contributions keep their amount
+func (r MongoCollectionRepository) SetCollectionAmount(ctx context.Context, id primitive.ObjectID, amount api.Money) error {
+	if _, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"amount": amount}}); err != nil {
+		return err
+	}
+	_, err := r.contributions.UpdateMany(ctx, bson.M{
+		"collection_id": id,
+		"status":        bson.M{"$in": bson.A{api.ContributionOutstanding, api.ContributionRejected}},
+	}, bson.M{"$set": bson.M{"amount": amount}})
+	return err
+}
+
+// SetCollectionExpense records the expense once, api.ErrAlreadySettled is returned when it is already recorded
+func (r MongoCollectionRepository) SetCollectionExpense(ctx context.Context, id primitive.ObjectID, expense api.Money, t time.Time) error {
+	res, err := r.col.UpdateOne(ctx, bson.M{"_id": id, "expense": nil}, bson.M{"$set": bson.M{"expense": expense, "settled_at": t}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return api.ErrAlreadySettled
+	}
+	return nil
+}
+
+// FindActiveCollectionsByCelebrant returns collections for the celebrant in all rooms without the expense,
+// collections merged into joint ones are skipped, the oldest first
+func (r MongoCollectionRepository) FindActiveCollectionsByCelebrant(ctx context.Context, celebrantId int64) (*[]api.Collection, error) {
+	cur, err := r.col.Find(ctx, bson.M{"celebrant._id": celebrantId, "expense": nil, "joint_id": nil}, getOrderOptions("create_at", 1))
+	if err != nil {
+		return nil, err
+	}
+	var m []api.Collection
+	if err = cur.All(ctx, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r MongoCollectionRepository) SetCollectionJoint(ctx context.Context, id primitive.ObjectID, jointId primitive.ObjectID) error {
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"joint_id": jointId}})
+	return err
+}
+
+func (r MongoCollectionRepository) MoveContributions(ctx context.Context, ids []primitive.ObjectID, collectionId primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := r.contributions.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": ids}}, bson.M{"$set": bson.M{"collection_id": collectionId}})
+	return err
+}
+
+func (r MongoCollectionRepository) DeleteContributions(ctx context.Context, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := r.contributions.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	return err
+}
+
+func (r MongoCollectionRepository) SaveOperations(ctx context.Context, ops ...api.Operation) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, 0, len(ops))
+	for _, op := range ops {
+		docs = append(docs, op)
+	}
+	// the unique index of revert_of lets only one of concurrent undo operations in
+	if _, err := r.operations.InsertMany(ctx, docs); isDuplicateKey(err) {
+		return api.ErrAlreadyReverted
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r MongoCollectionRepository) FindOperationById(ctx context.Context, id string) (*api.Operation, error) {
+	hex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	res := r.operations.FindOne(ctx, bson.M{"_id": hex})
+	if res.Err() != nil {
+		return nil, res.Err()
+	}
+	op := &api.Operation{}
+	if err := res.Decode(op); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func (r MongoCollectionRepository) FindOperationsByRoomId(ctx context.Context, roomId string) (*[]api.Operation, error) {
+	cur, err := r.operations.Find(ctx, bson.M{"room_id": roomId}, getOrderOptions("create_at", -1))
+	if err != nil {
+		return nil, err
+	}
+	var m []api.Operation
+	if err = cur.All(ctx, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// FindOperationsByMember returns operations made by the user or with the user in all rooms, the oldest first
+func (r MongoCollectionRepository) FindOperationsByMember(ctx context.Context, userId int64) (*[]api.Operation, error) {
+	cur, err := r.operations.Find(ctx, bson.M{"$or": bson.A{bson.M{"author._id": userId}, bson.M{"user._id": userId}}},
+		getOrderOptions("create_at", 1))
+	if err != nil {
+		return nil, err
+	}
+	var m []api.Operation
+	if err = cur.All(ctx, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r MongoCollectionRepository) IsOperationReverted(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	resp, err := r.operations.CountDocuments(ctx, bson.M{"revert_of": id})
+	return resp > 0, err
+}
+
+func (r MongoCollectionRepository) SaveAssignment(ctx context.Context, a *api.Assignment) (primitive.ObjectID, error) {
+	res, err := r.assignments.InsertOne(ctx, a)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return res.InsertedID.(primitive.ObjectID), nil
+}
+
+func (r MongoCollectionRepository) FindAssignmentById(ctx context.Context, id string) (*api.Assignment, error) {
+	hex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	res := r.assignments.FindOne(ctx, bson.M{"_id": hex})
+	if res.Err() != nil {
+		return nil, res.Err()
+	}
+	a := &api.Assignment{}
+	if err := res.Decode(a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (r MongoCollectionRepository) FindAssignmentsByRoomId(ctx context.Context, roomId string) (*[]api.Assignment, error) {
+	cur, err := r.assignments.Find(ctx, bson.M{"room_id": roomId}, getOrderOptions("create_at", 1))
+	if err != nil {
+		return nil, err
+	}
+	var m []api.Assignment
+	if err = cur.All(ctx, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// FindAssignmentsByMember returns assignments of the user as the organizer or the celebrant in all rooms
+func (r MongoCollectionRepository) FindAssignmentsByMember(ctx context.Context, userId int64) (*[]api.Assignment, error) {
+	cur, err := r.assignments.Find(ctx, bson.M{"$or": bson.A{bson.M{"organizer": userId}, bson.M{"celebrant._id": userId}}},
+		getOrderOptions("create_at", 1))
+	if err != nil {
+		return nil, err
+	}
+	var m []api.Assignment
+	if err = cur.All(ctx, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r MongoCollectionRepository) UpdateAssignment(ctx context.Context, a *api.Assignment) error {
+	_, err := r.assignments.UpdateOne(ctx, bson.M{"_id": a.ID}, bson.M{"$set": bson.M{
+		"organizer":     a.Organizer,
+		"status":        a.Status,
+		"declined":      a.Declined,
+		"collection_id": a.CollectionId,
+	}})
+	return err
+}
+
+func (r MongoCollectionRepository) findContributions(ctx context.Context, filter bson.M) (*[]api.Contribution, error) {
+	cur, err := r.contributions.Find(ctx, filter, getOrderOptions("create_at", 1))
+	if err != nil {
+		return nil, err
+	}
+	var m []api.Contribution
+	if err = cur.All(ctx, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// AnonymizeUser replaces the name and removes personal fields of the user in collections, contributions, operations
+// and assignments. Ids and amounts are kept, so debts and balances of other members stay the same
+func (r MongoCollectionRepository) AnonymizeUser(ctx context.Context, userId int64, name string) error {
+	fields := map[*mongo.Collection][]string{
+		r.col:           {"celebrant"},
+		r.contributions: {"user", "recipient"},
+		r.operations:    {"author", "user"},
+		r.assignments:   {"celebrant"},
+	}
+	for col, prefixes := range fields {
+		for _, p := range prefixes {
+			update := bson.M{
+				"$set": bson.M{p + ".display_name": name, p + ".user_name": ""},
+				"$unset": bson.M{p + ".birt_date": "", p + ".payment_details": "", p + ".time_zone": "",
+					p + ".user_lang": "", p + ".selected_lang": ""},
+			}
+			if _, err := col.UpdateMany(ctx, bson.M{p + "._id": userId}, update); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
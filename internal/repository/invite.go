@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"github.com/almaznur91/splitty/internal/api"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MongoInviteRepository struct {
+	col *mongo.Collection
+}
+
+func NewInviteRepository(col *mongo.Database) *MongoInviteRepository {
+	return &MongoInviteRepository{col: col.Collection("invite")}
+}
+
+type InviteRepository interface {
+	SaveInvite(ctx context.Context, i *api.Invite) (primitive.ObjectID, error)
+	FindInviteById(ctx context.Context, id string) (*api.Invite, error)
+	FindInviteByCode(ctx context.Context, code string) (*api.Invite, error)
+	FindInvitesByRoomId(ctx context.Context, roomId string) (*[]api.Invite, error)
+	UseInvite(ctx context.Context, id primitive.ObjectID) (bool, error)
+	RevokeInvite(ctx context.Context, id primitive.ObjectID) error
+}
+
+func (r MongoInviteRepository) SaveInvite(ctx context.Context, i *api.Invite) (primitive.ObjectID, error) {
+	res, err := r.col.InsertOne(ctx, i)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return res.InsertedID.(primitive.ObjectID), nil
+}
+
+func (r MongoInviteRepository) FindInviteById(ctx context.Context, id string) (*api.Invite, error) {
+	hex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	return r.findOne(ctx, bson.M{"_id": hex})
+}
+
+func (r MongoInviteRepository) FindInviteByCode(ctx context.Context, code string) (*api.Invite, error) {
+	return r.findOne(ctx, bson.M{"code": code})
+}
+
+// FindInvitesByRoomId returns invites of the room which are not revoked, the newest first
+func (r MongoInviteRepository) FindInvitesByRoomId(ctx context.Context, roomId string) (*[]api.Invite, error) {
+	cur, err := r.col.Find(ctx, bson.M{"room_id": roomId, "revoked": false}, getOrderOptions("create_at", descParameter))
+	if err != nil {
+		return nil, err
+	}
+	var m []api.Invite
+	if err = cur.All(ctx, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// UseInvite counts the use of the invite, false is returned when uses are over, so parallel joins can't exceed the limit
+func (r MongoInviteRepository) UseInvite(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	res, err := r.col.UpdateOne(ctx, bson.M{
+		"_id":     id,
+		"revoked": false,
+		"$or": bson.A{
+			bson.M{"max_uses": 0},
+			bson.M{"$expr": bson.M{"$lt": bson.A{"$uses", "$max_uses"}}},
+		},
+	}, bson.M{"$inc": bson.M{"uses": 1}})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+func (r MongoInviteRepository) RevokeInvite(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"revoked": true}})
+	return err
+}
+
+func (r MongoInviteRepository) findOne(ctx context.Context, filter bson.M) (*api.Invite, error) {
+	res := r.col.FindOne(ctx, filter)
+	if res.Err() != nil {
+		return nil, res.Err()
+	}
+	i := &api.Invite{}
+	if err := res.Decode(i); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
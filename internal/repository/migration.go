@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"context"
+	"github.com/almaznur91/splitty/internal/api"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// mongo errors of dropping the index which doesn't exist
+const (
+	namespaceNotFoundCode = 26
+	indexNotFoundCode     = 27
+)
+
+// buttonTTL is how long buttons of sent messages keep working, older ones are removed by mongo
+const buttonTTL = 90 * 24 * time.Hour
+
+// Migration is a versioned step of the database schema. Steps must be idempotent,
+// a step interrupted before it is recorded runs again on the next start
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+}
+
+type migrationRecord struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+// migrations must be appended only, versions of applied steps are kept in the migrations collection
+var migrations = []Migration{
+	{1, "chat state by user", func(ctx context.Context, db *mongo.Database) error {
+		return createIndexes(ctx, db.Collection("chat_state"), index(bson.D{{"user_id", ascParameter}}))
+	}},
+	{2, "rooms by members and chats", func(ctx context.Context, db *mongo.Database) error {
+		return createIndexes(ctx, db.Collection("room"),
+			index(bson.D{{"users._id", ascParameter}, {"search_name", ascParameter}}),
+			index(bson.D{{"chat.id", ascParameter}}))
+	}},
+	{3, "button ttl", func(ctx context.Context, db *mongo.Database) error {
+		return createIndexes(ctx, db.Collection("button"), mongo.IndexModel{
+			Keys:    bson.D{{"create_at", ascParameter}},
+			Options: options.Index().SetExpireAfterSeconds(int32(buttonTTL.Seconds())),
+		})
+	}},
+	{4, "collections, contributions and operations", func(ctx context.Context, db *mongo.Database) error {
+		if err := createIndexes(ctx, db.Collection("collection"),
+			index(bson.D{{"room_id", ascParameter}, {"create_at", descParameter}}),
+			index(bson.D{{"celebrant._id", ascParameter}})); err != nil {
+			return err
+		}
+		if err := createIndexes(ctx, db.Collection("contribution"),
+			index(bson.D{{"collection_id", ascParameter}}),
+			index(bson.D{{"room_id", ascParameter}, {"user._id", ascParameter}})); err != nil {
+			return err
+		}
+		if err := createIndexes(ctx, db.Collection("operation"),
+			index(bson.D{{"room_id", ascParameter}, {"create_at", descParameter}}),
+			index(bson.D{{"collection_id", ascParameter}}),
+			index(bson.D{{"revert_of", ascParameter}})); err != nil {
+			return err
+		}
+		return createIndexes(ctx, db.Collection("assignment"), index(bson.D{{"room_id", ascParameter}}))
+	}},
+	{5, "invites by code", func(ctx context.Context, db *mongo.Database) error {
+		return createIndexes(ctx, db.Collection("invite"),
+			mongo.IndexModel{Keys: bson.D{{"code", ascParameter}}, Options: options.Index().SetUnique(true)},
+			index(bson.D{{"room_id", ascParameter}}))
+	}},
+	{6, "notifications on by default", func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection("user").UpdateMany(ctx, bson.M{"notification_on": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"notification_on": true}})
+		return err
+	}},
+	{7, "room owners", func(ctx context.Context, db *mongo.Database) error {
+		return updateRooms(ctx, db, bson.M{"$or": bson.A{bson.M{"owner": bson.M{"$exists": false}}, bson.M{"owner": 0}}},
+			func(r *api.Room) bson.M {
+				if r.Members == nil || len(*r.Members) == 0 {
+					return nil
+				}
+				return bson.M{"owner": (*r.Members)[0].ID}
+			})
+	}},
+	{8, "room search names", func(ctx context.Context, db *mongo.Database) error {
+		return updateRooms(ctx, db, bson.M{"search_name": bson.M{"$exists": false}}, func(r *api.Room) bson.M {
+			return bson.M{"search_name": api.NormalizeSearch(r.Name)}
+		})
+	}},
+	{9, "one compensation per operation", func(ctx context.Context, db *mongo.Database) error {
+		col := db.Collection("operation")
+		// concurrent undo could revert the operation twice, the latter compensations are removed
+		cur, err := col.Aggregate(ctx, mongo.Pipeline{
+			{{"$match", bson.M{"revert_of": bson.M{"$exists": true}}}},
+			{{"$sort", bson.M{"create_at": ascParameter}}},
+			{{"$group", bson.M{"_id": "$revert_of", "ids": bson.M{"$push": "$_id"}, "count": bson.M{"$sum": 1}}}},
+			{{"$match", bson.M{"count": bson.M{"$gt": 1}}}},
+		})
+		if err != nil {
+			return err
+		}
+		var groups []struct {
+			RevertOf primitive.ObjectID   `bson:"_id"`
+			Ids      []primitive.ObjectID `bson:"ids"`
+		}
+		if err = cur.All(ctx, &groups); err != nil {
+			return err
+		}
+		for _, g := range groups {
+			if _, err := col.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": g.Ids[1:]}}); err != nil {
+				return err
+			}
+			log.Warn().Msgf("removed %v extra compensations of operation %v", len(g.Ids)-1, g.RevertOf.Hex())
+		}
+		// the unique index replaces the index of step 4
+		if _, err := col.Indexes().DropOne(ctx, "revert_of_1"); err != nil && !isIndexNotFound(err) {
+			return err
+		}
+		return createIndexes(ctx, col, mongo.IndexModel{
+			Keys: bson.D{{"revert_of", ascParameter}},
+			Options: options.Index().SetName("revert_of_unique").SetUnique(true).
+				SetPartialFilterExpression(bson.M{"revert_of": bson.M{"$exists": true}}),
+		})
+	}},
+	{10, "room search names with й", func(ctx context.Context, db *mongo.Database) error {
+		// search names of step 8 turned й into и
+		return updateRooms(ctx, db, bson.M{}, func(r *api.Room) bson.M {
+			return bson.M{"search_name": api.NormalizeSearch(r.Name)}
+		})
+	}},
+}
+
+type Migrator struct {
+	db         *mongo.Database
+	col        *mongo.Collection
+	migrations []Migration
+}
+
+func NewMigrator(db *mongo.Database) *Migrator {
+	return &Migrator{db: db, col: db.Collection("migrations"), migrations: migrations}
+}
+
+// Migrate applies steps which are not recorded yet in order of versions, returns count of applied steps
+func (m *Migrator) Migrate(ctx context.Context) (int, error) {
+	cur, err := m.col.Find(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	var records []migrationRecord
+	if err = cur.All(ctx, &records); err != nil {
+		return 0, err
+	}
+	applied := make(map[int]bool, len(records))
+	for _, r := range records {
+		applied[r.Version] = true
+	}
+
+	var count int
+	for _, step := range m.migrations {
+		if applied[step.Version] {
+			continue
+		}
+		log.Info().Msgf("apply migration %v %q", step.Version, step.Name)
+		if err := step.Up(ctx, m.db); err != nil {
+			return count, err
+		}
+		if _, err := m.col.InsertOne(ctx, migrationRecord{step.Version, step.Name, time.Now()}); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Version returns the latest applied step, 0 for the database which was never migrated
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	res := m.col.FindOne(ctx, bson.M{}, options.FindOne().SetSort(bson.M{"_id": descParameter}))
+	if res.Err() == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	var r migrationRecord
+	if err := res.Decode(&r); err != nil {
+		return 0, err
+	}
+	return r.Version, nil
+}
+
+// Reset forgets steps newer than the version, e.g. the restored data of the version must get them again.
+// Steps up to the version which were never applied to this database are kept unapplied, they are idempotent
+func (m *Migrator) Reset(ctx context.Context, version int) error {
+	_, err := m.col.DeleteMany(ctx, bson.M{"_id": bson.M{"$gt": version}})
+	return err
+}
+
+// LatestSchema returns the version of the last step known by this build
+func LatestSchema() int {
+	return migrations[len(migrations)-1].Version
+}
+
+func isIndexNotFound(err error) bool {
+	e, ok := err.(mongo.CommandError)
+	return ok && (e.Code == indexNotFoundCode || e.Code == namespaceNotFoundCode)
+}
+
+func index(keys bson.D) mongo.IndexModel {
+	return mongo.IndexModel{Keys: keys}
+}
+
+func createIndexes(ctx context.Context, col *mongo.Collection, models ...mongo.IndexModel) error {
+	_, err := col.Indexes().CreateMany(ctx, models)
+	return err
+}
+
+// updateRooms sets fields made by the function to every room matching the filter, nil fields leave the room as is
+func updateRooms(ctx context.Context, db *mongo.Database, filter bson.M, fields func(r *api.Room) bson.M) error {
+	col := db.Collection("room")
+	cur, err := col.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	var rooms []api.Room
+	if err = cur.All(ctx, &rooms); err != nil {
+		return err
+	}
+	for i := range rooms {
+		set := fields(&rooms[i])
+		if set == nil {
+			continue
+		}
+		if _, err := col.UpdateOne(ctx, bson.M{"_id": rooms[i].ID}, bson.M{"$set": set}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
 )
 
 type UserRepository interface {
@@ -16,7 +17,13 @@ type UserRepository interface {
 	SetUserLang(ctx context.Context, userId int64, lang string) error
 	SetNotificationUser(ctx context.Context, userId int64, notification bool) error
 	SetCountInPage(ctx context.Context, userId int64, count int) error
+	SetBirtDate(ctx context.Context, userId int64, date time.Time) error
+	SetHideYear(ctx context.Context, userId int64, hide bool) error
+	SetTimeZone(ctx context.Context, userId int64, tz string) error
+	SetPaymentField(ctx context.Context, userId int64, field string, value string) error
 	FindById(ctx context.Context, id int64) (*api.User, error)
+	FindByIds(ctx context.Context, ids []int64) (*[]api.User, error)
+	DeleteUser(ctx context.Context, id int64) error
 }
 
 type ChatStateRepository interface {
@@ -31,6 +38,7 @@ type ButtonRepository interface {
 	Save(ctx context.Context, b *api.Button) (primitive.ObjectID, error)
 	SaveAll(ctx context.Context, b ...*api.Button) ([]*api.Button, error)
 	FindById(ctx context.Context, id string) (*api.Button, error)
+	DeleteByUserId(ctx context.Context, userId int64) error
 }
 
 type MongoUserRepository struct {
@@ -74,6 +82,18 @@ func (r MongoUserRepository) FindById(ctx context.Context, id int64) (*api.User,
 	return cs, nil
 }
 
+func (r MongoUserRepository) FindByIds(ctx context.Context, ids []int64) (*[]api.User, error) {
+	cur, err := r.col.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	var m []api.User
+	if err = cur.All(ctx, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
 func (r MongoUserRepository) UpsertUser(ctx context.Context, u api.User) (*api.User, error) {
 	opts := options.Update().SetUpsert(true)
 	f := bson.D{{"_id", bson.D{{"$eq", u.ID}}}}
@@ -118,6 +138,35 @@ func (r MongoUserRepository) SetNotificationUser(ctx context.Context, userId int
 	return nil
 }
 
+func (r MongoUserRepository) SetBirtDate(ctx context.Context, userId int64, date time.Time) error {
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": userId}, bson.M{"$set": bson.M{"birt_date": date}})
+	return err
+}
+
+func (r MongoUserRepository) SetHideYear(ctx context.Context, userId int64, hide bool) error {
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": userId}, bson.M{"$set": bson.M{"hide_year": hide}})
+	return err
+}
+
+func (r MongoUserRepository) SetTimeZone(ctx context.Context, userId int64, tz string) error {
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": userId}, bson.M{"$set": bson.M{"time_zone": tz}})
+	return err
+}
+
+func (r MongoUserRepository) DeleteUser(ctx context.Context, id int64) error {
+	_, err := r.col.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func (r MongoUserRepository) SetPaymentField(ctx context.Context, userId int64, field string, value string) error {
+	update := bson.M{"$set": bson.M{"payment_details." + field: value}}
+	if value == "" {
+		update = bson.M{"$unset": bson.M{"payment_details." + field: ""}}
+	}
+	_, err := r.col.UpdateOne(ctx, bson.M{"_id": userId}, update)
+	return err
+}
+
 func (csr MongoChatStateRepository) Save(ctx context.Context, cs *api.ChatState) error {
 	res, err := csr.col.InsertOne(ctx, cs)
 	if err != nil {
@@ -219,3 +268,33 @@ func (br MongoButtonRepository) FindById(ctx context.Context, id string) (*api.B
 	}
 	return btn, nil
 }
+
+// DeleteByUserId removes buttons made for the user, they keep the user id in the callback data
+func (br MongoButtonRepository) DeleteByUserId(ctx context.Context, userId int64) error {
+	_, err := br.col.DeleteMany(ctx, bson.M{"callback_data.user_id": userId})
+	return err
+}
+
+// duplicateKeyCode is the mongo error of an insert violating a unique index
+const duplicateKeyCode = 11000
+
+// isDuplicateKey checks that the write failed because of a unique index
+func isDuplicateKey(err error) bool {
+	switch e := err.(type) {
+	case mongo.WriteException:
+		for _, we := range e.WriteErrors {
+			if we.Code == duplicateKeyCode {
+				return true
+			}
+		}
+	case mongo.BulkWriteException:
+		for _, we := range e.WriteErrors {
+			if we.Code == duplicateKeyCode {
+				return true
+			}
+		}
+	case mongo.CommandError:
+		return e.Code == duplicateKeyCode
+	}
+	return false
+}
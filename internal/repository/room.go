@@ -9,6 +9,9 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"regexp"
+	"sort"
+	"time"
 )
 
 const descParameter = -1
@@ -32,6 +35,28 @@ type RoomRepository interface {
 	FindRoomsByLikeName(ctx context.Context, userId int64, name string) (*[]api.Room, error)
 	ArchiveRoom(ctx context.Context, userId int64, roomId string) error
 	UnArchiveRoom(ctx context.Context, userId int64, roomId string) error
+	RenameRoom(ctx context.Context, roomId string, name string) error
+	SetOwner(ctx context.Context, roomId string, userId int64) error
+	AddAdmin(ctx context.Context, roomId string, userId int64) error
+	RemoveAdmin(ctx context.Context, roomId string, userId int64) error
+	DeleteRoom(ctx context.Context, roomId string) error
+	FindByChatId(ctx context.Context, chatId int64) (*api.Room, error)
+	FindRoomsToGreet(ctx context.Context) (*[]api.Room, error)
+	BindChat(ctx context.Context, roomId string, chat api.Chat) error
+	UnbindChat(ctx context.Context, chatId int64) error
+	SetGreetedAt(ctx context.Context, roomId string, t time.Time) error
+	SetGreeting(ctx context.Context, roomId string, g *api.Greeting) error
+	SetGreetingDraft(ctx context.Context, roomId string, g *api.Greeting) error
+	SetCurrency(ctx context.Context, roomId string, currency string) error
+	SetLang(ctx context.Context, roomId string, lang string) error
+	SetCollectionRule(ctx context.Context, roomId string, rule *api.CollectionRule) error
+	SetLastOrganizer(ctx context.Context, roomId string, userId int64) error
+	FindRoomsWithCollectionRule(ctx context.Context) (*[]api.Room, error)
+	AddOrganizerOptOut(ctx context.Context, roomId string, userId int64) error
+	RemoveOrganizerOptOut(ctx context.Context, roomId string, userId int64) error
+	AddJoinRequest(ctx context.Context, roomId string, u api.User) error
+	RemoveJoinRequest(ctx context.Context, roomId string, userId int64) error
+	ForgetMember(ctx context.Context, userId int64) error
 }
 
 func (rr MongoRoomRepository) FindById(ctx context.Context, id string) (*api.Room, error) {
@@ -79,6 +104,7 @@ func (rr MongoRoomRepository) LeaveRoom(ctx context.Context, userId int64, roomI
 }
 
 func (rr MongoRoomRepository) SaveRoom(ctx context.Context, r *api.Room) (primitive.ObjectID, error) {
+	r.SearchName = api.NormalizeSearch(r.Name)
 	res, err := rr.col.InsertOne(ctx, r)
 	if err != nil {
 		log.Error().Err(err).Msg("insert failed")
@@ -112,6 +138,164 @@ func (rr MongoRoomRepository) UnArchiveRoom(ctx context.Context, userId int64, r
 	return err
 }
 
+func (rr MongoRoomRepository) RenameRoom(ctx context.Context, roomId string, name string) error {
+	return rr.updateRoom(ctx, roomId, bson.M{"$set": bson.M{"name": name, "search_name": api.NormalizeSearch(name)}})
+}
+
+func (rr MongoRoomRepository) SetOwner(ctx context.Context, roomId string, userId int64) error {
+	return rr.updateRoom(ctx, roomId, bson.M{"$set": bson.M{"owner": userId}})
+}
+
+func (rr MongoRoomRepository) AddAdmin(ctx context.Context, roomId string, userId int64) error {
+	return rr.updateRoom(ctx, roomId, bson.M{"$addToSet": bson.M{"admins": userId}})
+}
+
+func (rr MongoRoomRepository) RemoveAdmin(ctx context.Context, roomId string, userId int64) error {
+	return rr.updateRoom(ctx, roomId, bson.M{"$pull": bson.M{"admins": userId}})
+}
+
+func (rr MongoRoomRepository) DeleteRoom(ctx context.Context, roomId string) error {
+	hex, err := primitive.ObjectIDFromHex(roomId)
+	if err != nil {
+		return err
+	}
+	_, err = rr.col.DeleteOne(ctx, bson.M{"_id": hex})
+	return err
+}
+
+func (rr MongoRoomRepository) FindByChatId(ctx context.Context, chatId int64) (*api.Room, error) {
+	res := rr.col.FindOne(ctx, bson.M{"chat.id": chatId})
+	if res.Err() == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if res.Err() != nil {
+		return nil, res.Err()
+	}
+	rm := &api.Room{}
+	if err := res.Decode(rm); err != nil {
+		return nil, err
+	}
+	return rm, nil
+}
+
+func (rr MongoRoomRepository) FindRoomsToGreet(ctx context.Context) (*[]api.Room, error) {
+	cur, err := rr.col.Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"chat.id": bson.M{"$nin": bson.A{nil, 0}}},
+		bson.M{"greeting.to_private": true},
+	}})
+	if err != nil {
+		return nil, err
+	}
+	var m []api.Room
+	if err = cur.All(ctx, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (rr MongoRoomRepository) BindChat(ctx context.Context, roomId string, chat api.Chat) error {
+	return rr.updateRoom(ctx, roomId, bson.M{"$set": bson.M{"chat": chat}})
+}
+
+func (rr MongoRoomRepository) UnbindChat(ctx context.Context, chatId int64) error {
+	_, err := rr.col.UpdateMany(ctx, bson.M{"chat.id": chatId}, bson.M{"$set": bson.M{"chat": api.Chat{}}})
+	return err
+}
+
+func (rr MongoRoomRepository) SetGreetedAt(ctx context.Context, roomId string, t time.Time) error {
+	return rr.updateRoom(ctx, roomId, bson.M{"$set": bson.M{"greeted_at": t}})
+}
+
+func (rr MongoRoomRepository) SetGreeting(ctx context.Context, roomId string, g *api.Greeting) error {
+	return rr.updateRoom(ctx, roomId, bson.M{"$set": bson.M{"greeting": g}})
+}
+
+func (rr MongoRoomRepository) SetGreetingDraft(ctx context.Context, roomId string, g *api.Greeting) error {
+	return rr.updateRoom(ctx, roomId, bson.M{"$set": bson.M{"greeting_draft": g}})
+}
+
+func (rr MongoRoomRepository) SetCurrency(ctx context.Context, roomId string, currency string) error {
+	return rr.updateRoom(ctx, roomId, bson.M{"$set": bson.M{"currency": currency}})
+}
+
+func (rr MongoRoomRepository) SetLang(ctx context.Context, roomId string, lang string) error {
+	if lang == "" {
+		return rr.updateRoom(ctx, roomId, bson.M{"$unset": bson.M{"lang": ""}})
+	}
+	return rr.updateRoom(ctx, roomId, bson.M{"$set": bson.M{"lang": lang}})
+}
+
+func (rr MongoRoomRepository) SetCollectionRule(ctx context.Context, roomId string, rule *api.CollectionRule) error {
+	return rr.updateRoom(ctx, roomId, bson.M{"$set": bson.M{"collection_rule": rule}})
+}
+
+func (rr MongoRoomRepository) SetLastOrganizer(ctx context.Context, roomId string, userId int64) error {
+	return rr.updateRoom(ctx, roomId, bson.M{"$set": bson.M{"collection_rule.last_organizer": userId}})
+}
+
+func (rr MongoRoomRepository) FindRoomsWithCollectionRule(ctx context.Context) (*[]api.Room, error) {
+	cur, err := rr.col.Find(ctx, bson.M{"collection_rule.enabled": true})
+	if err != nil {
+		return nil, err
+	}
+	var m []api.Room
+	if err = cur.All(ctx, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (rr MongoRoomRepository) AddOrganizerOptOut(ctx context.Context, roomId string, userId int64) error {
+	return rr.updateRoom(ctx, roomId, bson.M{"$addToSet": bson.M{"organizer_opt_outs": userId}})
+}
+
+func (rr MongoRoomRepository) RemoveOrganizerOptOut(ctx context.Context, roomId string, userId int64) error {
+	return rr.updateRoom(ctx, roomId, bson.M{"$pull": bson.M{"organizer_opt_outs": userId}})
+}
+
+func (rr MongoRoomRepository) AddJoinRequest(ctx context.Context, roomId string, u api.User) error {
+	if err := rr.RemoveJoinRequest(ctx, roomId, u.ID); err != nil {
+		return err
+	}
+	return rr.updateRoom(ctx, roomId, bson.M{"$push": bson.M{"join_requests": u}})
+}
+
+func (rr MongoRoomRepository) RemoveJoinRequest(ctx context.Context, roomId string, userId int64) error {
+	return rr.updateRoom(ctx, roomId, bson.M{"$pull": bson.M{"join_requests": bson.M{"_id": userId}}})
+}
+
+// ForgetMember removes the user from members, admins, join requests and settings of every room
+func (rr MongoRoomRepository) ForgetMember(ctx context.Context, userId int64) error {
+	_, err := rr.col.UpdateMany(ctx, bson.M{"$or": bson.A{
+		bson.M{"users._id": userId},
+		bson.M{"join_requests._id": userId},
+		bson.M{"admins": userId},
+		bson.M{"organizer_opt_outs": userId},
+	}}, bson.M{"$pull": bson.M{
+		"users":                bson.M{"_id": userId},
+		"join_requests":        bson.M{"_id": userId},
+		"admins":               userId,
+		"organizer_opt_outs":   userId,
+		"room_states.archived": userId,
+	}})
+	if err != nil {
+		return err
+	}
+	// the rule without the fixed organizer assigns organizers by the rotation
+	_, err = rr.col.UpdateMany(ctx, bson.M{"collection_rule.organizer": userId},
+		bson.M{"$unset": bson.M{"collection_rule.organizer": ""}})
+	return err
+}
+
+func (rr MongoRoomRepository) updateRoom(ctx context.Context, roomId string, update bson.M) error {
+	hex, err := primitive.ObjectIDFromHex(roomId)
+	if err != nil {
+		return err
+	}
+	_, err = rr.col.UpdateOne(ctx, bson.M{"_id": hex}, update)
+	return err
+}
+
 func (rr MongoRoomRepository) hasRoom(ctx context.Context, u *api.User) (bool, error) {
 	resp, err := rr.col.CountDocuments(ctx, bson.D{{"_id", bson.D{{"$eq", u.ID}}}})
 	return resp > 0, err
@@ -155,10 +339,13 @@ func (rr MongoRoomRepository) FindArchivedRoomsByUserId(ctx context.Context, use
 	return &m, nil
 }
 
+// FindRoomsByLikeName searches not archived rooms of the user ignoring case and diacritics,
+// names starting with the query go first, then the newest rooms
 func (rr MongoRoomRepository) FindRoomsByLikeName(ctx context.Context, userId int64, name string) (*[]api.Room, error) {
+	query := api.NormalizeSearch(name)
 	cur, err := rr.col.Find(ctx, bson.M{
 		"users":                bson.M{"$elemMatch": bson.M{"_id": userId}},
-		"name":                 bson.M{"$regex": ".*" + name + ".*"},
+		"search_name":          bson.M{"$regex": regexp.QuoteMeta(query)},
 		"room_states.archived": bson.M{"$ne": userId},
 	}, getOrderOptions("create_at", descParameter))
 	if err != nil {
@@ -168,6 +355,9 @@ func (rr MongoRoomRepository) FindRoomsByLikeName(ctx context.Context, userId in
 	if err = cur.All(ctx, &m); err != nil {
 		return nil, err
 	}
+	sort.SliceStable(m, func(i, j int) bool {
+		return api.SearchRank(m[i].SearchName, query) < api.SearchRank(m[j].SearchName, query)
+	})
 	return &m, nil
 }
 
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// txRetries is how many times the transaction runs again after a transient error of the server
+const txRetries = 3
+
+const (
+	transientTxError    = "TransientTransactionError"
+	unknownCommitResult = "UnknownTransactionCommitResult"
+)
+
+// UnitOfWork runs changes of several documents as a whole. Repository calls made with the context passed to the
+// function are either all applied or none of them. The function may run more than once, so it must not have
+// side effects outside the database
+type UnitOfWork interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// MongoUnitOfWork runs the function in a mongo transaction, transactions need a replica set
+type MongoUnitOfWork struct {
+	client *mongo.Client
+}
+
+// NoopUnitOfWork runs the function as is, for the standalone mongo which doesn't support transactions
+type NoopUnitOfWork struct{}
+
+func NewUnitOfWork(db *mongo.Database) *MongoUnitOfWork {
+	return &MongoUnitOfWork{client: db.Client()}
+}
+
+func (u *MongoUnitOfWork) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	// the nested call joins the transaction which is already started
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+	session, err := u.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	for i := 1; ; i++ {
+		err = mongo.WithSession(ctx, session, func(sc mongo.SessionContext) error {
+			if err := sc.StartTransaction(); err != nil {
+				return err
+			}
+			if err := fn(sc); err != nil {
+				_ = sc.AbortTransaction(sc)
+				return err
+			}
+			return commit(sc)
+		})
+		if err == nil || i == txRetries || !hasErrorLabel(err, transientTxError) {
+			return err
+		}
+		log.Warn().Err(err).Msgf("retry transaction, attempt %v", i+1)
+	}
+}
+
+// commit retries the commit which result is unknown, the server applies it only once
+func commit(sc mongo.SessionContext) error {
+	for i := 1; ; i++ {
+		err := sc.CommitTransaction(sc)
+		if err == nil || i == txRetries || !hasErrorLabel(err, unknownCommitResult) {
+			return err
+		}
+	}
+}
+
+func hasErrorLabel(err error, label string) bool {
+	var le interface{ HasErrorLabel(string) bool }
+	return errors.As(err, &le) && le.HasErrorLabel(label)
+}
+
+func (NoopUnitOfWork) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"github.com/almaznur91/splitty/internal/api"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// AssignOrganizer assigns the organizer of the collection for the upcoming birthday by the rotation of the room.
+// Returns nil if the rule has the fixed organizer or the birthday is already assigned
+func (cs *CollectionService) AssignOrganizer(ctx context.Context, roomId string, celebrantId int64, birthday time.Time) (*api.Assignment, error) {
+	room, err := cs.rr.FindById(ctx, roomId)
+	if err != nil {
+		return nil, err
+	}
+	if room.CollectionRule == nil || !room.CollectionRule.Enabled || !room.IsMember(celebrantId) || !rotates(room, celebrantId) {
+		return nil, nil
+	}
+	assignments, err := cs.CollectionRepository.FindAssignmentsByRoomId(ctx, roomId)
+	if err != nil {
+		return nil, err
+	}
+	if findAssignment(assignments, celebrantId, birthday) != nil {
+		return nil, nil
+	}
+	return cs.assign(ctx, room, *room.FindMember(celebrantId), birthday, assignments)
+}
+
+// AcceptAssignment confirms the assignment, allowed for the assigned organizer
+func (cs *CollectionService) AcceptAssignment(ctx context.Context, userId int64, assignmentId string) (*api.Assignment, error) {
+	a, err := cs.findOwnAssignment(ctx, userId, assignmentId)
+	if err != nil {
+		return nil, err
+	}
+	a.Status = api.AssignmentAccepted
+	return a, cs.CollectionRepository.UpdateAssignment(ctx, a)
+}
+
+// DeclineAssignment moves the assignment to the next candidate, allowed for the assigned organizer until the collection is opened.
+// The assignment becomes unassigned when nobody is left
+func (cs *CollectionService) DeclineAssignment(ctx context.Context, userId int64, assignmentId string) (*api.Assignment, error) {
+	a, err := cs.findOwnAssignment(ctx, userId, assignmentId)
+	if err != nil {
+		return nil, err
+	}
+	if a.CollectionId != nil {
+		return nil, api.ErrAlreadyOpened
+	}
+	room, err := cs.rr.FindById(ctx, a.RoomId)
+	if err != nil {
+		return nil, err
+	}
+	assignments, err := cs.CollectionRepository.FindAssignmentsByRoomId(ctx, a.RoomId)
+	if err != nil {
+		return nil, err
+	}
+
+	a.Declined = append(a.Declined, userId)
+	err = cs.uow.WithTx(ctx, func(ctx context.Context) error {
+		if err := cs.pickOrganizer(ctx, room, a, assignments); err != nil {
+			return err
+		}
+		return cs.CollectionRepository.UpdateAssignment(ctx, a)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// HandOver passes collections organized by the user leaving the room to other members by the rotation and
+// open assignments of the user as well. The fallback organizer takes collections nobody else can organize
+func (cs *CollectionService) HandOver(ctx context.Context, room *api.Room, userId int64, fallback int64) error {
+	left := *room
+	members := make([]api.User, 0, len(*room.Members))
+	for _, m := range *room.Members {
+		if m.ID != userId {
+			members = append(members, m)
+		}
+	}
+	left.Members = &members
+
+	collections, err := cs.CollectionRepository.FindCollectionsByRoomId(ctx, room.ID.Hex())
+	if err != nil {
+		return err
+	}
+	for id, organizer := range chooseSuccessors(&left, *collections, userId, fallback) {
+		if err := cs.CollectionRepository.SetCollectionOrganizer(ctx, id, organizer); err != nil {
+			return err
+		}
+	}
+
+	assignments, err := cs.CollectionRepository.FindAssignmentsByRoomId(ctx, room.ID.Hex())
+	if err != nil {
+		return err
+	}
+	for i := range *assignments {
+		a := &(*assignments)[i]
+		if a.Organizer != userId || a.CollectionId != nil {
+			continue
+		}
+		if left.CollectionRule == nil {
+			a.Organizer, a.Status = 0, api.AssignmentUnassigned
+		} else if err := cs.pickOrganizer(ctx, &left, a, assignments); err != nil {
+			return err
+		}
+		if err := cs.CollectionRepository.UpdateAssignment(ctx, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cs *CollectionService) assign(ctx context.Context, room *api.Room, celebrant api.User, birthday time.Time, assignments *[]api.Assignment) (*api.Assignment, error) {
+	a := &api.Assignment{RoomId: room.ID.Hex(), Celebrant: celebrant, Birthday: birthday, CreateAt: time.Now()}
+	err := cs.uow.WithTx(ctx, func(ctx context.Context) (err error) {
+		if err := cs.pickOrganizer(ctx, room, a, assignments); err != nil {
+			return err
+		}
+		a.ID, err = cs.CollectionRepository.SaveAssignment(ctx, a)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// pickOrganizer assigns the candidate with the least count of organized and assigned collections,
+// the rotation order after the last organizer breaks ties
+func (cs *CollectionService) pickOrganizer(ctx context.Context, room *api.Room, a *api.Assignment, assignments *[]api.Assignment) error {
+	collections, err := cs.CollectionRepository.FindCollectionsByRoomId(ctx, a.RoomId)
+	if err != nil {
+		return err
+	}
+	load := make(map[int64]int)
+	for _, c := range *collections {
+		load[c.Organizer]++
+	}
+	for _, other := range *assignments {
+		if other.ID != a.ID && other.CollectionId == nil && other.Organizer != 0 {
+			load[other.Organizer]++
+		}
+	}
+
+	a.Organizer, a.Status = chooseOrganizer(room, a, load), api.AssignmentPending
+	if a.Organizer == 0 {
+		a.Status = api.AssignmentUnassigned
+		return nil
+	}
+	return cs.rr.SetLastOrganizer(ctx, a.RoomId, a.Organizer)
+}
+
+func (cs *CollectionService) findOwnAssignment(ctx context.Context, userId int64, assignmentId string) (*api.Assignment, error) {
+	a, err := cs.CollectionRepository.FindAssignmentById(ctx, assignmentId)
+	if err != nil {
+		return nil, err
+	}
+	if a.Organizer != userId {
+		return nil, api.ErrForbidden
+	}
+	return a, nil
+}
+
+// chooseOrganizer never chooses the celebrant, members who opted out or declined the assignment
+func chooseOrganizer(room *api.Room, a *api.Assignment, load map[int64]int) int64 {
+	members := *room.Members
+	start := 0
+	for i, m := range members {
+		if m.ID == room.CollectionRule.LastOrganizer {
+			start = i + 1
+		}
+	}
+
+	var organizer int64
+	min := -1
+	for i := range members {
+		m := members[(start+i)%len(members)]
+		if m.ID == a.Celebrant.ID || room.IsOptedOut(m.ID) || containsId(a.Declined, m.ID) {
+			continue
+		}
+		if min < 0 || load[m.ID] < min {
+			organizer, min = m.ID, load[m.ID]
+		}
+	}
+	return organizer
+}
+
+// chooseSuccessors chooses organizers of collections organized by the user among the room members left,
+// the celebrant and members who opted out are skipped like in the rotation
+func chooseSuccessors(room *api.Room, collections []api.Collection, userId int64, fallback int64) map[primitive.ObjectID]int64 {
+	rotation := *room
+	if rotation.CollectionRule == nil {
+		rotation.CollectionRule = &api.CollectionRule{}
+	}
+	load := make(map[int64]int)
+	for _, c := range collections {
+		load[c.Organizer]++
+	}
+
+	successors := make(map[primitive.ObjectID]int64)
+	for _, c := range collections {
+		if c.Organizer != userId {
+			continue
+		}
+		a := &api.Assignment{}
+		if c.Celebrant != nil {
+			a.Celebrant = *c.Celebrant
+		}
+		organizer := chooseOrganizer(&rotation, a, load)
+		if organizer == 0 {
+			organizer = fallback
+		}
+		load[organizer]++
+		successors[c.ID] = organizer
+	}
+	return successors
+}
+
+// rotates checks that organizers are assigned by the rotation, the fixed organizer doesn't organize own birthday
+func rotates(room *api.Room, celebrantId int64) bool {
+	rule := room.CollectionRule
+	return rule.Organizer == 0 || rule.Organizer == celebrantId || !room.IsMember(rule.Organizer)
+}
+
+func findAssignment(assignments *[]api.Assignment, celebrantId int64, birthday time.Time) *api.Assignment {
+	for i := range *assignments {
+		if a := &(*assignments)[i]; a.Celebrant.ID == celebrantId && a.Birthday.Equal(birthday) {
+			return a
+		}
+	}
+	return nil
+}
+
+func containsId(ids []int64, id int64) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"github.com/almaznur91/splitty/internal/api"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+func TestChooseSuccessors(t *testing.T) {
+	members := func(ids ...int64) *[]api.User {
+		users := make([]api.User, 0, len(ids))
+		for _, id := range ids {
+			users = append(users, api.User{ID: id})
+		}
+		return &users
+	}
+	collection := func(organizer int64, celebrant int64) api.Collection {
+		return api.Collection{ID: primitive.NewObjectID(), Organizer: organizer, Celebrant: &api.User{ID: celebrant}}
+	}
+	const leaving, owner = 9, 1
+
+	tests := []struct {
+		name        string
+		room        api.Room
+		collections []api.Collection
+		want        []int64
+	}{
+		{name: "skips the celebrant", room: api.Room{Members: members(owner, 2, 3)},
+			collections: []api.Collection{collection(leaving, owner)}, want: []int64{2}},
+		{name: "skips opted out members", room: api.Room{Members: members(owner, 2, 3), OrganizerOptOuts: []int64{2}},
+			collections: []api.Collection{collection(leaving, owner)}, want: []int64{3}},
+		{name: "least loaded member", room: api.Room{Members: members(owner, 2, 3)},
+			collections: []api.Collection{collection(leaving, 3), collection(2, owner)}, want: []int64{owner}},
+		{name: "spreads several collections", room: api.Room{Members: members(owner, 2, 3)},
+			collections: []api.Collection{collection(leaving, owner), collection(leaving, owner)}, want: []int64{2, 3}},
+		{name: "rotation after the last organizer", room: api.Room{Members: members(owner, 2, 3),
+			CollectionRule: &api.CollectionRule{LastOrganizer: 2}},
+			collections: []api.Collection{collection(leaving, 4)}, want: []int64{3}},
+		{name: "owner when nobody is eligible", room: api.Room{Members: members(owner, 2), OrganizerOptOuts: []int64{2}},
+			collections: []api.Collection{collection(leaving, owner)}, want: []int64{owner}},
+		{name: "other organizers stay", room: api.Room{Members: members(owner, 2)},
+			collections: []api.Collection{collection(2, owner)}, want: []int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := chooseSuccessors(&tt.room, tt.collections, leaving, owner)
+			var organized []api.Collection
+			for _, c := range tt.collections {
+				if c.Organizer == leaving {
+					organized = append(organized, c)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("chooseSuccessors() = %v, want organizers %v", got, tt.want)
+			}
+			for i, c := range organized {
+				if got[c.ID] != tt.want[i] {
+					t.Errorf("successor of collection %v = %v, want %v", i, got[c.ID], tt.want[i])
+				}
+			}
+		})
+	}
+}
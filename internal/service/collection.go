@@ -0,0 +1,499 @@
+package service
+
+import (
+	"context"
+	"github.com/almaznur91/splitty/internal/api"
+	"github.com/almaznur91/splitty/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"time"
+)
+
+type CollectionService struct {
+	repository.CollectionRepository
+	rr  repository.RoomRepository
+	uow repository.UnitOfWork
+}
+
+func NewCollectionService(r repository.CollectionRepository, rr repository.RoomRepository, uow repository.UnitOfWork) *CollectionService {
+	return &CollectionService{r, rr, uow}
+}
+
+// CreateCollection starts collecting money for the celebrant, allowed for admins.
+// Every member except the celebrant and the organizer gets a contribution
+func (cs *CollectionService) CreateCollection(ctx context.Context, userId int64, roomId string, celebrantId int64, amount api.Money) (*api.Collection, error) {
+	room, err := cs.rr.FindById(ctx, roomId)
+	if err != nil {
+		return nil, err
+	}
+	if !room.IsAdmin(userId) {
+		return nil, api.ErrForbidden
+	}
+	if amount.Currency != room.CurrencyCode() {
+		return nil, api.ErrCurrencyMismatch
+	}
+	return cs.createCollection(ctx, room, userId, celebrantId, amount, nil)
+}
+
+// OpenBirthdayCollection opens the collection for the birthday by the rule of the room, the organizer is fixed
+// or assigned by the rotation. Returns nil if the collection for the celebrant is already opened or nobody organizes it
+func (cs *CollectionService) OpenBirthdayCollection(ctx context.Context, roomId string, celebrantId int64, birthday time.Time) (*api.Collection, error) {
+	room, err := cs.rr.FindById(ctx, roomId)
+	if err != nil {
+		return nil, err
+	}
+	rule := room.CollectionRule
+	// the amount of the rule is kept in the currency the room had when it was set
+	if rule == nil || !rule.Enabled || rule.Amount.Currency != room.CurrencyCode() || !room.IsMember(celebrantId) {
+		return nil, nil
+	}
+
+	collections, err := cs.CollectionRepository.FindCollectionsByRoomId(ctx, roomId)
+	if err != nil {
+		return nil, err
+	}
+	// the collection opened by hand a month before the rule counts too
+	opened := birthday.AddDate(0, -1, -rule.DaysBefore)
+	for _, c := range *collections {
+		if c.Celebrant == nil || c.Celebrant.ID != celebrantId {
+			continue
+		}
+		if c.Birthday != nil && c.Birthday.Equal(birthday) || c.CreateAt.After(opened) {
+			return nil, nil
+		}
+	}
+
+	organizer := rule.Organizer
+	var assignment *api.Assignment
+	if rotates(room, celebrantId) {
+		assignments, err := cs.CollectionRepository.FindAssignmentsByRoomId(ctx, roomId)
+		if err != nil {
+			return nil, err
+		}
+		// the scheduler assigns organizers in advance, the rule enabled just now has no assignment yet
+		if assignment = findAssignment(assignments, celebrantId, birthday); assignment == nil {
+			if assignment, err = cs.assign(ctx, room, *room.FindMember(celebrantId), birthday, assignments); err != nil {
+				return nil, err
+			}
+		}
+		organizer = assignment.Organizer
+	}
+	if organizer == 0 {
+		return nil, nil
+	}
+
+	var c *api.Collection
+	// the assignment is linked to the collection in the same transaction, so the scheduler doesn't open it twice
+	err = cs.uow.WithTx(ctx, func(ctx context.Context) (err error) {
+		if c, err = cs.createCollection(ctx, room, organizer, celebrantId, rule.Amount, &birthday); err != nil || assignment == nil {
+			return err
+		}
+		assignment.CollectionId = &c.ID
+		return cs.CollectionRepository.UpdateAssignment(ctx, assignment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// createCollection saves the collection with contributions of every member except the celebrant and the organizer.
+// The collection opened for the birthday should be paid before it
+func (cs *CollectionService) createCollection(ctx context.Context, room *api.Room, organizerId int64, celebrantId int64, amount api.Money, birthday *time.Time) (*api.Collection, error) {
+	roomId := room.ID.Hex()
+	c := &api.Collection{RoomId: roomId, Organizer: organizerId, Amount: amount, Birthday: birthday, Deadline: birthday, CreateAt: time.Now()}
+	for _, m := range *room.Members {
+		if m.ID == celebrantId {
+			m := m
+			c.Celebrant = &m
+		}
+	}
+	err := cs.uow.WithTx(ctx, func(ctx context.Context) (err error) {
+		if c.ID, err = cs.CollectionRepository.SaveCollection(ctx, c); err != nil {
+			return err
+		}
+
+		var contributions []api.Contribution
+		for _, m := range *room.Members {
+			if m.ID == celebrantId || m.ID == organizerId {
+				continue
+			}
+			contributions = append(contributions, api.Contribution{
+				CollectionId: c.ID,
+				RoomId:       roomId,
+				User:         m,
+				Amount:       amount,
+				Status:       api.ContributionOutstanding,
+				CreateAt:     c.CreateAt,
+			})
+		}
+		if err := cs.CollectionRepository.SaveContributions(ctx, contributions); err != nil {
+			return err
+		}
+		return cs.CollectionRepository.SaveOperations(ctx, api.Operation{
+			RoomId:       roomId,
+			CollectionId: c.ID,
+			Type:         api.OperationTarget,
+			Author:       author(room, organizerId),
+			User:         c.Celebrant,
+			Amount:       amount,
+			CreateAt:     c.CreateAt,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// MarkPaid sends the payment of the contributor for review, the receipt is optional
+func (cs *CollectionService) MarkPaid(ctx context.Context, userId int64, contributionId string, receipt *api.Receipt) (*api.Contribution, error) {
+	c, err := cs.CollectionRepository.FindContributionById(ctx, contributionId)
+	if err != nil {
+		return nil, err
+	}
+	if c.User.ID != userId || c.Status == api.ContributionApproved {
+		return nil, api.ErrForbidden
+	}
+	now := time.Now()
+	if err := cs.CollectionRepository.SetContributionPaid(ctx, c.ID, receipt, now); err != nil {
+		return nil, err
+	}
+	c.Status, c.PaidAt = api.ContributionPending, &now
+	if receipt != nil {
+		c.Receipt = receipt
+	}
+	return c, nil
+}
+
+// FindReviewQueue returns payments waiting for review in collections of the organizer and refunds to the user
+func (cs *CollectionService) FindReviewQueue(ctx context.Context, userId int64, roomId string) (*[]api.Contribution, error) {
+	collections, err := cs.CollectionRepository.FindCollectionsByRoomId(ctx, roomId)
+	if err != nil {
+		return nil, err
+	}
+	var ids []primitive.ObjectID
+	for _, c := range *collections {
+		if c.Organizer == userId {
+			ids = append(ids, c.ID)
+		}
+	}
+	queue := make([]api.Contribution, 0)
+	if len(ids) > 0 {
+		pending, err := cs.CollectionRepository.FindContributionsByStatus(ctx, ids, api.ContributionPending)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range *pending {
+			if c.Kind != api.ContributionRefund {
+				queue = append(queue, c)
+			}
+		}
+	}
+
+	refunds, err := cs.CollectionRepository.FindRefundsByRecipient(ctx, roomId, userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range *refunds {
+		if c.Status == api.ContributionPending {
+			queue = append(queue, c)
+		}
+	}
+	return &queue, nil
+}
+
+// ReviewPayment approves or rejects the payment, allowed for the payee: the organizer or the refund recipient.
+// A rejected payment returns to debts of the contributor, an approved one is recorded as an operation
+func (cs *CollectionService) ReviewPayment(ctx context.Context, userId int64, contributionId string, approve bool) (*api.Contribution, error) {
+	c, err := cs.CollectionRepository.FindContributionById(ctx, contributionId)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := cs.CollectionRepository.FindCollectionById(ctx, c.CollectionId.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if c.Payee(collection) != userId {
+		return nil, api.ErrForbidden
+	}
+	if c.Status != api.ContributionPending {
+		return nil, api.ErrNotPending
+	}
+
+	status := api.ContributionRejected
+	if approve {
+		status = api.ContributionApproved
+	}
+	room, err := cs.rr.FindById(ctx, c.RoomId)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	err = cs.uow.WithTx(ctx, func(ctx context.Context) error {
+		if err := cs.CollectionRepository.SetContributionReviewed(ctx, c.ID, status, userId, now); err != nil {
+			return err
+		}
+		if !approve {
+			return nil
+		}
+		op := api.Operation{
+			RoomId:         c.RoomId,
+			CollectionId:   c.CollectionId,
+			ContributionId: &c.ID,
+			Type:           api.OperationContribution,
+			Author:         author(room, userId),
+			User:           &c.User,
+			Amount:         c.Amount,
+			CreateAt:       now,
+		}
+		if c.Kind == api.ContributionRefund {
+			op.Type, op.User = api.OperationRefund, c.Recipient
+		}
+		return cs.CollectionRepository.SaveOperations(ctx, op)
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.Status, c.ReviewedBy, c.ReviewedAt = status, userId, &now
+	return c, nil
+}
+
+// RecordExpense saves what was spent on the gift and settles the difference with shares, allowed for the organizer.
+// The expense is split evenly between the organizer and contributors. A member who paid more than the part gets
+// a refund from the organizer and a member who paid less gets an extra payment debt, payments waiting for review
+// are counted as paid, so a rejected one returns to debts with the settled difference. Unpaid shares are changed
+// to the part, no money is refunded for them
+func (cs *CollectionService) RecordExpense(ctx context.Context, userId int64, collectionId string, spent api.Money, receipt *api.Receipt) (*[]api.Contribution, error) {
+	collection, err := cs.CollectionRepository.FindCollectionById(ctx, collectionId)
+	if err != nil {
+		return nil, err
+	}
+	if collection.Organizer != userId {
+		return nil, api.ErrForbidden
+	}
+	// contributions of the merged collection are moved, the expense is recorded in the joint one
+	if collection.Expense != nil || collection.JointId != nil {
+		return nil, api.ErrAlreadySettled
+	}
+	if spent.Currency != collection.Amount.Currency {
+		return nil, api.ErrCurrencyMismatch
+	}
+	room, err := cs.rr.FindById(ctx, collection.RoomId)
+	if err != nil {
+		return nil, err
+	}
+	contributions, err := cs.CollectionRepository.FindContributionsByCollectionId(ctx, collection.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var shares []api.Contribution
+	for _, c := range *contributions {
+		if c.Kind == api.ContributionShare {
+			shares = append(shares, c)
+		}
+	}
+	// the organizer takes the last part, so greater parts go to contributors
+	parts := spent.Split(len(shares) + 1)
+	organizer := author(room, userId)
+	now := time.Now()
+	settlement := make([]api.Contribution, 0)
+	var unpaid []api.Contribution
+	for i, c := range shares {
+		if c.IsDebt() {
+			if c.Amount.Amount != parts[i].Amount {
+				c.Amount = parts[i]
+				unpaid = append(unpaid, c)
+			}
+			continue
+		}
+		diff := parts[i].Amount - c.Amount.Amount
+		if diff == 0 {
+			continue
+		}
+		// members of merged collections settle in their own rooms
+		d := api.Contribution{
+			CollectionId: collection.ID,
+			RoomId:       c.RoomId,
+			User:         c.User,
+			Kind:         api.ContributionExtra,
+			Amount:       api.Money{Amount: diff, Currency: spent.Currency},
+			Status:       api.ContributionOutstanding,
+			CreateAt:     now,
+		}
+		if diff < 0 {
+			recipient := c.User
+			d.User, d.Recipient, d.Kind, d.Amount.Amount = organizer, &recipient, api.ContributionRefund, -diff
+		}
+		settlement = append(settlement, d)
+	}
+
+	err = cs.uow.WithTx(ctx, func(ctx context.Context) error {
+		if err := cs.CollectionRepository.SetCollectionExpense(ctx, collection.ID, spent, now); err != nil {
+			return err
+		}
+		for _, c := range unpaid {
+			if err := cs.CollectionRepository.SetContributionAmount(ctx, c.ID, c.Amount); err != nil {
+				return err
+			}
+		}
+		if err := cs.CollectionRepository.SaveContributions(ctx, settlement); err != nil {
+			return err
+		}
+		return cs.CollectionRepository.SaveOperations(ctx, api.Operation{
+			RoomId:       collection.RoomId,
+			CollectionId: collection.ID,
+			Type:         api.OperationExpense,
+			Author:       organizer,
+			Amount:       spent,
+			Receipt:      receipt,
+			CreateAt:     now,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &settlement, nil
+}
+
+// SetDeadline sets the date when the collection should be paid, allowed for the organizer, nil removes the deadline
+func (cs *CollectionService) SetDeadline(ctx context.Context, userId int64, collectionId string, deadline *time.Time) error {
+	c, err := cs.CollectionRepository.FindCollectionById(ctx, collectionId)
+	if err != nil {
+		return err
+	}
+	if c.Organizer != userId {
+		return api.ErrForbidden
+	}
+	return cs.CollectionRepository.SetCollectionDeadline(ctx, c.ID, deadline)
+}
+
+// SnoozeReminders stops reminders about the contribution until the time, allowed for the contributor
+func (cs *CollectionService) SnoozeReminders(ctx context.Context, userId int64, contributionId string, until time.Time) error {
+	c, err := cs.CollectionRepository.FindContributionById(ctx, contributionId)
+	if err != nil {
+		return err
+	}
+	if c.User.ID != userId {
+		return api.ErrForbidden
+	}
+	return cs.CollectionRepository.SetContributionSnoozed(ctx, c.ID, until)
+}
+
+// Statistics summarizes collections of the room, allowed for members.
+// Members are sorted by total paid amount in the room currency, the top contributors go first
+func (cs *CollectionService) Statistics(ctx context.Context, userId int64, roomId string, now time.Time) (*api.RoomStatistics, error) {
+	room, err := cs.rr.FindById(ctx, roomId)
+	if err != nil {
+		return nil, err
+	}
+	if !room.IsMember(userId) {
+		return nil, api.ErrNotMember
+	}
+	collections, err := cs.CollectionRepository.FindCollectionsByRoomId(ctx, roomId)
+	if err != nil {
+		return nil, err
+	}
+	contributions, err := cs.CollectionRepository.FindContributionsByRoomId(ctx, roomId)
+	if err != nil {
+		return nil, err
+	}
+
+	byId := make(map[primitive.ObjectID]api.Collection, len(*collections))
+	for _, c := range *collections {
+		byId[c.ID] = c
+	}
+
+	byYear := make(map[int][]api.Money)
+	var paid []api.Money
+	members := make(map[int64]*api.MemberStatistics)
+	var order []int64
+	for _, c := range *contributions {
+		collection, ok := byId[c.CollectionId]
+		if !ok {
+			continue
+		}
+		// a refund is paid back to the recipient, it reduces what the recipient contributed
+		member, amount := c.User, c.Amount
+		if c.Kind == api.ContributionRefund {
+			member, amount = *c.Recipient, api.Money{Amount: -c.Amount.Amount, Currency: c.Amount.Currency}
+		}
+		ms, ok := members[member.ID]
+		if !ok {
+			ms = &api.MemberStatistics{User: member}
+			members[member.ID] = ms
+			order = append(order, member.ID)
+		}
+
+		if c.Status == api.ContributionApproved {
+			year := collection.CreateAt.Year()
+			byYear[year] = append(byYear[year], amount)
+			ms.Total = append(ms.Total, amount)
+			if c.Kind == api.ContributionShare {
+				paid = append(paid, amount)
+			}
+		}
+		if c.Kind != api.ContributionShare {
+			continue
+		}
+
+		// a contribution without a deadline is due when approved, it is always paid in time. The payment counts
+		// when the payee approves it, a pending or rejected one is not paid yet
+		overdue := collection.Deadline != nil && now.After(*collection.Deadline)
+		paidAt := approvedAt(&c)
+		if paidAt == nil && !overdue {
+			continue
+		}
+		ms.Due++
+		if paidAt != nil && (collection.Deadline == nil || !paidAt.After(*collection.Deadline)) {
+			ms.OnTime++
+		}
+	}
+
+	stats := &api.RoomStatistics{}
+	for year, amounts := range byYear {
+		stats.CollectedByYear = append(stats.CollectedByYear, api.YearTotal{Year: year, Totals: api.SumByCurrency(amounts)})
+	}
+	sort.Slice(stats.CollectedByYear, func(i, j int) bool { return stats.CollectedByYear[i].Year > stats.CollectedByYear[j].Year })
+
+	counts := make(map[string]int64)
+	for _, m := range paid {
+		counts[m.Currency]++
+	}
+	for _, m := range api.SumByCurrency(paid) {
+		stats.Average = append(stats.Average, api.Money{Amount: m.Amount / counts[m.Currency], Currency: m.Currency})
+	}
+
+	for _, id := range order {
+		ms := members[id]
+		ms.Total = api.SumByCurrency(ms.Total)
+		stats.Members = append(stats.Members, *ms)
+	}
+	currency := room.CurrencyCode()
+	sort.SliceStable(stats.Members, func(i, j int) bool {
+		return amountIn(stats.Members[i].Total, currency) > amountIn(stats.Members[j].Total, currency)
+	})
+	return stats, nil
+}
+
+// approvedAt returns when the payment was approved, nil if it is not approved. Payments approved before reviews
+// were recorded have the time of the payment only
+func approvedAt(c *api.Contribution) *time.Time {
+	if c.Status != api.ContributionApproved {
+		return nil
+	}
+	if c.ReviewedAt != nil {
+		return c.ReviewedAt
+	}
+	return c.PaidAt
+}
+
+func amountIn(ms []api.Money, currency string) int64 {
+	for _, m := range ms {
+		if m.Currency == currency {
+			return m.Amount
+		}
+	}
+	return 0
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"github.com/almaznur91/splitty/internal/api"
+	"github.com/almaznur91/splitty/internal/repository"
+	"go.mongodb.org/mongo-driver/mongo"
+	"strings"
+	"time"
+)
+
+type InviteService struct {
+	repository.InviteRepository
+	rr  repository.RoomRepository
+	uow repository.UnitOfWork
+}
+
+func NewInviteService(r repository.InviteRepository, rr repository.RoomRepository, uow repository.UnitOfWork) *InviteService {
+	return &InviteService{r, rr, uow}
+}
+
+// CreateInvite makes a new invite code of the room, allowed for admins. Zero ttl means the invite never expires,
+// zero maxUses means unlimited uses
+func (is *InviteService) CreateInvite(ctx context.Context, userId int64, roomId string, ttl time.Duration, maxUses int, approval bool) (*api.Invite, error) {
+	if _, err := is.findForAdmin(ctx, userId, roomId); err != nil {
+		return nil, err
+	}
+	code, err := inviteCode()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	i := &api.Invite{Code: code, RoomId: roomId, CreatedBy: userId, MaxUses: maxUses, Approval: approval, CreateAt: now}
+	if ttl > 0 {
+		expires := now.Add(ttl)
+		i.ExpiresAt = &expires
+	}
+	i.ID, err = is.InviteRepository.SaveInvite(ctx, i)
+	return i, err
+}
+
+// FindInvites returns valid invites of the room, allowed for admins
+func (is *InviteService) FindInvites(ctx context.Context, userId int64, roomId string) (*[]api.Invite, error) {
+	if _, err := is.findForAdmin(ctx, userId, roomId); err != nil {
+		return nil, err
+	}
+	invites, err := is.InviteRepository.FindInvitesByRoomId(ctx, roomId)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	valid := make([]api.Invite, 0, len(*invites))
+	for _, i := range *invites {
+		if i.IsValid(now) {
+			valid = append(valid, i)
+		}
+	}
+	return &valid, nil
+}
+
+// RevokeInvite makes the invite unusable, allowed for admins of the invite room
+func (is *InviteService) RevokeInvite(ctx context.Context, userId int64, inviteId string) (*api.Invite, error) {
+	i, err := is.InviteRepository.FindInviteById(ctx, inviteId)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := is.findForAdmin(ctx, userId, i.RoomId); err != nil {
+		return nil, err
+	}
+	i.Revoked = true
+	return i, is.InviteRepository.RevokeInvite(ctx, i.ID)
+}
+
+// JoinByInvite adds the user to the room of the invite, the invite with approval only leaves a join request for admins.
+// Returns true if the user waits for approval
+func (is *InviteService) JoinByInvite(ctx context.Context, u api.User, code string) (*api.Room, bool, error) {
+	i, err := is.InviteRepository.FindInviteByCode(ctx, strings.ToLower(code))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, false, api.ErrInvalidInvite
+	} else if err != nil {
+		return nil, false, err
+	}
+	if !i.IsValid(time.Now()) {
+		return nil, false, api.ErrInvalidInvite
+	}
+	room, err := is.rr.FindById(ctx, i.RoomId)
+	if err != nil {
+		return nil, false, err
+	}
+	if room.IsMember(u.ID) {
+		return room, false, nil
+	}
+	// the use is counted only when the user gets into the room or the request is left
+	err = is.uow.WithTx(ctx, func(ctx context.Context) error {
+		used, err := is.InviteRepository.UseInvite(ctx, i.ID)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return api.ErrInvalidInvite
+		}
+		if i.Approval {
+			return is.rr.AddJoinRequest(ctx, i.RoomId, u)
+		}
+		return is.rr.JoinToRoom(ctx, u, i.RoomId)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return room, i.Approval, nil
+}
+
+// ReviewJoinRequest lets the user who came by the invite into the room or rejects the request, allowed for admins
+func (is *InviteService) ReviewJoinRequest(ctx context.Context, userId int64, roomId string, memberId int64, approve bool) (*api.User, error) {
+	room, err := is.findForAdmin(ctx, userId, roomId)
+	if err != nil {
+		return nil, err
+	}
+	var request *api.User
+	for i := range room.JoinRequests {
+		if room.JoinRequests[i].ID == memberId {
+			request = &room.JoinRequests[i]
+		}
+	}
+	if request == nil {
+		return nil, api.ErrNoJoinRequest
+	}
+	err = is.uow.WithTx(ctx, func(ctx context.Context) error {
+		if err := is.rr.RemoveJoinRequest(ctx, roomId, memberId); err != nil {
+			return err
+		}
+		if !approve || room.IsMember(memberId) {
+			return nil
+		}
+		return is.rr.JoinToRoom(ctx, *request, roomId)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (is *InviteService) findForAdmin(ctx context.Context, userId int64, roomId string) (*api.Room, error) {
+	room, err := is.rr.FindById(ctx, roomId)
+	if err != nil {
+		return nil, err
+	}
+	if !room.IsAdmin(userId) {
+		return nil, api.ErrForbidden
+	}
+	return room, nil
+}
+
+// inviteCode makes a random code, it fits the deep link start parameter
+func inviteCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)), nil
+}
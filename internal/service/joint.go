@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"github.com/almaznur91/splitty/internal/api"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// FindJointCandidate returns the oldest active collection for the same celebrant in another room,
+// the collection can be merged into it. Returns nil if there is no such collection
+func (cs *CollectionService) FindJointCandidate(ctx context.Context, collection *api.Collection) (*api.Collection, error) {
+	if collection.Celebrant == nil || collection.JointId != nil || collection.Expense != nil {
+		return nil, nil
+	}
+	active, err := cs.CollectionRepository.FindActiveCollectionsByCelebrant(ctx, collection.Celebrant.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range *active {
+		c := &(*active)[i]
+		if c.RoomId != collection.RoomId && c.Amount.Currency == collection.Amount.Currency {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+// MergeCollections moves contributors of the collection into the joint collection of another room, allowed for
+// the organizer of the merged collection. Members keep contributions in their room, members of both rooms keep
+// one share, see planMerge, and the former organizer becomes a contributor. Operations stay in the ledger
+// of the merged collection, which refers to the joint one by JointId
+func (cs *CollectionService) MergeCollections(ctx context.Context, userId int64, collectionId string, jointId string) (*api.Collection, error) {
+	collection, err := cs.CollectionRepository.FindCollectionById(ctx, collectionId)
+	if err != nil {
+		return nil, err
+	}
+	joint, err := cs.CollectionRepository.FindCollectionById(ctx, jointId)
+	if err != nil {
+		return nil, err
+	}
+	if collection.Organizer != userId || collection.ID == joint.ID || collection.RoomId == joint.RoomId ||
+		collection.JointId != nil || joint.JointId != nil || collection.Celebrant == nil || joint.Celebrant == nil ||
+		collection.Celebrant.ID != joint.Celebrant.ID {
+		return nil, api.ErrForbidden
+	}
+	if collection.Expense != nil || joint.Expense != nil {
+		return nil, api.ErrAlreadySettled
+	}
+	if collection.Amount.Currency != joint.Amount.Currency {
+		return nil, api.ErrCurrencyMismatch
+	}
+
+	contributions, err := cs.CollectionRepository.FindContributionsByCollectionId(ctx, collection.ID)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := cs.CollectionRepository.FindContributionsByCollectionId(ctx, joint.ID)
+	if err != nil {
+		return nil, err
+	}
+	room, err := cs.rr.FindById(ctx, collection.RoomId)
+	if err != nil {
+		return nil, err
+	}
+	plan := planMerge(*contributions, *existing, joint, author(room, userId), time.Now())
+	err = cs.uow.WithTx(ctx, func(ctx context.Context) error {
+		if err := cs.CollectionRepository.MoveContributions(ctx, plan.moved, joint.ID); err != nil {
+			return err
+		}
+		if err := cs.CollectionRepository.DeleteContributions(ctx, plan.deleted); err != nil {
+			return err
+		}
+		if err := cs.CollectionRepository.SaveContributions(ctx, plan.refunds); err != nil {
+			return err
+		}
+		if !plan.contributors[userId] {
+			if err := cs.CollectionRepository.SaveContributions(ctx, []api.Contribution{{
+				CollectionId: joint.ID,
+				RoomId:       collection.RoomId,
+				User:         author(room, userId),
+				Amount:       collection.Amount,
+				Status:       api.ContributionOutstanding,
+				CreateAt:     time.Now(),
+			}}); err != nil {
+				return err
+			}
+		}
+		return cs.CollectionRepository.SetCollectionJoint(ctx, collection.ID, joint.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return joint, nil
+}
+
+// mergePlan is what happens to contributions of both collections when they are merged
+type mergePlan struct {
+	moved        []primitive.ObjectID
+	deleted      []primitive.ObjectID
+	refunds      []api.Contribution
+	contributors map[int64]bool
+}
+
+// planMerge keeps one share of a member of both rooms in the joint collection, a debt is dropped in favour
+// of a paid copy and a payment waiting for review counts as paid. When the member paid in both collections,
+// the organizer of the merged collection, who got the second payment, refunds it
+func planMerge(contributions []api.Contribution, existing []api.Contribution, joint *api.Collection, organizer api.User, now time.Time) mergePlan {
+	plan := mergePlan{contributors: map[int64]bool{joint.Organizer: true}}
+	shares := make(map[int64]api.Contribution, len(existing))
+	for _, c := range existing {
+		plan.contributors[c.User.ID] = true
+		shares[c.User.ID] = c
+	}
+
+	for _, c := range contributions {
+		if !plan.contributors[c.User.ID] {
+			plan.moved = append(plan.moved, c.ID)
+			continue
+		}
+		if c.IsDebt() {
+			plan.deleted = append(plan.deleted, c.ID)
+			continue
+		}
+		// the joint organizer has no contribution, the part of the organizer is always paid
+		if j, ok := shares[c.User.ID]; ok && j.IsDebt() {
+			plan.moved = append(plan.moved, c.ID)
+			plan.deleted = append(plan.deleted, j.ID)
+			continue
+		}
+		// the second payment stays in the merged collection with its refund, so the member has one share to split
+		recipient := c.User
+		plan.refunds = append(plan.refunds, api.Contribution{
+			CollectionId: c.CollectionId,
+			RoomId:       c.RoomId,
+			User:         organizer,
+			Kind:         api.ContributionRefund,
+			Recipient:    &recipient,
+			Amount:       c.Amount,
+			Status:       api.ContributionOutstanding,
+			CreateAt:     now,
+		})
+	}
+	return plan
+}
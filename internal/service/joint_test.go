@@ -0,0 +1,89 @@
+package service
+
+import (
+	"github.com/almaznur91/splitty/internal/api"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPlanMerge(t *testing.T) {
+	merged, joint := primitive.NewObjectID(), &api.Collection{ID: primitive.NewObjectID(), Organizer: 1}
+	organizer := api.User{ID: 2, DisplayName: "Org"}
+	amount := api.NewMoney(500, "RUB")
+	share := func(collectionId primitive.ObjectID, userId int64, status api.ContributionStatus) api.Contribution {
+		return api.Contribution{ID: primitive.NewObjectID(), CollectionId: collectionId, RoomId: "room",
+			User: api.User{ID: userId}, Amount: amount, Status: status}
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		merged   api.Contribution
+		joint    *api.Contribution
+		moved    bool
+		deleted  string
+		refunded bool
+	}{
+		{name: "only in merged room", merged: share(merged, 3, api.ContributionApproved), moved: true},
+		{name: "both debts", merged: share(merged, 3, api.ContributionOutstanding),
+			joint: sharePtr(share(joint.ID, 3, api.ContributionOutstanding)), deleted: "merged"},
+		{name: "rejected in merged room", merged: share(merged, 3, api.ContributionRejected),
+			joint: sharePtr(share(joint.ID, 3, api.ContributionApproved)), deleted: "merged"},
+		{name: "paid in merged room", merged: share(merged, 3, api.ContributionApproved),
+			joint: sharePtr(share(joint.ID, 3, api.ContributionOutstanding)), moved: true, deleted: "joint"},
+		{name: "pending in merged room", merged: share(merged, 3, api.ContributionPending),
+			joint: sharePtr(share(joint.ID, 3, api.ContributionRejected)), moved: true, deleted: "joint"},
+		{name: "paid in both rooms", merged: share(merged, 3, api.ContributionApproved),
+			joint: sharePtr(share(joint.ID, 3, api.ContributionApproved)), refunded: true},
+		{name: "joint organizer paid", merged: share(merged, 1, api.ContributionApproved), refunded: true},
+		{name: "joint organizer owes", merged: share(merged, 1, api.ContributionOutstanding), deleted: "merged"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var existing []api.Contribution
+			if tt.joint != nil {
+				existing = append(existing, *tt.joint)
+			}
+			plan := planMerge([]api.Contribution{tt.merged}, existing, joint, organizer, now)
+
+			var moved, deleted []primitive.ObjectID
+			if tt.moved {
+				moved = []primitive.ObjectID{tt.merged.ID}
+			}
+			switch tt.deleted {
+			case "merged":
+				deleted = []primitive.ObjectID{tt.merged.ID}
+			case "joint":
+				deleted = []primitive.ObjectID{tt.joint.ID}
+			}
+			if !reflect.DeepEqual(plan.moved, moved) {
+				t.Errorf("moved = %v, want %v", plan.moved, moved)
+			}
+			if !reflect.DeepEqual(plan.deleted, deleted) {
+				t.Errorf("deleted = %v, want %v", plan.deleted, deleted)
+			}
+
+			if !tt.refunded {
+				if len(plan.refunds) != 0 {
+					t.Errorf("refunds = %v, want none", plan.refunds)
+				}
+				return
+			}
+			if len(plan.refunds) != 1 {
+				t.Fatalf("refunds = %v, want one", plan.refunds)
+			}
+			r := plan.refunds[0]
+			if r.Kind != api.ContributionRefund || r.User.ID != organizer.ID || r.Recipient == nil ||
+				r.Recipient.ID != tt.merged.User.ID || r.Amount != amount || r.CollectionId != merged ||
+				r.Status != api.ContributionOutstanding {
+				t.Errorf("refund = %+v, want %v refunded by %v in the merged collection", r, tt.merged.User.ID, organizer.ID)
+			}
+		})
+	}
+}
+
+func sharePtr(c api.Contribution) *api.Contribution {
+	return &c
+}
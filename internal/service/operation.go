@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"github.com/almaznur91/splitty/internal/api"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// FindOperations returns operations of the room from the newest one, allowed for members
+func (cs *CollectionService) FindOperations(ctx context.Context, userId int64, roomId string) (*[]api.Operation, error) {
+	room, err := cs.rr.FindById(ctx, roomId)
+	if err != nil {
+		return nil, err
+	}
+	if !room.IsMember(userId) {
+		return nil, api.ErrNotMember
+	}
+	return cs.CollectionRepository.FindOperationsByRoomId(ctx, roomId)
+}
+
+// UndoOperation writes the compensating operation, allowed for the organizer of the collection and room admins.
+// Undo of a payment or a refund returns it to debts, undo of a target change restores the previous amount
+func (cs *CollectionService) UndoOperation(ctx context.Context, userId int64, operationId string) (*api.Operation, error) {
+	op, collection, room, err := cs.findEditableOperation(ctx, userId, operationId)
+	if err != nil {
+		return nil, err
+	}
+
+	revert := compensation(op, author(room, userId), time.Now())
+	err = cs.uow.WithTx(ctx, func(ctx context.Context) error {
+		switch op.Type {
+		case api.OperationTarget:
+			amount, err := collection.Amount.Add(revert.Amount)
+			if err != nil {
+				return err
+			}
+			if amount.Amount <= 0 {
+				return api.ErrForbidden
+			}
+			if err := cs.CollectionRepository.SetCollectionAmount(ctx, collection.ID, amount); err != nil {
+				return err
+			}
+		case api.OperationContribution, api.OperationRefund:
+			if op.ContributionId != nil {
+				if err := cs.CollectionRepository.ResetContributionPayment(ctx, *op.ContributionId); err != nil {
+					return err
+				}
+			}
+		}
+		return cs.CollectionRepository.SaveOperations(ctx, revert)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &revert, nil
+}
+
+// EditOperation corrects the amount of the operation by the compensating operation and the new one with the amount.
+// Edit of a target change sets the amount every member contributes, edit of a payment or a refund sets the amount
+// of the contribution
+func (cs *CollectionService) EditOperation(ctx context.Context, userId int64, operationId string, amount api.Money) (*api.Operation, error) {
+	op, collection, room, err := cs.findEditableOperation(ctx, userId, operationId)
+	if err != nil {
+		return nil, err
+	}
+	if amount.Currency != op.Amount.Currency {
+		return nil, api.ErrCurrencyMismatch
+	}
+
+	now := time.Now()
+	if op.Type == api.OperationTarget {
+		change := api.Money{Amount: amount.Amount - collection.Amount.Amount, Currency: amount.Currency}
+		edited := api.Operation{
+			RoomId:       op.RoomId,
+			CollectionId: op.CollectionId,
+			Type:         api.OperationTarget,
+			Author:       author(room, userId),
+			User:         op.User,
+			Amount:       change,
+			CreateAt:     now,
+		}
+		err := cs.uow.WithTx(ctx, func(ctx context.Context) error {
+			if err := cs.CollectionRepository.SetCollectionAmount(ctx, collection.ID, amount); err != nil {
+				return err
+			}
+			return cs.CollectionRepository.SaveOperations(ctx, edited)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &edited, nil
+	}
+
+	revert := compensation(op, author(room, userId), now)
+	edited := *op
+	edited.ID, edited.Author, edited.Amount, edited.CreateAt = primitive.NilObjectID, revert.Author, amount, now
+	err = cs.uow.WithTx(ctx, func(ctx context.Context) error {
+		if op.ContributionId != nil {
+			if err := cs.CollectionRepository.SetContributionAmount(ctx, *op.ContributionId, amount); err != nil {
+				return err
+			}
+		}
+		return cs.CollectionRepository.SaveOperations(ctx, revert, edited)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &edited, nil
+}
+
+// findEditableOperation checks that the operation is not undone yet and the user can change it
+func (cs *CollectionService) findEditableOperation(ctx context.Context, userId int64, operationId string) (*api.Operation, *api.Collection, *api.Room, error) {
+	op, err := cs.CollectionRepository.FindOperationById(ctx, operationId)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	collection, err := cs.CollectionRepository.FindCollectionById(ctx, op.CollectionId.Hex())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	room, err := cs.rr.FindById(ctx, op.RoomId)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// settlement debts are already created from the expense, so it can't be changed, and the joint collection
+	// owns contributions and the amount of the merged one
+	if !op.IsEditable(collection) || collection.Organizer != userId && !room.IsAdmin(userId) {
+		return nil, nil, nil, api.ErrForbidden
+	}
+	reverted, err := cs.CollectionRepository.IsOperationReverted(ctx, op.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if reverted {
+		return nil, nil, nil, api.ErrAlreadyReverted
+	}
+	return op, collection, room, nil
+}
+
+func compensation(op *api.Operation, author api.User, t time.Time) api.Operation {
+	return api.Operation{
+		RoomId:         op.RoomId,
+		CollectionId:   op.CollectionId,
+		ContributionId: op.ContributionId,
+		Type:           op.Type,
+		Author:         author,
+		User:           op.User,
+		Amount:         api.Money{Amount: -op.Amount.Amount, Currency: op.Amount.Currency},
+		RevertOf:       &op.ID,
+		CreateAt:       t,
+	}
+}
+
+// author returns the room member making the operation, the user who left the room is known by id only
+func author(room *api.Room, userId int64) api.User {
+	if u := room.FindMember(userId); u != nil {
+		return *u
+	}
+	return api.User{ID: userId}
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"github.com/almaznur91/splitty/internal/api"
+	"github.com/almaznur91/splitty/internal/repository"
+	"time"
+)
+
+// PrivacyService exports and forgets everything stored about the user
+type PrivacyService struct {
+	ur  repository.UserRepository
+	rr  repository.RoomRepository
+	cs  *CollectionService
+	csr repository.ChatStateRepository
+	br  repository.ButtonRepository
+	uow repository.UnitOfWork
+}
+
+func NewPrivacyService(ur repository.UserRepository, rr repository.RoomRepository, cs *CollectionService,
+	csr repository.ChatStateRepository, br repository.ButtonRepository, uow repository.UnitOfWork) *PrivacyService {
+	return &PrivacyService{ur, rr, cs, csr, br, uow}
+}
+
+// ExportUserData collects the profile, rooms, contributions, operations, assignments, organized collections
+// and the chat state of the user
+func (ps *PrivacyService) ExportUserData(ctx context.Context, userId int64) (*api.UserData, error) {
+	profile, err := ps.ur.FindById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	data := &api.UserData{Profile: profile, Rooms: make([]api.UserRoom, 0), ExportedAt: time.Now()}
+
+	active, err := ps.rr.FindRoomsByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	archived, err := ps.rr.FindArchivedRoomsByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	for i, rooms := range []*[]api.Room{active, archived} {
+		for _, r := range *rooms {
+			role := "member"
+			if r.IsOwner(userId) {
+				role = "owner"
+			} else if r.IsAdmin(userId) {
+				role = "admin"
+			}
+			data.Rooms = append(data.Rooms, api.UserRoom{
+				ID:              r.ID,
+				Name:            r.Name,
+				Role:            role,
+				Archived:        i == 1,
+				OrganizerOptOut: r.IsOptedOut(userId),
+			})
+		}
+	}
+
+	contributions, err := ps.cs.FindContributionsByMember(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	data.Contributions = *contributions
+	operations, err := ps.cs.FindOperationsByMember(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	data.Operations = *operations
+	assignments, err := ps.cs.FindAssignmentsByMember(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	data.Assignments = *assignments
+	organized, err := ps.cs.FindCollectionsByOrganizer(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	data.Organized = *organized
+	if data.ChatState, err = ps.csr.FindByUserId(ctx, userId); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ForgetUser removes the user from rooms and deletes the profile, chat states and buttons of the user.
+// Financial records are kept with the anonymized name, so debts and balances of other members stay the same.
+// The ownership of the room passes to an admin or the earliest member, the room without other members is deleted.
+// Collections of the user pass to the room owner and open assignments to other members by the rotation
+func (ps *PrivacyService) ForgetUser(ctx context.Context, userId int64) error {
+	active, err := ps.rr.FindRoomsByUserId(ctx, userId)
+	if err != nil {
+		return err
+	}
+	archived, err := ps.rr.FindArchivedRoomsByUserId(ctx, userId)
+	if err != nil {
+		return err
+	}
+	rooms := append(*active, *archived...)
+
+	return ps.uow.WithTx(ctx, func(ctx context.Context) error {
+		for _, r := range rooms {
+			owner := r.Owner
+			if r.IsOwner(userId) {
+				if owner = nextOwner(&r, userId); owner == 0 {
+					if err := ps.rr.DeleteRoom(ctx, r.ID.Hex()); err != nil {
+						return err
+					}
+					continue
+				}
+				if err := ps.rr.RemoveAdmin(ctx, r.ID.Hex(), owner); err != nil {
+					return err
+				}
+				if err := ps.rr.SetOwner(ctx, r.ID.Hex(), owner); err != nil {
+					return err
+				}
+			}
+			if err := ps.cs.HandOver(ctx, &r, userId, owner); err != nil {
+				return err
+			}
+		}
+		if err := ps.rr.ForgetMember(ctx, userId); err != nil {
+			return err
+		}
+		if err := ps.cs.AnonymizeUser(ctx, userId, api.ForgottenName); err != nil {
+			return err
+		}
+		if err := ps.csr.DeleteByUserId(ctx, userId); err != nil {
+			return err
+		}
+		if err := ps.br.DeleteByUserId(ctx, userId); err != nil {
+			return err
+		}
+		return ps.ur.DeleteUser(ctx, userId)
+	})
+}
+
+// nextOwner returns the first admin or the earliest member except the user, 0 if nobody is left
+func nextOwner(r *api.Room, userId int64) int64 {
+	for _, id := range r.Admins {
+		if id != userId && r.IsMember(id) {
+			return id
+		}
+	}
+	for _, m := range *r.Members {
+		if m.ID != userId {
+			return m.ID
+		}
+	}
+	return 0
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"github.com/almaznur91/splitty/internal/api"
 	"github.com/almaznur91/splitty/internal/repository"
+	"time"
 )
 
+// RoomService checks the rights of room changes, the repository isn't exposed so members join only by invites
+// or by the bound group chat
 type RoomService struct {
-	repository.RoomRepository
+	rr repository.RoomRepository
 }
 
 func NewRoomService(r repository.RoomRepository) *RoomService {
@@ -15,7 +18,279 @@ func NewRoomService(r repository.RoomRepository) *RoomService {
 }
 
 func (rs *RoomService) CreateRoom(ctx context.Context, r *api.Room) (*api.Room, error) {
-	rId, err := rs.RoomRepository.SaveRoom(ctx, r)
+	if r.Owner == 0 && r.Members != nil && len(*r.Members) > 0 {
+		r.Owner = (*r.Members)[0].ID
+	}
+	rId, err := rs.rr.SaveRoom(ctx, r)
 	r.ID = rId
 	return r, err
 }
+
+func (rs *RoomService) FindById(ctx context.Context, id string) (*api.Room, error) {
+	return rs.rr.FindById(ctx, id)
+}
+
+func (rs *RoomService) FindRoomsByUserId(ctx context.Context, id int64) (*[]api.Room, error) {
+	return rs.rr.FindRoomsByUserId(ctx, id)
+}
+
+func (rs *RoomService) FindArchivedRoomsByUserId(ctx context.Context, id int64) (*[]api.Room, error) {
+	return rs.rr.FindArchivedRoomsByUserId(ctx, id)
+}
+
+func (rs *RoomService) FindRoomsByLikeName(ctx context.Context, userId int64, name string) (*[]api.Room, error) {
+	return rs.rr.FindRoomsByLikeName(ctx, userId, name)
+}
+
+func (rs *RoomService) FindRoomsToGreet(ctx context.Context) (*[]api.Room, error) {
+	return rs.rr.FindRoomsToGreet(ctx)
+}
+
+func (rs *RoomService) FindRoomsWithCollectionRule(ctx context.Context) (*[]api.Room, error) {
+	return rs.rr.FindRoomsWithCollectionRule(ctx)
+}
+
+func (rs *RoomService) SetGreetedAt(ctx context.Context, roomId string, t time.Time) error {
+	return rs.rr.SetGreetedAt(ctx, roomId, t)
+}
+
+// ArchiveRoom hides the room from the room list of the member
+func (rs *RoomService) ArchiveRoom(ctx context.Context, userId int64, roomId string) error {
+	return rs.rr.ArchiveRoom(ctx, userId, roomId)
+}
+
+// UnArchiveRoom returns the room to the room list of the member
+func (rs *RoomService) UnArchiveRoom(ctx context.Context, userId int64, roomId string) error {
+	return rs.rr.UnArchiveRoom(ctx, userId, roomId)
+}
+
+// UnbindChat unlinks the group chat the bot has left from its room
+func (rs *RoomService) UnbindChat(ctx context.Context, chatId int64) error {
+	return rs.rr.UnbindChat(ctx, chatId)
+}
+
+// RenameRoom sets new room name, allowed for admins
+func (rs *RoomService) RenameRoom(ctx context.Context, userId int64, roomId string, name string) error {
+	if _, err := rs.findForAdmin(ctx, userId, roomId); err != nil {
+		return err
+	}
+	return rs.rr.RenameRoom(ctx, roomId, name)
+}
+
+// LeaveRoom removes user from the room, the last member leaving deletes the room
+func (rs *RoomService) LeaveRoom(ctx context.Context, userId int64, roomId string) error {
+	room, err := rs.rr.FindById(ctx, roomId)
+	if err != nil {
+		return err
+	}
+	if !room.IsMember(userId) {
+		return api.ErrNotMember
+	}
+	if room.IsOwner(userId) {
+		if len(*room.Members) > 1 {
+			return api.ErrOwnerLeave
+		}
+		return rs.rr.DeleteRoom(ctx, roomId)
+	}
+	if err := rs.rr.RemoveAdmin(ctx, roomId, userId); err != nil {
+		return err
+	}
+	return rs.rr.LeaveRoom(ctx, userId, roomId)
+}
+
+// RemoveMember removes another member from the room, allowed for admins, the owner can't be removed
+func (rs *RoomService) RemoveMember(ctx context.Context, userId int64, roomId string, memberId int64) error {
+	room, err := rs.findForAdmin(ctx, userId, roomId)
+	if err != nil {
+		return err
+	}
+	if !room.IsMember(memberId) {
+		return api.ErrNotMember
+	}
+	if room.IsOwner(memberId) || room.IsAdmin(memberId) && !room.IsOwner(userId) {
+		return api.ErrForbidden
+	}
+	if err := rs.rr.RemoveAdmin(ctx, roomId, memberId); err != nil {
+		return err
+	}
+	return rs.rr.LeaveRoom(ctx, memberId, roomId)
+}
+
+// SwitchAdmin grants or revokes admin rights of the member, allowed for the owner
+func (rs *RoomService) SwitchAdmin(ctx context.Context, userId int64, roomId string, memberId int64) error {
+	room, err := rs.findForOwner(ctx, userId, roomId)
+	if err != nil {
+		return err
+	}
+	if !room.IsMember(memberId) {
+		return api.ErrNotMember
+	}
+	if room.IsOwner(memberId) {
+		return api.ErrForbidden
+	}
+	if room.IsAdmin(memberId) {
+		return rs.rr.RemoveAdmin(ctx, roomId, memberId)
+	}
+	return rs.rr.AddAdmin(ctx, roomId, memberId)
+}
+
+// TransferOwnership makes the member a new owner, the previous owner stays an admin
+func (rs *RoomService) TransferOwnership(ctx context.Context, userId int64, roomId string, memberId int64) error {
+	room, err := rs.findForOwner(ctx, userId, roomId)
+	if err != nil {
+		return err
+	}
+	if !room.IsMember(memberId) {
+		return api.ErrNotMember
+	}
+	if err := rs.rr.AddAdmin(ctx, roomId, userId); err != nil {
+		return err
+	}
+	if err := rs.rr.RemoveAdmin(ctx, roomId, memberId); err != nil {
+		return err
+	}
+	return rs.rr.SetOwner(ctx, roomId, memberId)
+}
+
+// DeleteRoom deletes the room, allowed for the owner
+func (rs *RoomService) DeleteRoom(ctx context.Context, userId int64, roomId string) error {
+	if _, err := rs.findForOwner(ctx, userId, roomId); err != nil {
+		return err
+	}
+	return rs.rr.DeleteRoom(ctx, roomId)
+}
+
+// BindChat links the group chat to the room, allowed for admins, the chat is unlinked from other rooms
+func (rs *RoomService) BindChat(ctx context.Context, userId int64, roomId string, chat api.Chat) error {
+	if _, err := rs.findForAdmin(ctx, userId, roomId); err != nil {
+		return err
+	}
+	if err := rs.rr.UnbindChat(ctx, chat.ID); err != nil {
+		return err
+	}
+	return rs.rr.BindChat(ctx, roomId, chat)
+}
+
+// SyncChatMember adds the group chat member to the room bound to the chat
+func (rs *RoomService) SyncChatMember(ctx context.Context, chatId int64, u api.User) error {
+	room, err := rs.rr.FindByChatId(ctx, chatId)
+	if err != nil || room == nil || room.IsMember(u.ID) {
+		return err
+	}
+	return rs.rr.JoinToRoom(ctx, u, room.ID.Hex())
+}
+
+// RemoveChatMember removes the member who left the group chat from the room bound to the chat, the owner stays
+func (rs *RoomService) RemoveChatMember(ctx context.Context, chatId int64, userId int64) error {
+	room, err := rs.rr.FindByChatId(ctx, chatId)
+	if err != nil || room == nil || !room.IsMember(userId) || room.IsOwner(userId) {
+		return err
+	}
+	if err := rs.rr.RemoveAdmin(ctx, room.ID.Hex(), userId); err != nil {
+		return err
+	}
+	return rs.rr.LeaveRoom(ctx, userId, room.ID.Hex())
+}
+
+// SetGreeting replaces the active birthday greeting, allowed for admins
+func (rs *RoomService) SetGreeting(ctx context.Context, userId int64, roomId string, g *api.Greeting) error {
+	if _, err := rs.findForAdmin(ctx, userId, roomId); err != nil {
+		return err
+	}
+	return rs.rr.SetGreeting(ctx, roomId, g)
+}
+
+// SetGreetingDraft saves the greeting for preview, allowed for admins
+func (rs *RoomService) SetGreetingDraft(ctx context.Context, userId int64, roomId string, g *api.Greeting) error {
+	if _, err := rs.findForAdmin(ctx, userId, roomId); err != nil {
+		return err
+	}
+	return rs.rr.SetGreetingDraft(ctx, roomId, g)
+}
+
+// ActivateGreeting makes the previewed draft the active greeting, allowed for admins
+func (rs *RoomService) ActivateGreeting(ctx context.Context, userId int64, roomId string) error {
+	room, err := rs.findForAdmin(ctx, userId, roomId)
+	if err != nil || room.GreetingDraft == nil {
+		return err
+	}
+	if err := rs.rr.SetGreeting(ctx, roomId, room.GreetingDraft); err != nil {
+		return err
+	}
+	return rs.rr.SetGreetingDraft(ctx, roomId, nil)
+}
+
+// SetCurrency changes the room currency, allowed for admins
+func (rs *RoomService) SetCurrency(ctx context.Context, userId int64, roomId string, currency string) error {
+	if _, ok := api.FindCurrency(currency); !ok {
+		return api.ErrUnknownCurrency
+	}
+	if _, err := rs.findForAdmin(ctx, userId, roomId); err != nil {
+		return err
+	}
+	return rs.rr.SetCurrency(ctx, roomId, currency)
+}
+
+// SetLang changes the language of group chat posts of the room, allowed for admins. Empty lang resets it
+func (rs *RoomService) SetLang(ctx context.Context, userId int64, roomId string, lang string) error {
+	if _, err := rs.findForAdmin(ctx, userId, roomId); err != nil {
+		return err
+	}
+	return rs.rr.SetLang(ctx, roomId, lang)
+}
+
+// SetCollectionRule changes the rule of automatic collections, allowed for admins.
+// The rule can be enabled only with the amount in the room currency, the fixed organizer must be a member
+func (rs *RoomService) SetCollectionRule(ctx context.Context, userId int64, roomId string, rule *api.CollectionRule) error {
+	room, err := rs.findForAdmin(ctx, userId, roomId)
+	if err != nil {
+		return err
+	}
+	if rule.Enabled && (rule.Amount.Amount <= 0 || rule.DaysBefore <= 0) {
+		return api.ErrWrongAmount
+	}
+	if rule.Amount.Amount > 0 && rule.Amount.Currency != room.CurrencyCode() {
+		return api.ErrCurrencyMismatch
+	}
+	if rule.Organizer != 0 && !room.IsMember(rule.Organizer) {
+		return api.ErrNotMember
+	}
+	return rs.rr.SetCollectionRule(ctx, roomId, rule)
+}
+
+// SwitchOrganizerOptOut excludes the member from the organizer rotation of the room or brings the member back
+func (rs *RoomService) SwitchOrganizerOptOut(ctx context.Context, userId int64, roomId string) error {
+	room, err := rs.rr.FindById(ctx, roomId)
+	if err != nil {
+		return err
+	}
+	if !room.IsMember(userId) {
+		return api.ErrNotMember
+	}
+	if room.IsOptedOut(userId) {
+		return rs.rr.RemoveOrganizerOptOut(ctx, roomId, userId)
+	}
+	return rs.rr.AddOrganizerOptOut(ctx, roomId, userId)
+}
+
+func (rs *RoomService) findForAdmin(ctx context.Context, userId int64, roomId string) (*api.Room, error) {
+	room, err := rs.rr.FindById(ctx, roomId)
+	if err != nil {
+		return nil, err
+	}
+	if !room.IsAdmin(userId) {
+		return nil, api.ErrForbidden
+	}
+	return room, nil
+}
+
+func (rs *RoomService) findForOwner(ctx context.Context, userId int64, roomId string) (*api.Room, error) {
+	room, err := rs.rr.FindById(ctx, roomId)
+	if err != nil {
+		return nil, err
+	}
+	if !room.IsOwner(userId) {
+		return nil, api.ErrForbidden
+	}
+	return room, nil
+}
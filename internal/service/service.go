@@ -38,6 +38,20 @@ func (css *ChatStateService) CleanChatState(ctx context.Context, state *api.Chat
 	}
 }
 
+// SetPaymentField validates and saves one of payment details of the user, an empty value removes the field
+func (us *UserService) SetPaymentField(ctx context.Context, userId int64, field string, value string) error {
+	if value != "" {
+		v, err := api.NormalizePaymentField(field, value)
+		if err != nil {
+			return err
+		}
+		value = v
+	} else if !api.IsPaymentField(field) {
+		return api.ErrWrongPaymentField
+	}
+	return us.UserRepository.SetPaymentField(ctx, userId, field, value)
+}
+
 func containsUserId(users *[]api.User, id int64) bool {
 	for _, u := range *users {
 		if u.ID == id {